@@ -0,0 +1,179 @@
+// Package cmd parses the command line and runs the application
+package cmd
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/Aearsears/fuzzy-guacamole/services"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// version, commit and date are set at build time, see the makefile
+var (
+	version = "dev"
+	commit  = "none"
+	date    = "unknown"
+)
+
+const name = "fuzzy-guacamole"
+
+// envPrefix starts the environment variables, e.g. FUZZY_GUACAMOLE_PROFILE
+const envPrefix = "FUZZY_GUACAMOLE_"
+
+// settings are what the flags and the environment can change. Empty fields
+// leave the value of the next source
+type settings struct {
+	config    string
+	profile   string
+	region    string
+	endpoint  string
+	startView string
+	log       utils.LogConfig
+}
+
+// Execute runs the application with args, the command line without the
+// program name, and returns its exit code
+func Execute(args []string) int {
+	flags, showVersion, err := parseFlags(args, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		return 2
+	}
+	if showVersion {
+		fmt.Println(versionString())
+		return 0
+	}
+
+	s := flags.over(fromEnv(os.Getenv))
+	path, cfgErr := utils.ExpandHome(s.config), error(nil)
+	if path == "" {
+		path, cfgErr = services.DefaultConfigPath()
+	} else if _, err := os.Stat(path); err != nil {
+		// only the default config may be missing
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	var cfg services.Config
+	if cfgErr == nil {
+		cfg, cfgErr = services.LoadConfig(path)
+	}
+	// the file is only validated with the flags and environment applied, so
+	// that they can fix it
+	cfg = s.apply(cfg)
+	if cfgErr == nil {
+		cfgErr = cfg.Validate()
+	}
+
+	logger, closer, err := utils.NewLogger(cfg.Log)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	defer closer.Close()
+	logger.Info("starting", "version", version, "commit", commit, "config", path)
+	if cfgErr != nil {
+		logger.Warn("invalid config", "error", cfgErr)
+	}
+
+	p := tea.NewProgram(services.InitTUI(cfg, cfgErr, logger))
+	if _, err := p.Run(); err != nil {
+		logger.Error("program failed", "error", err)
+		fmt.Fprintf(os.Stderr, "Alas, there's been an error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+func versionString() string {
+	return fmt.Sprintf("%s %s (commit %s, built %s)", name, version, commit, date)
+}
+
+// parseFlags reads the command line, writing usage and errors to output
+func parseFlags(args []string, output io.Writer) (settings, bool, error) {
+	var s settings
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(output)
+	fs.StringVar(&s.config, "config", "", "config file (default config.yaml in the application directory)")
+	fs.StringVar(&s.profile, "profile", "", "AWS profile to start with")
+	fs.StringVar(&s.region, "region", "", "region to start in, overriding the profile's")
+	fs.StringVar(&s.endpoint, "endpoint", "", "configured S3-compatible endpoint to start with")
+	fs.StringVar(&s.startView, "start-view", "", "view to start on: "+strings.Join(services.StartViews(), ", "))
+	fs.StringVar(&s.log.Level, "log-level", "", "log level: debug, info, warn or error (default info)")
+	fs.StringVar(&s.log.Format, "log-format", "", "log format: text or json (default text)")
+	fs.StringVar(&s.log.Output, "log-output", "", "log file (default debug.log in the application directory)")
+	showVersion := fs.Bool("version", false, "print the version and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(output, "Usage: %s [flags]\n\nEvery flag can also be set with an environment variable,\n"+
+			"e.g. --log-level with %sLOG_LEVEL.\n\nFlags:\n", name, envPrefix)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return s, false, err
+	}
+	if fs.NArg() > 0 {
+		fmt.Fprintf(output, "unexpected argument %q\n", fs.Arg(0))
+		fs.Usage()
+		return s, false, fmt.Errorf("unexpected argument %q", fs.Arg(0))
+	}
+	return s, *showVersion, nil
+}
+
+// fromEnv reads the environment variables matching the flags
+func fromEnv(getenv func(string) string) settings {
+	env := func(key string) string { return getenv(envPrefix + key) }
+	return settings{
+		config:    env("CONFIG"),
+		profile:   env("PROFILE"),
+		region:    env("REGION"),
+		endpoint:  env("ENDPOINT"),
+		startView: env("START_VIEW"),
+		log: utils.LogConfig{
+			Level:  env("LOG_LEVEL"),
+			Format: env("LOG_FORMAT"),
+			Output: env("LOG_OUTPUT"),
+		},
+	}
+}
+
+// over fills the settings s leaves empty from base. The profile and the
+// endpoint are one choice: setting either drops both of base
+func (s settings) over(base settings) settings {
+	if s.config == "" {
+		s.config = base.config
+	}
+	if s.profile == "" && s.endpoint == "" {
+		s.profile, s.endpoint = base.profile, base.endpoint
+	}
+	if s.region == "" {
+		s.region = base.region
+	}
+	if s.startView == "" {
+		s.startView = base.startView
+	}
+	s.log = s.log.Over(base.log)
+	return s
+}
+
+// apply overrides the config file with the settings
+func (s settings) apply(cfg services.Config) services.Config {
+	if s.profile != "" || s.endpoint != "" {
+		cfg.Profile, cfg.Endpoint = s.profile, s.endpoint
+	}
+	if s.region != "" {
+		cfg.Region = s.region
+	}
+	if s.startView != "" {
+		cfg.StartView = s.startView
+	}
+	cfg.Log = s.log.Over(cfg.Log)
+	return cfg
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/Aearsears/fuzzy-guacamole/services"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFlags(t *testing.T) {
+	s, showVersion, err := parseFlags([]string{"--config", "./configs/dev.yaml", "--log-level", "debug", "--log-format", "text", "--log-output", "./dev.log"}, io.Discard)
+	assert.NoError(t, err)
+	assert.False(t, showVersion)
+	assert.Equal(t, settings{
+		config: "./configs/dev.yaml",
+		log:    utils.LogConfig{Level: "debug", Format: "text", Output: "./dev.log"},
+	}, s)
+
+	_, showVersion, err = parseFlags([]string{"--version"}, io.Discard)
+	assert.NoError(t, err)
+	assert.True(t, showVersion)
+
+	_, _, err = parseFlags([]string{"s3"}, io.Discard)
+	assert.Error(t, err)
+}
+
+func TestFromEnv(t *testing.T) {
+	env := map[string]string{"FUZZY_GUACAMOLE_REGION": "eu-west-1", "FUZZY_GUACAMOLE_LOG_LEVEL": "warn", "AWS_REGION": "us-east-1"}
+	s := fromEnv(func(key string) string { return env[key] })
+	assert.Equal(t, settings{region: "eu-west-1", log: utils.LogConfig{Level: "warn"}}, s)
+}
+
+func TestResolveOrder(t *testing.T) {
+	flags := settings{profile: "prod", log: utils.LogConfig{Level: "debug"}}
+	env := settings{endpoint: "localstack", region: "eu-west-1", log: utils.LogConfig{Level: "warn", Format: "json"}}
+	file := services.Config{
+		Endpoint:  "minio",
+		Region:    "us-east-1",
+		StartView: "transfers",
+		Log:       utils.LogConfig{Output: "app.log"},
+	}
+
+	cfg := flags.over(env).apply(file)
+	assert.Equal(t, "prod", cfg.Profile)
+	assert.Empty(t, cfg.Endpoint, "a profile flag replaces the endpoint of the environment and the config")
+	assert.Equal(t, "eu-west-1", cfg.Region)
+	assert.Equal(t, "transfers", cfg.StartView)
+	assert.Equal(t, utils.LogConfig{Level: "debug", Format: "json", Output: "app.log"}, cfg.Log)
+
+	cfg = settings{}.over(env).apply(file)
+	assert.Equal(t, "localstack", cfg.Endpoint)
+	assert.Empty(t, cfg.Profile)
+}
+
+func TestFlagsFixConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte("start_view: nope\nprofile: prod\n"), 0o644))
+	cfg, err := services.LoadConfig(path)
+	assert.NoError(t, err, "loading does not validate")
+	assert.Error(t, cfg.Validate())
+
+	cfg = settings{startView: "s3", profile: "dev"}.apply(cfg)
+	assert.NoError(t, cfg.Validate())
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Bookmark is a saved bucket prefix, reached with the profile it was saved under
+type Bookmark struct {
+	Name    string `json:"name"`
+	Profile string `json:"profile"`
+	Bucket  string `json:"bucket"`
+	Prefix  string `json:"prefix"`
+}
+
+// NewBookmark creates a bookmark named after its location
+func NewBookmark(profile, bucket, prefix string) Bookmark {
+	prefix = strings.Trim(prefix, "/")
+	return Bookmark{
+		Name:    strings.TrimSuffix(fmt.Sprintf("%s/%s", bucket, prefix), "/"),
+		Profile: profile,
+		Bucket:  bucket,
+		Prefix:  prefix,
+	}
+}
+
+func (b Bookmark) sameLocation(other Bookmark) bool {
+	return b.Profile == other.Profile && b.Bucket == other.Bucket && b.Prefix == other.Prefix
+}
+
+// AddBookmark appends b unless the location is already bookmarked. It reports whether b was added
+func AddBookmark(bookmarks []Bookmark, b Bookmark) ([]Bookmark, bool) {
+	for _, existing := range bookmarks {
+		if existing.sameLocation(b) {
+			return bookmarks, false
+		}
+	}
+	return append(bookmarks, b), true
+}
+
+// RemoveBookmark removes the bookmark at index i
+func RemoveBookmark(bookmarks []Bookmark, i int) []Bookmark {
+	if i < 0 || i >= len(bookmarks) {
+		return bookmarks
+	}
+	return append(bookmarks[:i:i], bookmarks[i+1:]...)
+}
@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBookmarks(t *testing.T) {
+	a := NewBookmark("default", "logs", "/2024/01/")
+	assert.Equal(t, "logs/2024/01", a.Name)
+	assert.Equal(t, "2024/01", a.Prefix)
+	assert.Equal(t, "logs", NewBookmark("default", "logs", "").Name)
+
+	bookmarks, added := AddBookmark(nil, a)
+	assert.True(t, added)
+	bookmarks, added = AddBookmark(bookmarks, NewBookmark("default", "logs", "2024/01"))
+	assert.False(t, added, "same location")
+	bookmarks, added = AddBookmark(bookmarks, NewBookmark("minio", "logs", "2024/01"))
+	assert.True(t, added, "other profile")
+	assert.Len(t, bookmarks, 2)
+
+	remaining := RemoveBookmark(bookmarks, 0)
+	assert.Len(t, remaining, 1)
+	assert.Equal(t, "minio", remaining[0].Profile)
+	assert.Equal(t, "default", bookmarks[0].Profile, "the original slice is left alone")
+	assert.Len(t, RemoveBookmark(remaining, 5), 1)
+}
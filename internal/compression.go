@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// content encodings an upload can be compressed with
+const (
+	EncodingGzip   = "gzip"
+	EncodingBrotli = "br"
+)
+
+// CompressionRule compresses uploads whose extension is listed, e.g. [".html", ".css"]
+type CompressionRule struct {
+	Extensions []string `yaml:"extensions"`
+	Encoding   string   `yaml:"encoding"`
+}
+
+// ValidateCompressionRules checks the encodings of the rules
+func ValidateCompressionRules(rules []CompressionRule) error {
+	for _, rule := range rules {
+		if rule.Encoding != EncodingGzip && rule.Encoding != EncodingBrotli {
+			return fmt.Errorf("unknown compression encoding %q, expected %s or %s", rule.Encoding, EncodingGzip, EncodingBrotli)
+		}
+	}
+	return nil
+}
+
+// CompressionFor returns the encoding of the first rule matching the file's extension, or ""
+func CompressionFor(rules []CompressionRule, path string) string {
+	ext := strings.ToLower(filepath.Ext(path))
+	if ext == "" {
+		return ""
+	}
+	for _, rule := range rules {
+		for _, e := range rule.Extensions {
+			if strings.ToLower("."+strings.TrimPrefix(e, ".")) == ext {
+				return rule.Encoding
+			}
+		}
+	}
+	return ""
+}
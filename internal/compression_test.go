@@ -0,0 +1,23 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressionFor(t *testing.T) {
+	rules := []CompressionRule{
+		{Extensions: []string{".html", "css"}, Encoding: EncodingBrotli},
+		{Extensions: []string{".css", ".json"}, Encoding: EncodingGzip},
+	}
+	assert.NoError(t, ValidateCompressionRules(rules))
+
+	assert.Equal(t, EncodingBrotli, CompressionFor(rules, "site/index.HTML"))
+	assert.Equal(t, EncodingBrotli, CompressionFor(rules, "style.css"), "first rule wins")
+	assert.Equal(t, EncodingGzip, CompressionFor(rules, "data.json"))
+	assert.Equal(t, "", CompressionFor(rules, "photo.jpg"))
+	assert.Equal(t, "", CompressionFor(rules, "Makefile"))
+
+	assert.Error(t, ValidateCompressionRules([]CompressionRule{{Encoding: "zstd"}}))
+}
@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffStatus tells on which side of a comparison a key differs
+type DiffStatus int
+
+const (
+	DiffLeftOnly DiffStatus = iota
+	DiffRightOnly
+	DiffChanged
+)
+
+func (s DiffStatus) String() string {
+	switch s {
+	case DiffLeftOnly:
+		return "left only"
+	case DiffRightOnly:
+		return "right only"
+	case DiffChanged:
+		return "differs"
+	}
+	return "unknown"
+}
+
+// DiffEntry is one key that is not identical on both sides.
+// Left or Right is the zero SyncFile when the key is missing on that side
+type DiffEntry struct {
+	Path   string
+	Status DiffStatus
+	Left   SyncFile
+	Right  SyncFile
+	Reason string
+}
+
+// DiffCounts summarises a comparison
+type DiffCounts struct {
+	LeftOnly  int
+	RightOnly int
+	Changed   int
+	Same      int
+	// Unverified are counted in Same: objects of the same size with a
+	// multipart ETag on either side and no checksums to compare
+	Unverified int
+}
+
+// DiffListings compares two listings by path, size and ETag.
+// Entries are sorted by path
+func DiffListings(left, right []SyncFile) ([]DiffEntry, DiffCounts) {
+	rightMap := make(map[string]SyncFile, len(right))
+	for _, f := range right {
+		rightMap[f.Path] = f
+	}
+
+	var entries []DiffEntry
+	var counts DiffCounts
+	seen := make(map[string]bool, len(left))
+	for _, l := range left {
+		seen[l.Path] = true
+		r, ok := rightMap[l.Path]
+		if !ok {
+			entries = append(entries, DiffEntry{Path: l.Path, Status: DiffLeftOnly, Left: l, Reason: "missing on the right"})
+			counts.LeftOnly++
+			continue
+		}
+		reason, verified := diffReason(l, r)
+		if reason != "" {
+			entries = append(entries, DiffEntry{Path: l.Path, Status: DiffChanged, Left: l, Right: r, Reason: reason})
+			counts.Changed++
+			continue
+		}
+		counts.Same++
+		if !verified {
+			counts.Unverified++
+		}
+	}
+	for _, r := range right {
+		if seen[r.Path] {
+			continue
+		}
+		entries = append(entries, DiffEntry{Path: r.Path, Status: DiffRightOnly, Right: r, Reason: "missing on the left"})
+		counts.RightOnly++
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, counts
+}
+
+// diffReason returns why two files with the same path differ, or "" when they
+// match. ETags are only compared when both are known. A multipart ETag depends
+// on the part size as well as the content, so when either side has one the
+// checksums decide. Without them files of the same size match, but verified
+// is false. Modification times are not compared, copies are always newer
+func diffReason(l, r SyncFile) (reason string, verified bool) {
+	if l.Size != r.Size {
+		return "size differs", true
+	}
+	if l.ETag == "" || r.ETag == "" {
+		return "", true
+	}
+	if !strings.Contains(l.ETag, "-") && !strings.Contains(r.ETag, "-") {
+		if l.ETag != r.ETag {
+			return "etag differs", true
+		}
+		return "", true
+	}
+	if l.Checksum != "" && r.Checksum != "" {
+		if l.Checksum != r.Checksum {
+			return "checksum differs", true
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// DiffPaths returns the paths of the entries with the given status
+func DiffPaths(entries []DiffEntry, status DiffStatus) []string {
+	var paths []string
+	for _, e := range entries {
+		if e.Status == status {
+			paths = append(paths, e.Path)
+		}
+	}
+	return paths
+}
+
+// WriteDiffCSV writes the entries with a header row to w
+func WriteDiffCSV(w io.Writer, entries []DiffEntry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"key", "status", "left_size", "left_etag", "right_size", "right_etag", "reason"}); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		record := []string{e.Path, e.Status.String(), "", e.Left.ETag, "", e.Right.ETag, e.Reason}
+		if e.Status != DiffRightOnly {
+			record[2] = strconv.FormatInt(e.Left.Size, 10)
+		}
+		if e.Status != DiffLeftOnly {
+			record[4] = strconv.FormatInt(e.Right.Size, 10)
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiffListings(t *testing.T) {
+	left := []SyncFile{
+		{Path: "same.txt", Size: 3, ETag: "abc"},
+		{Path: "size.txt", Size: 3, ETag: "abc"},
+		{Path: "etag.txt", Size: 3, ETag: "abc"},
+		{Path: "unknown.txt", Size: 3},
+		{Path: "only-left.txt", Size: 1},
+	}
+	right := []SyncFile{
+		{Path: "same.txt", Size: 3, ETag: "abc"},
+		{Path: "size.txt", Size: 4, ETag: "abc"},
+		{Path: "etag.txt", Size: 3, ETag: "def"},
+		{Path: "unknown.txt", Size: 3, ETag: "abc"},
+		{Path: "only-right.txt", Size: 2},
+	}
+
+	entries, counts := DiffListings(left, right)
+	assert.Equal(t, DiffCounts{LeftOnly: 1, RightOnly: 1, Changed: 2, Same: 2}, counts)
+
+	paths := make([]string, len(entries))
+	for i, e := range entries {
+		paths[i] = e.Path
+	}
+	assert.Equal(t, []string{"etag.txt", "only-left.txt", "only-right.txt", "size.txt"}, paths)
+	assert.Equal(t, "etag differs", entries[0].Reason)
+	assert.Equal(t, "size differs", entries[3].Reason)
+	assert.Equal(t, []string{"only-left.txt"}, DiffPaths(entries, DiffLeftOnly))
+	assert.Equal(t, []string{"only-right.txt"}, DiffPaths(entries, DiffRightOnly))
+}
+
+func TestDiffListingsMultipartETags(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	left := []SyncFile{
+		{Path: "same.bin", Size: 8, ETag: "abc-2", ModTime: now},
+		{Path: "checksum.bin", Size: 8, ETag: "abc-2", Checksum: "aa", ModTime: now},
+		{Path: "newer.bin", Size: 8, ETag: "abc", ModTime: now},
+	}
+	right := []SyncFile{
+		{Path: "same.bin", Size: 8, ETag: "def", ModTime: now.Add(500 * time.Millisecond)},
+		{Path: "checksum.bin", Size: 8, ETag: "def-3", Checksum: "bb", ModTime: now},
+		{Path: "newer.bin", Size: 8, ETag: "def-3", ModTime: now.Add(time.Hour)},
+	}
+
+	entries, counts := DiffListings(left, right)
+	assert.Equal(t, DiffCounts{Changed: 1, Same: 2, Unverified: 2}, counts, "a multipart ETag alone does not make a difference")
+	if assert.Len(t, entries, 1) {
+		assert.Equal(t, "checksum.bin", entries[0].Path)
+		assert.Equal(t, "checksum differs", entries[0].Reason)
+	}
+}
+
+func TestWriteDiffCSV(t *testing.T) {
+	entries := []DiffEntry{
+		{Path: "a,b.txt", Status: DiffLeftOnly, Left: SyncFile{Size: 1, ETag: "x"}, Reason: "missing on the right"},
+		{Path: "c.txt", Status: DiffChanged, Left: SyncFile{Size: 1}, Right: SyncFile{Size: 2}, Reason: "size differs"},
+	}
+
+	var buf bytes.Buffer
+	assert.NoError(t, WriteDiffCSV(&buf, entries))
+	assert.Equal(t, "key,status,left_size,left_etag,right_size,right_etag,reason\n"+
+		"\"a,b.txt\",left only,1,x,,,missing on the right\n"+
+		"c.txt,differs,1,,2,,size differs\n", buf.String())
+}
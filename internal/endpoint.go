@@ -0,0 +1,34 @@
+package internal
+
+// Endpoint describes an S3-compatible service such as MinIO, Ceph, R2 or LocalStack
+type Endpoint struct {
+	Name          string              `yaml:"name"`
+	URL           string              `yaml:"url"`
+	PathStyle     bool                `yaml:"path_style"`
+	SigningRegion string              `yaml:"signing_region"`
+	TLS           EndpointTLS         `yaml:"tls"`
+	Credentials   EndpointCredentials `yaml:"credentials"`
+}
+
+type EndpointTLS struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+	CAFile             string `yaml:"ca_file"` // PEM bundle trusted in addition to the system roots
+}
+
+// credential sources an endpoint can use
+const (
+	CredentialsDefault = "default" // the AWS default credential chain
+	CredentialsStatic  = "static"  // keys written in the config
+	CredentialsProfile = "profile" // a profile from the shared AWS files
+	CredentialsEnv     = "env"     // keys read from the named environment variables
+)
+
+type EndpointCredentials struct {
+	Source          string `yaml:"source"`
+	AccessKeyID     string `yaml:"access_key_id"`
+	SecretAccessKey string `yaml:"secret_access_key"`
+	SessionToken    string `yaml:"session_token"`
+	Profile         string `yaml:"profile"`
+	AccessKeyEnv    string `yaml:"access_key_env"`
+	SecretKeyEnv    string `yaml:"secret_key_env"`
+}
@@ -0,0 +1,84 @@
+package internal
+
+import (
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// FuzzyMatch is one item that matched a fuzzy pattern
+type FuzzyMatch struct {
+	Index     int    // position of the item in the filtered slice
+	Str       string // the item itself
+	Score     int
+	Positions []int // byte offsets of the matched runes in Str
+}
+
+const (
+	fuzzyMatchScore       = 16
+	fuzzyConsecutiveBonus = 8
+	fuzzyBoundaryBonus    = 12 // match at the start or after a separator
+	fuzzyGapPenalty       = 1
+)
+
+// Fuzzy reports whether the runes of pattern appear in s in order, ignoring case.
+// Matches at word boundaries and runs of consecutive runes score higher
+func Fuzzy(pattern, s string) (score int, positions []int, ok bool) {
+	if pattern == "" {
+		return 0, nil, true
+	}
+
+	p := []rune(pattern)
+	pi := 0
+	last := -1
+	prev := rune(0)
+	for i, r := range s {
+		if pi < len(p) && unicode.ToLower(r) == unicode.ToLower(p[pi]) {
+			score += fuzzyMatchScore
+			if last >= 0 && last+utf8.RuneLen(prev) == i {
+				score += fuzzyConsecutiveBonus
+			} else if last >= 0 {
+				score -= fuzzyGapPenalty * (i - last)
+			}
+			if i == 0 || isFuzzySeparator(prev) {
+				score += fuzzyBoundaryBonus
+			}
+			positions = append(positions, i)
+			last = i
+			pi++
+		}
+		prev = r
+	}
+	if pi < len(p) {
+		return 0, nil, false
+	}
+	// prefer shorter items when everything else is equal
+	score -= utf8.RuneCountInString(s) - len(p)
+	return score, positions, true
+}
+
+func isFuzzySeparator(r rune) bool {
+	switch r {
+	case '/', '-', '_', '.', ' ', ':':
+		return true
+	}
+	return false
+}
+
+// FuzzyFilter returns the items matching pattern, best first.
+// An empty pattern keeps every item in its original order
+func FuzzyFilter(pattern string, items []string) []FuzzyMatch {
+	matches := make([]FuzzyMatch, 0, len(items))
+	for i, item := range items {
+		score, positions, ok := Fuzzy(pattern, item)
+		if ok {
+			matches = append(matches, FuzzyMatch{Index: i, Str: item, Score: score, Positions: positions})
+		}
+	}
+	if pattern != "" {
+		sort.SliceStable(matches, func(i, j int) bool {
+			return matches[i].Score > matches[j].Score
+		})
+	}
+	return matches
+}
@@ -0,0 +1,44 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFuzzy(t *testing.T) {
+	_, positions, ok := Fuzzy("lg24", "logs/2024")
+	assert.True(t, ok)
+	assert.Equal(t, []int{0, 2, 5, 8}, positions)
+
+	_, _, ok = Fuzzy("LOGS", "logs/2024")
+	assert.True(t, ok, "case insensitive")
+
+	_, _, ok = Fuzzy("gl", "logs")
+	assert.False(t, ok, "order matters")
+
+	score, positions, ok := Fuzzy("", "anything")
+	assert.True(t, ok)
+	assert.Zero(t, score)
+	assert.Empty(t, positions)
+
+	_, positions, ok = Fuzzy("é", "café")
+	assert.True(t, ok)
+	assert.Equal(t, []int{3}, positions, "byte offsets")
+}
+
+func TestFuzzyFilter(t *testing.T) {
+	items := []string{"profile", "prefix-cache", "bucket", "pr"}
+
+	matches := FuzzyFilter("pr", items)
+	assert.Len(t, matches, 3)
+	assert.Equal(t, "pr", matches[0].Str, "exact match first")
+	assert.Equal(t, 3, matches[0].Index)
+
+	matches = FuzzyFilter("pc", items)
+	assert.Equal(t, "prefix-cache", matches[0].Str, "boundary match wins")
+
+	matches = FuzzyFilter("", items)
+	assert.Len(t, matches, 4)
+	assert.Equal(t, "profile", matches[0].Str, "original order")
+}
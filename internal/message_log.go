@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+)
+
+// LogEntry is a status message kept in the message log
+type LogEntry struct {
+	Time     time.Time
+	Severity Severity
+	Text     string
+	Detail   string // what the error chain adds to the text, for errors
+}
+
+// MessageLog keeps the latest status messages, oldest first
+type MessageLog struct {
+	entries []LogEntry
+	limit   int
+}
+
+func NewMessageLog(limit int) *MessageLog {
+	return &MessageLog{limit: limit}
+}
+
+// Add records msg as received at, dropping the oldest entry once the log is full
+func (l *MessageLog) Add(msg APIMessage, at time.Time) LogEntry {
+	entry := LogEntry{
+		Time:     at,
+		Severity: msg.Level(),
+		Text:     msg.Text(),
+		Detail:   ErrorDetail(msg.Err),
+	}
+	if l.limit > 0 && len(l.entries) == l.limit {
+		l.entries = append(l.entries[:0], l.entries[1:]...)
+	}
+	l.entries = append(l.entries, entry)
+	return entry
+}
+
+func (l *MessageLog) Entries() []LogEntry {
+	return l.entries
+}
+
+func (l *MessageLog) Len() int {
+	return len(l.entries)
+}
+
+// WriteTo writes the log as text, one entry per line with its details indented below
+func (l *MessageLog) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+	for _, e := range l.entries {
+		fmt.Fprintf(&b, "%s %-7s %s\n", e.Time.Format(time.RFC3339), strings.ToUpper(e.Severity.String()), e.Text)
+		for _, line := range strings.Split(e.Detail, "\n") {
+			if line != "" {
+				fmt.Fprintf(&b, "    %s\n", line)
+			}
+		}
+	}
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ErrorDetail describes err beyond its message: the AWS error code, request
+// ID and HTTP status when it came from a service, and the types of the
+// wrapped errors. It is empty for a nil error
+func ErrorDetail(err error) string {
+	if err == nil {
+		return ""
+	}
+	var lines []string
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		lines = append(lines, fmt.Sprintf("code: %s (%s fault)", apiErr.ErrorCode(), apiErr.ErrorFault()))
+		if msg := apiErr.ErrorMessage(); msg != "" {
+			lines = append(lines, "message: "+msg)
+		}
+	}
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) {
+		lines = append(lines, fmt.Sprintf("http status: %d", respErr.HTTPStatusCode()))
+		if id := respErr.ServiceRequestID(); id != "" {
+			lines = append(lines, "request id: "+id)
+		}
+	}
+	var chain []string
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		chain = append(chain, fmt.Sprintf("%T", e))
+	}
+	lines = append(lines, "error: "+strings.Join(chain, " > "))
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIMessageLevel(t *testing.T) {
+	assert.Equal(t, SeverityInfo, APIMessage{Status: "Listing..."}.Level())
+	assert.Equal(t, SeverityError, APIMessage{Err: errors.New("boom")}.Level())
+	assert.Equal(t, SeveritySuccess, APIMessage{Response: "done"}.Level())
+	assert.Equal(t, SeverityWarn, APIMessage{Status: "nothing to do", Severity: SeverityWarn}.Level())
+}
+
+func TestMessageLogLimit(t *testing.T) {
+	log := NewMessageLog(2)
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		log.Add(APIMessage{Status: fmt.Sprintf("message %d", i)}, at)
+	}
+	assert.Equal(t, 2, log.Len())
+	assert.Equal(t, "message 1", log.Entries()[0].Text, "the oldest entry is dropped")
+}
+
+func TestMessageLogWriteTo(t *testing.T) {
+	log := NewMessageLog(10)
+	at := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	log.Add(APIMessage{Status: "Bookmarked logs", Severity: SeveritySuccess}, at)
+	apiErr := &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied", Fault: smithy.FaultClient}
+	log.Add(APIMessage{Err: fmt.Errorf("listing logs: %w", apiErr)}, at.Add(time.Second))
+
+	var b strings.Builder
+	_, err := log.WriteTo(&b)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-03-01T12:00:00Z SUCCESS Bookmarked logs\n"+
+		"2025-03-01T12:00:01Z ERROR   listing logs: api error AccessDenied: Access Denied\n"+
+		"    code: AccessDenied (client fault)\n"+
+		"    message: Access Denied\n"+
+		"    error: *fmt.wrapError > *smithy.GenericAPIError\n", b.String())
+}
+
+func TestErrorDetail(t *testing.T) {
+	assert.Empty(t, ErrorDetail(nil))
+	assert.Equal(t, "error: *errors.errorString", ErrorDetail(errors.New("boom")))
+}
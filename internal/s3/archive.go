@@ -0,0 +1,342 @@
+package s3
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// ArchiveFormat is an archive format that can be browsed without extracting it first
+type ArchiveFormat string
+
+const (
+	ArchiveZip   ArchiveFormat = "zip"
+	ArchiveTarGz ArchiveFormat = "tar.gz"
+	ArchiveTar   ArchiveFormat = "tar"
+)
+
+// ArchiveFormatOf returns the archive format of key from its extension, or "" for other objects
+func ArchiveFormatOf(key string) ArchiveFormat {
+	key = strings.ToLower(key)
+	switch {
+	case strings.HasSuffix(key, ".zip"), strings.HasSuffix(key, ".jar"):
+		return ArchiveZip
+	case strings.HasSuffix(key, ".tar.gz"), strings.HasSuffix(key, ".tgz"):
+		return ArchiveTarGz
+	case strings.HasSuffix(key, ".tar"):
+		return ArchiveTar
+	}
+	return ""
+}
+
+// ArchiveMember is a file or directory inside an archive.
+// Directory names end in "/"
+type ArchiveMember struct {
+	Name           string
+	Size           int64
+	CompressedSize int64 // 0 when the format does not record it
+	Modified       time.Time
+}
+
+// archiveBlockSize is how much of a zip archive each ranged GET fetches
+var archiveBlockSize int64 = 1 << 20
+
+// rangeReader reads an object through ranged GETs. It fetches aligned blocks
+// and keeps the last one, so the small reads archive/zip makes while walking
+// the central directory do not each cost a request
+type rangeReader struct {
+	ctx      context.Context
+	client   S3SDK
+	bucket   string
+	key      string
+	etag     string
+	size     int64
+	block    []byte
+	blockOff int64
+}
+
+func (r *rangeReader) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) && off < r.size {
+		start := off - off%archiveBlockSize
+		if r.block == nil || r.blockOff != start {
+			block, err := r.fetch(start, min(start+archiveBlockSize, r.size)-1)
+			if err != nil {
+				return n, err
+			}
+			r.block, r.blockOff = block, start
+		}
+		copied := copy(p[n:], r.block[off-start:])
+		n += copied
+		off += int64(copied)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// fetch reads the inclusive byte range first-last
+func (r *rangeReader) fetch(first, last int64) ([]byte, error) {
+	body, err := r.open(first, last)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func (r *rangeReader) open(first, last int64) (io.ReadCloser, error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", first, last)),
+	}
+	// fail rather than mix ranges of two versions when the archive is replaced
+	if r.etag != "" {
+		input.IfMatch = aws.String(r.etag)
+	}
+	resp, err := r.client.GetObject(r.ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// openZip reads the central directory of a zip object with ranged GETs
+func (c *S3Client) openZip(ctx context.Context, bucket, key string) (*zip.Reader, *rangeReader, error) {
+	client := c.clientFor(ctx, bucket)
+	head, err := client.HeadObject(ctx, &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &rangeReader{
+		ctx:    ctx,
+		client: client,
+		bucket: bucket,
+		key:    key,
+		etag:   aws.ToString(head.ETag),
+		size:   aws.ToInt64(head.ContentLength),
+	}
+	zr, err := zip.NewReader(r, r.size)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading zip directory of %s: %w", key, err)
+	}
+	return zr, r, nil
+}
+
+// openTar streams the object and returns a reader positioned before the first member
+func (c *S3Client) openTar(ctx context.Context, bucket, key string, format ArchiveFormat) (*tar.Reader, io.Closer, error) {
+	resp, err := c.clientFor(ctx, bucket).GetObject(ctx, &s3.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)})
+	if err != nil {
+		return nil, nil, err
+	}
+	if format == ArchiveTar {
+		return tar.NewReader(resp.Body), resp.Body, nil
+	}
+	gz, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("reading %s: %w", key, err)
+	}
+	return tar.NewReader(gz), resp.Body, nil
+}
+
+// tarMemberName normalises a tar header name, returning "" for entries to skip
+func tarMemberName(hdr *tar.Header) string {
+	name := strings.TrimPrefix(hdr.Name, "./")
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		name = strings.TrimSuffix(name, "/")
+		if name == "" || name == "." {
+			return ""
+		}
+		return name + "/"
+	case tar.TypeReg:
+		return name
+	}
+	return ""
+}
+
+func (c *S3Client) listArchive(ctx context.Context, bucket, key string) ([]ArchiveMember, error) {
+	format := ArchiveFormatOf(key)
+	switch format {
+	case ArchiveZip:
+		zr, _, err := c.openZip(ctx, bucket, key)
+		if err != nil {
+			return nil, err
+		}
+		members := make([]ArchiveMember, 0, len(zr.File))
+		for _, f := range zr.File {
+			members = append(members, ArchiveMember{
+				Name:           f.Name,
+				Size:           int64(f.UncompressedSize64),
+				CompressedSize: int64(f.CompressedSize64),
+				Modified:       f.Modified,
+			})
+		}
+		return members, nil
+
+	case ArchiveTarGz, ArchiveTar:
+		tr, body, err := c.openTar(ctx, bucket, key, format)
+		if err != nil {
+			return nil, err
+		}
+		defer body.Close()
+		var members []ArchiveMember
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return members, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("reading %s: %w", key, err)
+			}
+			if name := tarMemberName(hdr); name != "" {
+				members = append(members, ArchiveMember{Name: name, Size: hdr.Size, Modified: hdr.ModTime})
+			}
+		}
+	}
+	return nil, fmt.Errorf("%s is not a zip or tar archive", key)
+}
+
+// ListArchive lists the members of a zip or tar archive object.
+// Zip archives only have their central directory read, tar archives are streamed
+func (c *S3Client) ListArchive(ctx context.Context, bucket, key string) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpListArchive
+		mssg.Bucket = bucket
+		mssg.Key = key
+
+		members, err := c.listArchive(ctx, bucket, key)
+		if err != nil {
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		mssg.Archive = members
+		mssg.APIMessage.Status = fmt.Sprintf("Listed %d archive members in %s/%s", len(members), bucket, key)
+		return mssg, nil
+	})
+}
+
+// ExtractMember saves one member of an archive object at its path under the savePath directory.
+// Zip members are fetched with a single ranged GET, tar archives are streamed up to the member
+func (c *S3Client) ExtractMember(ctx context.Context, bucket, key, member, savePath string, tracker *transfer.Tracker) error {
+	format := ArchiveFormatOf(key)
+	switch format {
+	case ArchiveZip:
+		zr, r, err := c.openZip(ctx, bucket, key)
+		if err != nil {
+			return err
+		}
+		for _, f := range zr.File {
+			if f.Name == member {
+				return extractZipMember(f, r, savePath, tracker)
+			}
+		}
+
+	case ArchiveTarGz, ArchiveTar:
+		tr, body, err := c.openTar(ctx, bucket, key, format)
+		if err != nil {
+			return err
+		}
+		defer body.Close()
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("reading %s: %w", key, err)
+			}
+			if hdr.Typeflag == tar.TypeReg && tarMemberName(hdr) == member {
+				tracker.SetTotal(hdr.Size)
+				return writeMember(savePath, member, tracker.Reader(tr))
+			}
+		}
+
+	default:
+		return fmt.Errorf("%s is not a zip or tar archive", key)
+	}
+	return fmt.Errorf("%s has no member %s", key, member)
+}
+
+// extractZipMember fetches exactly the compressed bytes of f and inflates them
+func extractZipMember(f *zip.File, r *rangeReader, savePath string, tracker *transfer.Tracker) error {
+	if f.Method != zip.Store && f.Method != zip.Deflate {
+		return fmt.Errorf("%s uses unsupported zip compression method %d", f.Name, f.Method)
+	}
+	offset, err := f.DataOffset()
+	if err != nil {
+		return err
+	}
+
+	var body io.ReadCloser = io.NopCloser(strings.NewReader(""))
+	if f.CompressedSize64 > 0 {
+		body, err = r.open(offset, offset+int64(f.CompressedSize64)-1)
+		if err != nil {
+			return err
+		}
+	}
+	defer body.Close()
+
+	tracker.SetTotal(int64(f.CompressedSize64))
+	var content io.Reader = tracker.Reader(body)
+	if f.Method == zip.Deflate {
+		inflater := flate.NewReader(content)
+		defer inflater.Close()
+		content = inflater
+	}
+
+	return writeMember(savePath, f.Name, &crcReader{r: content, hash: crc32.NewIEEE(), want: f.CRC32, name: f.Name})
+}
+
+// crcReader fails at the end of a zip member whose content does not match
+// its checksum, before the member is saved
+type crcReader struct {
+	r    io.Reader
+	hash hash.Hash32
+	want uint32
+	name string
+}
+
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	if errors.Is(err, io.EOF) && c.hash.Sum32() != c.want {
+		return n, fmt.Errorf("%s: checksum mismatch", c.name)
+	}
+	return n, err
+}
+
+// writeMember saves the member content at its path inside the archive under
+// savePath. Members that would leave savePath are refused, and the file
+// only appears once all of it was read
+func writeMember(savePath, member string, content io.Reader) error {
+	local, err := internal.JoinLocal(savePath, member)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return err
+	}
+	return saveFile(local, content)
+}
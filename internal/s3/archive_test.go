@@ -0,0 +1,166 @@
+package s3
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// rangedObject serves data to HeadObject and ranged GetObject calls, recording the ranges
+func rangedObject(data []byte, ranges *[]string) *mockS3 {
+	return &mockS3{
+		HeadObjectFunc: func(ctx context.Context, input *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data))), ETag: aws.String("\"v1\"")}, nil
+		},
+		GetObjectFunc: func(ctx context.Context, input *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			body := data
+			if input.Range != nil {
+				*ranges = append(*ranges, aws.ToString(input.Range))
+				var first, last int
+				if _, err := fmt.Sscanf(aws.ToString(input.Range), "bytes=%d-%d", &first, &last); err != nil {
+					return nil, err
+				}
+				body = data[first : last+1]
+			}
+			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(body))}, nil
+		},
+	}
+}
+
+func TestArchiveFormatOf(t *testing.T) {
+	assert.Equal(t, ArchiveZip, ArchiveFormatOf("a/b.ZIP"))
+	assert.Equal(t, ArchiveTarGz, ArchiveFormatOf("b.tar.gz"))
+	assert.Equal(t, ArchiveTarGz, ArchiveFormatOf("b.tgz"))
+	assert.Equal(t, ArchiveTar, ArchiveFormatOf("b.tar"))
+	assert.Equal(t, ArchiveFormat(""), ArchiveFormatOf("b.gz"))
+}
+
+func TestZipArchive(t *testing.T) {
+	defer func(size int64) { archiveBlockSize = size }(archiveBlockSize)
+	archiveBlockSize = 256
+
+	// random text so deflate cannot shrink the archive below a few blocks
+	rng := rand.New(rand.NewSource(1))
+	letters := make([]byte, 20000)
+	for i := range letters {
+		letters[i] = byte('a' + rng.Intn(26))
+	}
+	big := string(letters)
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("docs/big.txt")
+	w.Write([]byte(big))
+	w, _ = zw.CreateHeader(&zip.FileHeader{Name: "stored.txt", Method: zip.Store})
+	w.Write([]byte("stored"))
+	zw.Create("empty/")
+	require.NoError(t, zw.Close())
+	data := buf.Bytes()
+
+	var ranges []string
+	client := &S3Client{Client: rangedObject(data, &ranges)}
+
+	msg := client.ListArchive(context.Background(), "bucket", "bundle.zip")().(S3MenuMessage)
+	require.NoError(t, msg.APIMessage.Err)
+	assert.Equal(t, "bundle.zip", msg.Key)
+	require.Len(t, msg.Archive, 3)
+	assert.Equal(t, "docs/big.txt", msg.Archive[0].Name)
+	assert.Equal(t, int64(len(big)), msg.Archive[0].Size)
+	assert.Equal(t, "empty/", msg.Archive[2].Name)
+	assert.NotEmpty(t, ranges)
+	assert.Less(t, len(ranges)*int(archiveBlockSize), len(data), "only the central directory is read")
+
+	dir := t.TempDir()
+	require.NoError(t, client.ExtractMember(context.Background(), "bucket", "bundle.zip", "docs/big.txt", dir, nil))
+	got, err := os.ReadFile(filepath.Join(dir, "docs", "big.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, big, string(got))
+
+	require.NoError(t, client.ExtractMember(context.Background(), "bucket", "bundle.zip", "stored.txt", dir, nil))
+	got, err = os.ReadFile(filepath.Join(dir, "stored.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "stored", string(got))
+
+	assert.Error(t, client.ExtractMember(context.Background(), "bucket", "bundle.zip", "missing.txt", dir, nil))
+}
+
+func TestTarGzArchive(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	tw.WriteHeader(&tar.Header{Name: "./dir/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for name, content := range map[string]string{"./dir/a.txt": "aaa", "b.txt": "bb"} {
+		tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(content))})
+		tw.Write([]byte(content))
+	}
+	tw.WriteHeader(&tar.Header{Name: "link", Typeflag: tar.TypeSymlink, Linkname: "b.txt"})
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	var ranges []string
+	client := &S3Client{Client: rangedObject(buf.Bytes(), &ranges)}
+
+	msg := client.ListArchive(context.Background(), "bucket", "bundle.tgz")().(S3MenuMessage)
+	require.NoError(t, msg.APIMessage.Err)
+	names := make([]string, len(msg.Archive))
+	for i, m := range msg.Archive {
+		names[i] = m.Name
+	}
+	assert.ElementsMatch(t, []string{"dir/", "dir/a.txt", "b.txt"}, names)
+
+	dir := t.TempDir()
+	require.NoError(t, client.ExtractMember(context.Background(), "bucket", "bundle.tgz", "dir/a.txt", dir, nil))
+	got, err := os.ReadFile(filepath.Join(dir, "dir", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "aaa", string(got))
+	assert.Empty(t, ranges)
+}
+
+func TestExtractMemberRefusesPathsLeavingSaveDir(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	tw.WriteHeader(&tar.Header{Name: "../evil.txt", Typeflag: tar.TypeReg, Mode: 0o644, Size: 4})
+	tw.Write([]byte("evil"))
+	require.NoError(t, tw.Close())
+
+	var ranges []string
+	client := &S3Client{Client: rangedObject(buf.Bytes(), &ranges)}
+	root := t.TempDir()
+	dir := filepath.Join(root, "save")
+	require.NoError(t, os.Mkdir(dir, 0o755))
+
+	err := client.ExtractMember(context.Background(), "bucket", "bundle.tar", "../evil.txt", dir, nil)
+	assert.ErrorContains(t, err, "leaves the local directory")
+	assert.NoFileExists(t, filepath.Join(root, "evil.txt"))
+}
+
+func TestExtractZipMemberChecksumMismatch(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, err := zw.CreateRaw(&zip.FileHeader{Name: "bad.txt", Method: zip.Store, CRC32: 1, CompressedSize64: 3, UncompressedSize64: 3})
+	require.NoError(t, err)
+	w.Write([]byte("bad"))
+	require.NoError(t, zw.Close())
+
+	var ranges []string
+	client := &S3Client{Client: rangedObject(buf.Bytes(), &ranges)}
+	dir := t.TempDir()
+
+	err = client.ExtractMember(context.Background(), "bucket", "bundle.zip", "bad.txt", dir, nil)
+	assert.ErrorContains(t, err, "checksum mismatch")
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries, "a corrupt member leaves nothing behind")
+}
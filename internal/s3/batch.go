@@ -0,0 +1,221 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type BatchOperation int
+
+const (
+	BatchDelete BatchOperation = iota
+	BatchCopy
+	BatchTag
+	BatchStorageClass
+	BatchAbortMultipart
+)
+
+func (o BatchOperation) String() string {
+	switch o {
+	case BatchDelete:
+		return "delete"
+	case BatchCopy:
+		return "copy"
+	case BatchTag:
+		return "tag"
+	case BatchStorageClass:
+		return "storage class change"
+	case BatchAbortMultipart:
+		return "multipart abort"
+	}
+	return "unknown"
+}
+
+// deleteObjectsLimit is the maximum number of keys DeleteObjects accepts per call
+const deleteObjectsLimit = 1000
+
+// BatchInput describes one operation applied to a set of keys in a bucket
+type BatchInput struct {
+	Op           BatchOperation
+	Bucket       string
+	Keys         []string
+	CopyTargets  map[string]string // source key -> destination key, for BatchCopy
+	Tags         map[string]string // for BatchTag
+	StorageClass types.StorageClass
+}
+
+// BatchResult summarises a batch operation
+type BatchResult struct {
+	Op        BatchOperation
+	Succeeded int
+	Done      []string // keys the operation succeeded on
+	Failed    map[string]error
+}
+
+// Summary renders the result as a single status line
+func (r BatchResult) Summary() string {
+	total := r.Succeeded + len(r.Failed)
+	if len(r.Failed) == 0 {
+		return fmt.Sprintf("S3: %s of %d objects succeeded", r.Op, total)
+	}
+
+	keys := make([]string, 0, len(r.Failed))
+	for k := range r.Failed {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	details := make([]string, 0, len(keys))
+	for _, k := range keys {
+		details = append(details, fmt.Sprintf("%s: %v", k, r.Failed[k]))
+	}
+	return fmt.Sprintf("S3: %s of %d objects: %d succeeded, %d failed (%s)",
+		r.Op, total, r.Succeeded, len(r.Failed), strings.Join(details, "; "))
+}
+
+// RunBatch applies the operation to every key and reports a single summary
+func (c *S3Client) RunBatch(ctx context.Context, input BatchInput) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpBatch
+		mssg.Bucket = input.Bucket
+
+		result := BatchResult{Op: input.Op, Failed: make(map[string]error)}
+		switch input.Op {
+		case BatchDelete:
+			c.batchDelete(ctx, input, &result)
+		default:
+			for _, key := range input.Keys {
+				if err := c.batchOne(ctx, input, key); err != nil {
+					result.Failed[key] = err
+				} else {
+					result.Succeeded++
+					result.Done = append(result.Done, key)
+				}
+			}
+		}
+
+		mssg.Batch = result
+		if len(result.Failed) != 0 {
+			err := fmt.Errorf("%s", result.Summary())
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		mssg.APIMessage.Status = result.Summary()
+		return mssg, nil
+	})
+}
+
+func (c *S3Client) batchDelete(ctx context.Context, input BatchInput, result *BatchResult) {
+	for start := 0; start < len(input.Keys); start += deleteObjectsLimit {
+		end := min(start+deleteObjectsLimit, len(input.Keys))
+		chunk := input.Keys[start:end]
+
+		ids := make([]types.ObjectIdentifier, len(chunk))
+		for i, key := range chunk {
+			ids[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+		resp, err := c.clientFor(ctx, input.Bucket).DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(input.Bucket),
+			Delete: &types.Delete{Objects: ids, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			for _, key := range chunk {
+				result.Failed[key] = err
+			}
+			continue
+		}
+		for _, e := range resp.Errors {
+			result.Failed[aws.ToString(e.Key)] = fmt.Errorf("%s: %s", aws.ToString(e.Code), aws.ToString(e.Message))
+		}
+		for _, key := range chunk {
+			if _, failed := result.Failed[key]; !failed {
+				result.Done = append(result.Done, key)
+			}
+		}
+		result.Succeeded += len(chunk) - len(resp.Errors)
+	}
+}
+
+func (c *S3Client) batchOne(ctx context.Context, input BatchInput, key string) error {
+	switch input.Op {
+	case BatchCopy:
+		dest, ok := input.CopyTargets[key]
+		if !ok {
+			return fmt.Errorf("no copy destination")
+		}
+		_, err := c.clientFor(ctx, input.Bucket).CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(input.Bucket),
+			Key:        aws.String(dest),
+			CopySource: aws.String(copySource(input.Bucket, key)),
+		})
+		return err
+
+	case BatchTag:
+		tagSet := make([]types.Tag, 0, len(input.Tags))
+		for k, v := range input.Tags {
+			tagSet = append(tagSet, types.Tag{Key: aws.String(k), Value: aws.String(v)})
+		}
+		_, err := c.clientFor(ctx, input.Bucket).PutObjectTagging(ctx, &s3.PutObjectTaggingInput{
+			Bucket:  aws.String(input.Bucket),
+			Key:     aws.String(key),
+			Tagging: &types.Tagging{TagSet: tagSet},
+		})
+		return err
+
+	case BatchStorageClass:
+		// S3 changes the storage class of an object by copying it onto itself
+		_, err := c.clientFor(ctx, input.Bucket).CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:            aws.String(input.Bucket),
+			Key:               aws.String(key),
+			CopySource:        aws.String(copySource(input.Bucket, key)),
+			StorageClass:      input.StorageClass,
+			MetadataDirective: types.MetadataDirectiveCopy,
+		})
+		return err
+	}
+	return fmt.Errorf("unsupported batch operation %s", input.Op)
+}
+
+// copySource builds the url encoded bucket/key CopyObject expects
+func copySource(bucket, key string) string {
+	return url.PathEscape(bucket) + "/" + strings.ReplaceAll(url.PathEscape(key), "%2F", "/")
+}
+
+// ParseTags parses "key=value,key2=value2" into a tag map
+func ParseTags(s string) (map[string]string, error) {
+	tags := make(map[string]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		k, v, found := strings.Cut(pair, "=")
+		if !found || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid tag %q, expected key=value", pair)
+		}
+		tags[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	if len(tags) == 0 {
+		return nil, fmt.Errorf("no tags given")
+	}
+	return tags, nil
+}
+
+// ParseStorageClass validates a user entered storage class
+func ParseStorageClass(s string) (types.StorageClass, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	for _, class := range types.StorageClassStandard.Values() {
+		if string(class) == s {
+			return class, nil
+		}
+	}
+	return "", fmt.Errorf("unknown storage class %q", s)
+}
@@ -0,0 +1,41 @@
+package s3
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	tags, err := ParseTags("env=prod, team = data")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"env": "prod", "team": "data"}, tags)
+
+	_, err = ParseTags("novalue")
+	assert.Error(t, err)
+	_, err = ParseTags("")
+	assert.Error(t, err)
+}
+
+func TestParseStorageClass(t *testing.T) {
+	class, err := ParseStorageClass("standard_ia")
+	assert.NoError(t, err)
+	assert.Equal(t, types.StorageClassStandardIa, class)
+
+	_, err = ParseStorageClass("cold")
+	assert.Error(t, err)
+}
+
+func TestBatchResultSummary(t *testing.T) {
+	ok := BatchResult{Op: BatchDelete, Succeeded: 3, Failed: map[string]error{}}
+	assert.Equal(t, "S3: delete of 3 objects succeeded", ok.Summary())
+
+	partial := BatchResult{Op: BatchCopy, Succeeded: 1, Failed: map[string]error{"a.txt": errors.New("denied")}}
+	assert.Equal(t, "S3: copy of 2 objects: 1 succeeded, 1 failed (a.txt: denied)", partial.Summary())
+}
+
+func TestCopySource(t *testing.T) {
+	assert.Equal(t, "bucket/dir/my%20file.txt", copySource("bucket", "dir/my file.txt"))
+}
@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
@@ -17,7 +18,9 @@ import (
 )
 
 type S3Client struct {
-	Client *s3.Client
+	// Client serves calls that are not tied to a bucket, in the configured region
+	Client S3SDK
+	pool   *regionPool
 }
 type S3OperationType int
 
@@ -29,6 +32,16 @@ const (
 	S3OpPutObject
 	S3OpDeleteObject
 	S3OpGetObjectMetadata
+	S3OpPlanSync
+	S3OpBatch
+	S3OpListMultipartUploads
+	S3OpAbortMultipartUploads
+	S3OpGetObjectLock
+	S3OpPutObjectRetention
+	S3OpPutObjectLegalHold
+	S3OpCreateFolder
+	S3OpDiff
+	S3OpListArchive
 )
 
 type S3ObjectMetadata struct {
@@ -40,15 +53,27 @@ type S3ObjectMetadata struct {
 	ETag          string
 	StorageClass  types.StorageClass
 	Metadata      map[string]string
+	LockMode      types.ObjectLockMode // empty when the object has no retention
+	RetainUntil   time.Time
+	LegalHold     types.ObjectLockLegalHoldStatus
 }
 
 type S3MenuMessage struct {
 	Op         S3OperationType
 	APIMessage internal.APIMessage
-	Buckets    []types.Bucket // for ListBuckets
-	Objects    []string       // for ListObjects
+	Buckets    []types.Bucket   // for ListBuckets
+	Objects    []string         // for ListObjects
+	Sizes      map[string]int64 // for ListObjects, by key
 	Bucket     string
+	Key        string // for DeleteObject, CreateFolder and ListArchive
 	Metadata   S3ObjectMetadata
+	SyncPlan   []internal.SyncAction // for PlanSync
+	Batch      BatchResult           // for RunBatch and AbortMultipartUploads
+	Uploads    []MultipartUpload     // for ListMultipartUploads
+	ObjectLock BucketObjectLock      // for GetBucketObjectLock
+	Diff       []internal.DiffEntry  // for Diff
+	DiffCounts internal.DiffCounts   // for Diff
+	Archive    []ArchiveMember       // for ListArchive
 }
 
 func (c *S3Client) NewMessage() S3MenuMessage {
@@ -80,6 +105,9 @@ func (c *S3Client) ListBuckets(ctx context.Context, input *s3.ListBucketsInput)
 		}
 
 		mssg.Buckets = output.Buckets
+		for _, bucket := range output.Buckets {
+			c.pool.remember(aws.ToString(bucket.Name), aws.ToString(bucket.BucketRegion))
+		}
 		return mssg, err
 	})
 }
@@ -105,7 +133,7 @@ func (c *S3Client) CreateBucket(ctx context.Context, input *s3.CreateBucketInput
 
 func (c *S3Client) ListObjects(ctx context.Context, input *s3.ListObjectsV2Input) tea.Cmd {
 	return c.Wrapper(func() (any, error) {
-		resp, err := c.Client.ListObjectsV2(ctx, input)
+		resp, err := c.clientFor(ctx, aws.ToString(input.Bucket)).ListObjectsV2(ctx, input)
 		mssg := c.NewMessage()
 		mssg.APIMessage = internal.APIMessage{
 			Response: resp,
@@ -118,8 +146,10 @@ func (c *S3Client) ListObjects(ctx context.Context, input *s3.ListObjectsV2Input
 		}
 
 		var objs []string
+		mssg.Sizes = make(map[string]int64, len(resp.Contents))
 		for _, obj := range resp.Contents {
 			objs = append(objs, *obj.Key)
+			mssg.Sizes[*obj.Key] = aws.ToInt64(obj.Size)
 		}
 		mssg.Objects = objs
 
@@ -130,65 +160,111 @@ func (c *S3Client) ListObjects(ctx context.Context, input *s3.ListObjectsV2Input
 // PutObject uploads a file to S3. filePath is relative to the current working directory of the TUI
 func (c *S3Client) PutObject(ctx context.Context, input *s3.PutObjectInput, filePath string) tea.Cmd {
 	return c.Wrapper(func() (any, error) {
-		//TODO: handle large objects
-
 		mssg := c.NewMessage()
-		mssg.APIMessage = internal.APIMessage{}
-		file, err := os.Open(filePath)
-		if err != nil {
-			mssg.APIMessage.Err = err
-			return mssg, err
-		}
-
-		defer file.Close()
-		input.Body = file
-
-		resp, err := c.Client.PutObject(ctx, input)
-		mssg.APIMessage.Response = resp
+		mssg.Op = S3OpPutObject
+		err := c.Upload(ctx, input, filePath, nil)
 		mssg.APIMessage.Err = err
 		mssg.APIMessage.Status = fmt.Sprintf("Uploaded %s/%s successfully", *input.Bucket, *input.Key)
-		mssg.Op = S3OpPutObject
 
 		return mssg, err
 	})
 }
 
-func (c *S3Client) GetObject(ctx context.Context, input *s3.GetObjectInput, savePath string) tea.Cmd {
-	return c.Wrapper(func() (any, error) {
-		//TODO: handle large objects
-		resp, err := c.Client.GetObject(ctx, input)
-		mssg := c.NewMessage()
-		mssg.APIMessage = internal.APIMessage{
-			Response: resp,
-			Err:      err,
-			Status:   fmt.Sprintf("Fetched %s/%s successfully", *input.Bucket, *input.Key),
-		}
-		mssg.Op = S3OpGetObject
-
+// Upload sends the file at filePath to S3, reporting progress to tracker.
+// The content type is detected unless input sets one, and the file is
+// compressed when input.ContentEncoding is gzip or br
+func (c *S3Client) Upload(ctx context.Context, input *s3.PutObjectInput, filePath string, tracker *transfer.Tracker) error {
+	//TODO: handle large objects
+	if input.ContentType == nil {
+		contentType, err := DetectContentType(filePath)
 		if err != nil {
-			return mssg, err
+			return err
 		}
+		input.ContentType = aws.String(contentType)
+	}
 
-		defer resp.Body.Close()
-
-		_, tail := splitLast(*input.Key, "/")
-		outFile, err := os.Create(filepath.Join(savePath, tail))
+	var file *os.File
+	var err error
+	if encoding := aws.ToString(input.ContentEncoding); encoding != "" {
+		file, err = compressFile(filePath, encoding)
 		if err != nil {
-			mssg.APIMessage.Err = err
-			return mssg, err
+			return err
 		}
-		defer outFile.Close()
-
-		_, err = io.Copy(outFile, resp.Body)
+		defer os.Remove(file.Name())
+	} else {
+		file, err = os.Open(filePath)
 		if err != nil {
-			mssg.APIMessage.Err = err
-			return mssg, err
+			return err
 		}
+	}
+	defer file.Close()
+
+	if info, err := file.Stat(); err == nil {
+		tracker.SetTotal(info.Size())
+	}
+	input.Body = tracker.ReadSeeker(file)
+
+	_, err = c.clientFor(ctx, aws.ToString(input.Bucket)).PutObject(ctx, input)
+	return err
+}
+
+func (c *S3Client) GetObject(ctx context.Context, input *s3.GetObjectInput, savePath string) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpGetObject
+		err := c.Download(ctx, input, savePath, nil)
+		mssg.APIMessage.Err = err
+		mssg.APIMessage.Status = fmt.Sprintf("Fetched %s/%s successfully", *input.Bucket, *input.Key)
 
 		return mssg, err
 	})
 }
 
+// Download saves the object into the savePath directory, reporting progress to tracker
+func (c *S3Client) Download(ctx context.Context, input *s3.GetObjectInput, savePath string, tracker *transfer.Tracker) error {
+	//TODO: handle large objects
+	resp, err := c.clientFor(ctx, aws.ToString(input.Bucket)).GetObject(ctx, input)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.ContentLength != nil {
+		tracker.SetTotal(*resp.ContentLength)
+	}
+
+	_, tail := splitLast(*input.Key, "/")
+	if tail == "" {
+		tail = *input.Key
+	}
+	return saveFile(filepath.Join(savePath, tail), tracker.Reader(resp.Body))
+}
+
+// saveFile writes r to a temporary file next to path and renames it into
+// place once all of r was read, so a failed or cancelled transfer never
+// leaves a partial file under the real name
+func saveFile(path string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.part")
+	if err != nil {
+		return err
+	}
+	// temporary files are private, downloads are not
+	err = tmp.Chmod(0o644)
+	if err == nil {
+		_, err = io.Copy(tmp, r)
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+	return err
+}
+
 // splitLast splits a string s into two parts at the last occurrence of sep
 func splitLast(s, sep string) (string, string) {
 	idx := strings.LastIndex(s, sep)
@@ -200,13 +276,14 @@ func splitLast(s, sep string) (string, string) {
 
 func (c *S3Client) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) tea.Cmd {
 	return c.Wrapper(func() (any, error) {
-		resp, err := c.Client.DeleteObject(ctx, input)
+		resp, err := c.clientFor(ctx, aws.ToString(input.Bucket)).DeleteObject(ctx, input)
 		mssg := c.NewMessage()
 		mssg.APIMessage = internal.APIMessage{
 			Response: resp,
 			Err:      err,
 		}
 		mssg.Op = S3OpDeleteObject
+		mssg.Key = aws.ToString(input.Key)
 		if err != nil {
 			return mssg, err
 		}
@@ -216,9 +293,38 @@ func (c *S3Client) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput
 	})
 }
 
+// CreateFolder creates the zero-byte "folder/" marker object for key
+func (c *S3Client) CreateFolder(ctx context.Context, bucket, key string) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		if !strings.HasSuffix(key, "/") {
+			key += "/"
+		}
+		resp, err := c.clientFor(ctx, bucket).PutObject(ctx, &s3.PutObjectInput{
+			Bucket:        aws.String(bucket),
+			Key:           aws.String(key),
+			Body:          strings.NewReader(""),
+			ContentLength: aws.Int64(0),
+		})
+		mssg := c.NewMessage()
+		mssg.APIMessage = internal.APIMessage{
+			Response: resp,
+			Err:      err,
+		}
+		mssg.Op = S3OpCreateFolder
+		mssg.Bucket = bucket
+		mssg.Key = key
+		if err != nil {
+			return mssg, err
+		}
+
+		mssg.APIMessage.Status = fmt.Sprintf("Created folder %s/%s", bucket, key)
+		return mssg, err
+	})
+}
+
 func (c *S3Client) GetObjectMetadata(ctx context.Context, input *s3.HeadObjectInput) tea.Cmd {
 	return c.Wrapper(func() (any, error) {
-		resp, err := c.Client.HeadObject(ctx, input)
+		resp, err := c.clientFor(ctx, aws.ToString(input.Bucket)).HeadObject(ctx, input)
 		mssg := c.NewMessage()
 		mssg.APIMessage = internal.APIMessage{
 			Response: resp,
@@ -239,6 +345,9 @@ func (c *S3Client) GetObjectMetadata(ctx context.Context, input *s3.HeadObjectIn
 			ETag:          aws.ToString(resp.ETag),
 			StorageClass:  resp.StorageClass,
 			Metadata:      resp.Metadata,
+			LockMode:      resp.ObjectLockMode,
+			RetainUntil:   aws.ToTime(resp.ObjectLockRetainUntilDate),
+			LegalHold:     resp.ObjectLockLegalHoldStatus,
 		}
 		mssg.Metadata = metadata
 
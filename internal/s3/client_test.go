@@ -8,6 +8,7 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"testing/iotest"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
@@ -18,13 +19,26 @@ import (
 
 // Mock S3 client
 type mockS3 struct {
-	ListBucketsFunc   func(ctx context.Context, input *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
-	CreateBucketFunc  func(ctx context.Context, input *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
-	ListObjectsV2Func func(ctx context.Context, input *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
-	PutObjectFunc     func(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
-	GetObjectFunc     func(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
-	DeleteObjectFunc  func(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
-	HeadObjectFunc    func(ctx context.Context, input *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	ListBucketsFunc       func(ctx context.Context, input *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+	CreateBucketFunc      func(ctx context.Context, input *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+	ListObjectsV2Func     func(ctx context.Context, input *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	PutObjectFunc         func(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObjectFunc         func(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObjectFunc      func(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	HeadObjectFunc        func(ctx context.Context, input *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	HeadBucketFunc        func(ctx context.Context, input *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	GetBucketLocationFunc func(ctx context.Context, input *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+	DeleteObjectsFunc     func(ctx context.Context, input *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	CopyObjectFunc        func(ctx context.Context, input *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	PutObjectTaggingFunc  func(ctx context.Context, input *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+
+	ListMultipartUploadsFunc func(ctx context.Context, input *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
+	ListPartsFunc            func(ctx context.Context, input *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	AbortMultipartUploadFunc func(ctx context.Context, input *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+
+	GetObjectLockConfigurationFunc func(ctx context.Context, input *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+	PutObjectRetentionFunc         func(ctx context.Context, input *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error)
+	PutObjectLegalHoldFunc         func(ctx context.Context, input *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error)
 }
 
 func (m *mockS3) ListBuckets(ctx context.Context, input *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
@@ -48,6 +62,39 @@ func (m *mockS3) DeleteObject(ctx context.Context, input *s3.DeleteObjectInput,
 func (m *mockS3) HeadObject(ctx context.Context, input *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
 	return m.HeadObjectFunc(ctx, input, optFns...)
 }
+func (m *mockS3) HeadBucket(ctx context.Context, input *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+	return m.HeadBucketFunc(ctx, input, optFns...)
+}
+func (m *mockS3) GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+	return m.GetBucketLocationFunc(ctx, input, optFns...)
+}
+func (m *mockS3) DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error) {
+	return m.DeleteObjectsFunc(ctx, input, optFns...)
+}
+func (m *mockS3) CopyObject(ctx context.Context, input *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+	return m.CopyObjectFunc(ctx, input, optFns...)
+}
+func (m *mockS3) PutObjectTagging(ctx context.Context, input *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error) {
+	return m.PutObjectTaggingFunc(ctx, input, optFns...)
+}
+func (m *mockS3) ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+	return m.ListMultipartUploadsFunc(ctx, input, optFns...)
+}
+func (m *mockS3) ListParts(ctx context.Context, input *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+	return m.ListPartsFunc(ctx, input, optFns...)
+}
+func (m *mockS3) AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+	return m.AbortMultipartUploadFunc(ctx, input, optFns...)
+}
+func (m *mockS3) GetObjectLockConfiguration(ctx context.Context, input *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	return m.GetObjectLockConfigurationFunc(ctx, input, optFns...)
+}
+func (m *mockS3) PutObjectRetention(ctx context.Context, input *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+	return m.PutObjectRetentionFunc(ctx, input, optFns...)
+}
+func (m *mockS3) PutObjectLegalHold(ctx context.Context, input *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error) {
+	return m.PutObjectLegalHoldFunc(ctx, input, optFns...)
+}
 
 func TestListBuckets(t *testing.T) {
 	mock := &mockS3{
@@ -139,7 +186,7 @@ func TestGetObject_FileWrite(t *testing.T) {
 		GetObjectFunc: func(ctx context.Context, input *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
 			return &s3.GetObjectOutput{
 				Body:          io.NopCloser(bytes.NewReader([]byte("data"))),
-				ContentLength: 4,
+				ContentLength: aws.Int64(4),
 			}, nil
 		},
 	}
@@ -156,6 +203,24 @@ func TestGetObject_FileWrite(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestDownloadLeavesNoPartialFile(t *testing.T) {
+	tmpdir := t.TempDir()
+	mock := &mockS3{
+		GetObjectFunc: func(ctx context.Context, input *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			body := io.MultiReader(bytes.NewReader([]byte("partial")), iotest.ErrReader(errors.New("connection reset")))
+			return &s3.GetObjectOutput{Body: io.NopCloser(body), ContentLength: aws.Int64(100)}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+	err := client.Download(context.Background(), &s3.GetObjectInput{
+		Bucket: aws.String("bucket"),
+		Key:    aws.String("foo/bar.txt"),
+	}, tmpdir, nil)
+	assert.ErrorContains(t, err, "connection reset")
+	entries, _ := os.ReadDir(tmpdir)
+	assert.Empty(t, entries, "neither the file nor its temporary copy is left behind")
+}
+
 func TestGetObject_Error(t *testing.T) {
 	mock := &mockS3{
 		GetObjectFunc: func(ctx context.Context, input *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DiffSide is one bucket prefix of a comparison, listed with its own client
+// so the two sides can belong to different profiles
+type DiffSide struct {
+	Client S3API
+	Bucket string
+	Prefix string // no leading or trailing "/"
+}
+
+func (s DiffSide) String() string {
+	if s.Prefix == "" {
+		return s.Bucket
+	}
+	return s.Bucket + "/" + s.Prefix
+}
+
+func (s DiffSide) key(rel string) string {
+	return SyncInput{Prefix: s.Prefix}.remoteKey(rel)
+}
+
+// DiffInput identifies both sides of a comparison
+type DiffInput struct {
+	Left  DiffSide
+	Right DiffSide
+}
+
+// ListFiles lists every object under the prefix with paths relative to it
+func (c *S3Client) ListFiles(ctx context.Context, bucket, prefix string) ([]internal.SyncFile, error) {
+	return c.listSyncFiles(ctx, bucket, prefix)
+}
+
+// Head returns the metadata of an object
+func (c *S3Client) Head(ctx context.Context, bucket, key string) (*s3.HeadObjectOutput, error) {
+	return c.clientFor(ctx, bucket).HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucket),
+		Key:    aws.String(key),
+	})
+}
+
+// Diff lists both sides and reports the keys that are missing or differ
+func (c *S3Client) Diff(ctx context.Context, input DiffInput) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpDiff
+
+		left, err := input.Left.Client.ListFiles(ctx, input.Left.Bucket, input.Left.Prefix)
+		if err != nil {
+			mssg.APIMessage.Err = fmt.Errorf("listing %s: %w", input.Left, err)
+			return mssg, mssg.APIMessage.Err
+		}
+		right, err := input.Right.Client.ListFiles(ctx, input.Right.Bucket, input.Right.Prefix)
+		if err != nil {
+			mssg.APIMessage.Err = fmt.Errorf("listing %s: %w", input.Right, err)
+			return mssg, mssg.APIMessage.Err
+		}
+
+		mssg.Diff, mssg.DiffCounts = internal.DiffListings(left, right)
+		mssg.APIMessage.Status = fmt.Sprintf("Compared %s with %s: %d differences",
+			input.Left, input.Right, len(mssg.Diff))
+		return mssg, nil
+	})
+}
+
+// CopyJob returns the transfer copying one path from one side of a
+// comparison to the other, to be queued on the transfer manager.
+// Sides sharing a client are copied server side, otherwise the object is
+// downloaded to a temporary directory and uploaded with the other client,
+// keeping the content headers and user metadata of the source
+func (c *S3Client) CopyJob(from, to DiffSide, rel string) transfer.RunFunc {
+	return func(ctx context.Context, tracker *transfer.Tracker) error {
+		return copyAcross(ctx, from, to, rel, tracker)
+	}
+}
+
+func copyAcross(ctx context.Context, from, to DiffSide, rel string, tracker *transfer.Tracker) error {
+	if client, ok := to.Client.(*S3Client); ok && from.Client == to.Client {
+		_, err := client.clientFor(ctx, to.Bucket).CopyObject(ctx, &s3.CopyObjectInput{
+			Bucket:     aws.String(to.Bucket),
+			Key:        aws.String(to.key(rel)),
+			CopySource: aws.String(copySource(from.Bucket, from.key(rel))),
+		})
+		return err
+	}
+
+	dir, err := os.MkdirTemp("", "fuzzy-guacamole-copy-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+
+	srcKey := from.key(rel)
+	head, err := from.Client.Head(ctx, from.Bucket, srcKey)
+	if err != nil {
+		return err
+	}
+	if err := from.Client.Download(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(from.Bucket),
+		Key:    aws.String(srcKey),
+	}, dir, tracker); err != nil {
+		return err
+	}
+	_, name := splitLast("/"+srcKey, "/")
+	path := filepath.Join(dir, name)
+	if encoding := aws.ToString(head.ContentEncoding); encoding != "" {
+		// the download keeps the encoded bytes, which Upload encodes again
+		plain := filepath.Join(dir, "plain")
+		if err := os.Mkdir(plain, 0o700); err != nil {
+			return err
+		}
+		if err := decompressFile(path, filepath.Join(plain, name), encoding); err != nil {
+			return err
+		}
+		path = filepath.Join(plain, name)
+	}
+	return to.Client.Upload(ctx, &s3.PutObjectInput{
+		Bucket:             aws.String(to.Bucket),
+		Key:                aws.String(to.key(rel)),
+		ContentType:        head.ContentType,
+		ContentEncoding:    head.ContentEncoding,
+		ContentDisposition: head.ContentDisposition,
+		ContentLanguage:    head.ContentLanguage,
+		CacheControl:       head.CacheControl,
+		Metadata:           head.Metadata,
+	}, path, tracker)
+}
@@ -0,0 +1,139 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func listing(objects ...types.Object) func(context.Context, *s3.ListObjectsV2Input, ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	return func(ctx context.Context, input *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+		return &s3.ListObjectsV2Output{Contents: objects}, nil
+	}
+}
+
+func TestDiff(t *testing.T) {
+	left := &S3Client{Client: &mockS3{ListObjectsV2Func: listing(
+		types.Object{Key: aws.String("src/a.txt"), Size: aws.Int64(1), ETag: aws.String("\"a\"")},
+		types.Object{Key: aws.String("src/b.txt"), Size: aws.Int64(1), ETag: aws.String("\"b\"")},
+	)}}
+	right := &S3Client{Client: &mockS3{ListObjectsV2Func: listing(
+		types.Object{Key: aws.String("b.txt"), Size: aws.Int64(1), ETag: aws.String("\"changed\"")},
+		types.Object{Key: aws.String("c.txt"), Size: aws.Int64(1), ETag: aws.String("\"c\"")},
+	)}}
+
+	msg := left.Diff(context.Background(), DiffInput{
+		Left:  DiffSide{Client: left, Bucket: "one", Prefix: "src"},
+		Right: DiffSide{Client: right, Bucket: "two"},
+	})().(S3MenuMessage)
+	assert.NoError(t, msg.APIMessage.Err)
+	assert.Equal(t, S3OpDiff, msg.Op)
+	assert.Len(t, msg.Diff, 3)
+	assert.Equal(t, 1, msg.DiffCounts.LeftOnly)
+	assert.Equal(t, 1, msg.DiffCounts.RightOnly)
+	assert.Equal(t, 1, msg.DiffCounts.Changed)
+}
+
+func TestCopyJobSameClient(t *testing.T) {
+	var sources, keys []string
+	client := &S3Client{Client: &mockS3{
+		CopyObjectFunc: func(ctx context.Context, input *s3.CopyObjectInput, _ ...func(*s3.Options)) (*s3.CopyObjectOutput, error) {
+			sources = append(sources, aws.ToString(input.CopySource))
+			keys = append(keys, aws.ToString(input.Key))
+			return &s3.CopyObjectOutput{}, nil
+		},
+	}}
+
+	err := client.CopyJob(
+		DiffSide{Client: client, Bucket: "one", Prefix: "src"},
+		DiffSide{Client: client, Bucket: "two", Prefix: "dst"},
+		"a b.txt")(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"one/src/a%20b.txt"}, sources)
+	assert.Equal(t, []string{"dst/a b.txt"}, keys)
+}
+
+func TestCopyJobClients(t *testing.T) {
+	from := &S3Client{Client: &mockS3{
+		HeadObjectFunc: func(ctx context.Context, input *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{}, nil
+		},
+		GetObjectFunc: func(ctx context.Context, input *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			assert.Equal(t, "dir/a.txt", aws.ToString(input.Key))
+			return &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader("hello"))}, nil
+		},
+	}}
+	var uploaded string
+	to := &S3Client{Client: &mockS3{
+		PutObjectFunc: func(ctx context.Context, input *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			assert.Equal(t, "dir/a.txt", aws.ToString(input.Key))
+			body, err := io.ReadAll(input.Body)
+			assert.NoError(t, err)
+			uploaded = string(body)
+			return &s3.PutObjectOutput{}, nil
+		},
+	}}
+
+	err := from.CopyJob(
+		DiffSide{Client: from, Bucket: "one"},
+		DiffSide{Client: to, Bucket: "two"},
+		"dir/a.txt")(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", uploaded)
+}
+
+func TestCopyJobClientsKeepsHeaders(t *testing.T) {
+	var encoded bytes.Buffer
+	gz := gzip.NewWriter(&encoded)
+	gz.Write([]byte("<p>hello</p>"))
+	gz.Close()
+
+	from := &S3Client{Client: &mockS3{
+		HeadObjectFunc: func(ctx context.Context, input *s3.HeadObjectInput, _ ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+			return &s3.HeadObjectOutput{
+				ContentType:     aws.String("text/html"),
+				ContentEncoding: aws.String("gzip"),
+				CacheControl:    aws.String("max-age=60"),
+				Metadata:        map[string]string{"owner": "web"},
+			}, nil
+		},
+		GetObjectFunc: func(ctx context.Context, input *s3.GetObjectInput, _ ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+			return &s3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(encoded.Bytes()))}, nil
+		},
+	}}
+	var put *s3.PutObjectInput
+	var uploaded string
+	to := &S3Client{Client: &mockS3{
+		PutObjectFunc: func(ctx context.Context, input *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			put = input
+			r, err := gzip.NewReader(input.Body)
+			if assert.NoError(t, err, "the body is encoded once") {
+				body, err := io.ReadAll(r)
+				assert.NoError(t, err)
+				uploaded = string(body)
+			}
+			return &s3.PutObjectOutput{}, nil
+		},
+	}}
+
+	err := from.CopyJob(
+		DiffSide{Client: from, Bucket: "one"},
+		DiffSide{Client: to, Bucket: "two"},
+		"site/index.bin")(context.Background(), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "<p>hello</p>", uploaded)
+	if assert.NotNil(t, put) {
+		assert.Equal(t, "text/html", aws.ToString(put.ContentType), "the source type wins over detection")
+		assert.Equal(t, "gzip", aws.ToString(put.ContentEncoding))
+		assert.Equal(t, "max-age=60", aws.ToString(put.CacheControl))
+		assert.Equal(t, map[string]string{"owner": "web"}, put.Metadata)
+	}
+}
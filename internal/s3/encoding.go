@@ -0,0 +1,107 @@
+package s3
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/andybalholm/brotli"
+)
+
+// sniffLen is the number of bytes http.DetectContentType looks at
+const sniffLen = 512
+
+// DetectContentType guesses the MIME type of a file from its extension,
+// falling back to sniffing its first bytes
+func DetectContentType(path string) (string, error) {
+	if t := mime.TypeByExtension(filepath.Ext(path)); t != "" {
+		return t, nil
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	return http.DetectContentType(head[:n]), nil
+}
+
+// compressFile writes the file compressed with encoding into a temporary
+// file, positioned at its start. The caller removes the file
+func compressFile(path, encoding string) (*os.File, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	tmp, err := os.CreateTemp("", "upload-*")
+	if err != nil {
+		return nil, err
+	}
+	cleanup := func() {
+		tmp.Close()
+		os.Remove(tmp.Name())
+	}
+
+	var w io.WriteCloser
+	switch encoding {
+	case internal.EncodingGzip:
+		w = gzip.NewWriter(tmp)
+	case internal.EncodingBrotli:
+		w = brotli.NewWriter(tmp)
+	default:
+		cleanup()
+		return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+
+	if _, err := io.Copy(w, src); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		cleanup()
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		cleanup()
+		return nil, err
+	}
+	return tmp, nil
+}
+
+// decompressFile writes src, encoded with encoding, decoded to dst
+func decompressFile(src, dst, encoding string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var r io.Reader
+	switch encoding {
+	case internal.EncodingGzip:
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	case internal.EncodingBrotli:
+		r = brotli.NewReader(file)
+	default:
+		return fmt.Errorf("unsupported content encoding %q", encoding)
+	}
+	return saveFile(dst, r)
+}
@@ -0,0 +1,74 @@
+package s3
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/andybalholm/brotli"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectContentType(t *testing.T) {
+	dir := t.TempDir()
+	html := filepath.Join(dir, "index.html")
+	noExt := filepath.Join(dir, "image")
+	assert.NoError(t, os.WriteFile(html, []byte("<p>hi</p>"), 0o644))
+	assert.NoError(t, os.WriteFile(noExt, []byte("\x89PNG\r\n\x1a\nrest"), 0o644))
+
+	contentType, err := DetectContentType(html)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", contentType)
+
+	contentType, err = DetectContentType(noExt)
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", contentType, "sniffed from the content")
+}
+
+func TestUploadCompressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.js")
+	content := bytes.Repeat([]byte("console.log('hi');\n"), 100)
+	assert.NoError(t, os.WriteFile(path, content, 0o644))
+
+	var got *s3.PutObjectInput
+	var body []byte
+	mock := &mockS3{
+		PutObjectFunc: func(ctx context.Context, input *s3.PutObjectInput, _ ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			got = input
+			body, _ = io.ReadAll(input.Body)
+			return &s3.PutObjectOutput{}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+
+	err := client.Upload(context.Background(), &s3.PutObjectInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("app.js"),
+		ContentEncoding: aws.String(internal.EncodingGzip),
+	}, path, nil)
+	assert.NoError(t, err)
+	assert.Contains(t, aws.ToString(got.ContentType), "javascript")
+	assert.Less(t, len(body), len(content))
+	r, err := gzip.NewReader(bytes.NewReader(body))
+	assert.NoError(t, err)
+	plain, _ := io.ReadAll(r)
+	assert.Equal(t, content, plain)
+
+	err = client.Upload(context.Background(), &s3.PutObjectInput{
+		Bucket:          aws.String("bucket"),
+		Key:             aws.String("app.js"),
+		ContentType:     aws.String("text/plain"),
+		ContentEncoding: aws.String(internal.EncodingBrotli),
+	}, path, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "text/plain", aws.ToString(got.ContentType), "explicit type is kept")
+	plain, _ = io.ReadAll(brotli.NewReader(bytes.NewReader(body)))
+	assert.Equal(t, content, plain)
+}
@@ -3,6 +3,8 @@ package s3
 import (
 	"context"
 
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	tea "github.com/charmbracelet/bubbletea"
 )
@@ -10,9 +12,26 @@ import (
 type S3API interface {
 	PutObject(ctx context.Context, input *s3.PutObjectInput, filePath string) tea.Cmd
 	GetObject(ctx context.Context, input *s3.GetObjectInput, savePath string) tea.Cmd
+	Upload(ctx context.Context, input *s3.PutObjectInput, filePath string, tracker *transfer.Tracker) error
+	Download(ctx context.Context, input *s3.GetObjectInput, savePath string, tracker *transfer.Tracker) error
 	GetObjectMetadata(ctx context.Context, input *s3.HeadObjectInput) tea.Cmd
 	DeleteObject(ctx context.Context, input *s3.DeleteObjectInput) tea.Cmd
+	CreateFolder(ctx context.Context, bucket, key string) tea.Cmd
 	ListBuckets(ctx context.Context, input *s3.ListBucketsInput) tea.Cmd
 	CreateBucket(ctx context.Context, input *s3.CreateBucketInput) tea.Cmd
 	ListObjects(ctx context.Context, input *s3.ListObjectsV2Input) tea.Cmd
+	PlanSync(ctx context.Context, input SyncInput) tea.Cmd
+	SyncJob(input SyncInput, action internal.SyncAction) transfer.RunFunc
+	RunBatch(ctx context.Context, input BatchInput) tea.Cmd
+	ListMultipartUploads(ctx context.Context, bucket string) tea.Cmd
+	AbortMultipartUploads(ctx context.Context, bucket string, uploads []MultipartUpload) tea.Cmd
+	GetBucketObjectLock(ctx context.Context, bucket string) tea.Cmd
+	SetObjectRetention(ctx context.Context, input RetentionInput) tea.Cmd
+	SetObjectLegalHold(ctx context.Context, bucket, key string, on bool) tea.Cmd
+	ListFiles(ctx context.Context, bucket, prefix string) ([]internal.SyncFile, error)
+	Head(ctx context.Context, bucket, key string) (*s3.HeadObjectOutput, error)
+	Diff(ctx context.Context, input DiffInput) tea.Cmd
+	CopyJob(from, to DiffSide, rel string) transfer.RunFunc
+	ListArchive(ctx context.Context, bucket, key string) tea.Cmd
+	ExtractMember(ctx context.Context, bucket, key, member, savePath string, tracker *transfer.Tracker) error
 }
@@ -0,0 +1,56 @@
+package s3
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go/middleware"
+)
+
+// WithLogger logs every S3 call at debug level with its bucket, key, region,
+// duration and error
+func WithLogger(logger *slog.Logger) func(*s3.Options) {
+	return func(o *s3.Options) {
+		o.APIOptions = append(o.APIOptions, func(stack *middleware.Stack) error {
+			return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("AppLogger",
+				func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+					start := time.Now()
+					out, metadata, err := next.HandleInitialize(ctx, in)
+					attrs := []any{
+						"operation", awsmiddleware.GetOperationName(ctx),
+						"region", awsmiddleware.GetRegion(ctx),
+						"duration", time.Since(start),
+					}
+					for _, name := range []string{"Bucket", "Key", "Prefix"} {
+						if v := stringField(in.Parameters, name); v != "" {
+							attrs = append(attrs, strings.ToLower(name), v)
+						}
+					}
+					if err != nil {
+						logger.DebugContext(ctx, "s3 call failed", append(attrs, "error", err)...)
+					} else {
+						logger.DebugContext(ctx, "s3 call", attrs...)
+					}
+					return out, metadata, err
+				}), middleware.After)
+		})
+	}
+}
+
+// stringField reads a *string field of an SDK input struct, "" when it has none
+func stringField(input any, name string) string {
+	v := reflect.Indirect(reflect.ValueOf(input))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+	field := v.FieldByName(name)
+	if !field.IsValid() || field.Type() != reflect.TypeOf((*string)(nil)) || field.IsNil() {
+		return ""
+	}
+	return field.Elem().String()
+}
@@ -0,0 +1,36 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithLogger(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer server.Close()
+
+	var out bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&out, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("id", "secret", ""),
+	}, WithLogger(logger))
+
+	_, err := client.HeadObject(context.TODO(), &s3.HeadObjectInput{Bucket: aws.String("logs"), Key: aws.String("2025/app.log")})
+	assert.Error(t, err)
+	assert.Contains(t, out.String(), `msg="s3 call failed" operation=HeadObject region=us-east-1`)
+	assert.Contains(t, out.String(), "bucket=logs key=2025/app.log")
+}
@@ -0,0 +1,168 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// MultipartUpload is an in-progress multipart upload and the parts uploaded so far
+type MultipartUpload struct {
+	Key       string
+	UploadID  string
+	Initiated time.Time
+	Parts     int
+	Size      int64
+	PartsErr  error // why the parts could not be listed, Parts and Size are unknown then
+}
+
+// partListers bounds the ListParts calls made at once while listing uploads
+const partListers = 8
+
+// ListMultipartUploads lists the in-progress multipart uploads of a bucket, oldest first
+func (c *S3Client) ListMultipartUploads(ctx context.Context, bucket string) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpListMultipartUploads
+		mssg.Bucket = bucket
+
+		uploads, err := c.listMultipartUploads(ctx, bucket)
+		if err != nil {
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		mssg.Uploads = uploads
+		mssg.APIMessage.Status = fmt.Sprintf("S3: Found %d incomplete multipart uploads in %s", len(uploads), bucket)
+		return mssg, nil
+	})
+}
+
+func (c *S3Client) listMultipartUploads(ctx context.Context, bucket string) ([]MultipartUpload, error) {
+	client := c.clientFor(ctx, bucket)
+	var uploads []MultipartUpload
+	paginator := s3.NewListMultipartUploadsPaginator(client, &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucket),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range page.Uploads {
+			uploads = append(uploads, MultipartUpload{
+				Key:       aws.ToString(u.Key),
+				UploadID:  aws.ToString(u.UploadId),
+				Initiated: aws.ToTime(u.Initiated),
+			})
+		}
+	}
+	uploads = c.countAllParts(ctx, client, bucket, uploads)
+	sort.SliceStable(uploads, func(i, j int) bool {
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+	return uploads, nil
+}
+
+// countAllParts counts the parts of the uploads, a few at a time. Uploads
+// completed or aborted since they were listed are dropped, and the others
+// keep an unknown size when their parts cannot be listed
+func (c *S3Client) countAllParts(ctx context.Context, client S3SDK, bucket string, uploads []MultipartUpload) []MultipartUpload {
+	gone := make([]bool, len(uploads))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, partListers)
+	for i := range uploads {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			err := c.countParts(ctx, client, bucket, &uploads[i])
+			switch {
+			case apiErrorCode(err) == "NoSuchUpload":
+				gone[i] = true
+			case err != nil:
+				uploads[i].Parts, uploads[i].Size, uploads[i].PartsErr = 0, 0, err
+			}
+		}()
+	}
+	wg.Wait()
+
+	kept := uploads[:0]
+	for i, u := range uploads {
+		if !gone[i] {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// countParts fills in the number and accumulated size of the uploaded parts
+func (c *S3Client) countParts(ctx context.Context, client S3SDK, bucket string, upload *MultipartUpload) error {
+	paginator := s3.NewListPartsPaginator(client, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(upload.Key),
+		UploadId: aws.String(upload.UploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return err
+		}
+		for _, p := range page.Parts {
+			upload.Parts++
+			upload.Size += aws.ToInt64(p.Size)
+		}
+	}
+	return nil
+}
+
+// AbortMultipartUploads aborts the uploads and discards their parts
+func (c *S3Client) AbortMultipartUploads(ctx context.Context, bucket string, uploads []MultipartUpload) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpAbortMultipartUploads
+		mssg.Bucket = bucket
+
+		result := BatchResult{Op: BatchAbortMultipart, Failed: make(map[string]error)}
+		client := c.clientFor(ctx, bucket)
+		for _, u := range uploads {
+			_, err := client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+				Bucket:   aws.String(bucket),
+				Key:      aws.String(u.Key),
+				UploadId: aws.String(u.UploadID),
+			})
+			if err != nil {
+				result.Failed[u.Key] = err
+			} else {
+				result.Succeeded++
+			}
+		}
+
+		mssg.Batch = result
+		if len(result.Failed) != 0 {
+			err := fmt.Errorf("%s", result.Summary())
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		mssg.APIMessage.Status = result.Summary()
+		return mssg, nil
+	})
+}
+
+// UploadsOlderThan returns the uploads initiated more than days before now
+func UploadsOlderThan(uploads []MultipartUpload, days int, now time.Time) []MultipartUpload {
+	cutoff := now.AddDate(0, 0, -days)
+	var old []MultipartUpload
+	for _, u := range uploads {
+		if u.Initiated.Before(cutoff) {
+			old = append(old, u)
+		}
+	}
+	return old
+}
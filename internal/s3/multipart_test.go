@@ -0,0 +1,104 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListMultipartUploads(t *testing.T) {
+	now := time.Now()
+	mock := &mockS3{
+		ListMultipartUploadsFunc: func(ctx context.Context, input *s3.ListMultipartUploadsInput, _ ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+			return &s3.ListMultipartUploadsOutput{
+				Uploads: []types.MultipartUpload{
+					{Key: aws.String("new.bin"), UploadId: aws.String("u2"), Initiated: aws.Time(now)},
+					{Key: aws.String("old.bin"), UploadId: aws.String("u1"), Initiated: aws.Time(now.AddDate(0, 0, -10))},
+				},
+			}, nil
+		},
+		ListPartsFunc: func(ctx context.Context, input *s3.ListPartsInput, _ ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+			if aws.ToString(input.UploadId) == "u1" {
+				return &s3.ListPartsOutput{Parts: []types.Part{{Size: aws.Int64(5)}, {Size: aws.Int64(7)}}}, nil
+			}
+			return &s3.ListPartsOutput{}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+
+	msg := client.ListMultipartUploads(context.Background(), "bucket")().(S3MenuMessage)
+	assert.NoError(t, msg.APIMessage.Err)
+	assert.Len(t, msg.Uploads, 2)
+	assert.Equal(t, "old.bin", msg.Uploads[0].Key, "oldest first")
+	assert.Equal(t, 2, msg.Uploads[0].Parts)
+	assert.Equal(t, int64(12), msg.Uploads[0].Size)
+	assert.Equal(t, 0, msg.Uploads[1].Parts)
+
+	old := UploadsOlderThan(msg.Uploads, 7, now)
+	assert.Len(t, old, 1)
+	assert.Equal(t, "u1", old[0].UploadID)
+}
+
+func TestListMultipartUploadsPartsErrors(t *testing.T) {
+	mock := &mockS3{
+		ListMultipartUploadsFunc: func(ctx context.Context, input *s3.ListMultipartUploadsInput, _ ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error) {
+			return &s3.ListMultipartUploadsOutput{
+				Uploads: []types.MultipartUpload{
+					{Key: aws.String("done.bin"), UploadId: aws.String("completed")},
+					{Key: aws.String("denied.bin"), UploadId: aws.String("denied")},
+					{Key: aws.String("ok.bin"), UploadId: aws.String("ok")},
+				},
+			}, nil
+		},
+		ListPartsFunc: func(ctx context.Context, input *s3.ListPartsInput, _ ...func(*s3.Options)) (*s3.ListPartsOutput, error) {
+			switch aws.ToString(input.UploadId) {
+			case "completed":
+				return nil, &types.NoSuchUpload{}
+			case "denied":
+				return nil, &smithy.GenericAPIError{Code: "AccessDenied"}
+			}
+			return &s3.ListPartsOutput{Parts: []types.Part{{Size: aws.Int64(5)}}}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+
+	msg := client.ListMultipartUploads(context.Background(), "bucket")().(S3MenuMessage)
+	assert.NoError(t, msg.APIMessage.Err)
+	if assert.Len(t, msg.Uploads, 2, "uploads finished since the listing are dropped") {
+		assert.Equal(t, "denied.bin", msg.Uploads[0].Key)
+		assert.Error(t, msg.Uploads[0].PartsErr)
+		assert.Equal(t, "ok.bin", msg.Uploads[1].Key)
+		assert.NoError(t, msg.Uploads[1].PartsErr)
+		assert.Equal(t, int64(5), msg.Uploads[1].Size)
+	}
+}
+
+func TestAbortMultipartUploads(t *testing.T) {
+	var aborted []string
+	mock := &mockS3{
+		AbortMultipartUploadFunc: func(ctx context.Context, input *s3.AbortMultipartUploadInput, _ ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error) {
+			if aws.ToString(input.Key) == "denied" {
+				return nil, errors.New("access denied")
+			}
+			aborted = append(aborted, aws.ToString(input.UploadId))
+			return &s3.AbortMultipartUploadOutput{}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+
+	msg := client.AbortMultipartUploads(context.Background(), "bucket", []MultipartUpload{
+		{Key: "a", UploadID: "u1"},
+		{Key: "denied", UploadID: "u2"},
+	})().(S3MenuMessage)
+	assert.Error(t, msg.APIMessage.Err)
+	assert.Equal(t, []string{"u1"}, aborted)
+	assert.Equal(t, 1, msg.Batch.Succeeded)
+	assert.Contains(t, msg.Batch.Failed, "denied")
+}
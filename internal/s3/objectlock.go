@@ -0,0 +1,209 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// BucketObjectLock is the Object Lock configuration of a bucket
+type BucketObjectLock struct {
+	Enabled bool
+	Mode    types.ObjectLockRetentionMode // default retention, empty when there is none
+	Days    int32
+	Years   int32
+}
+
+func (l BucketObjectLock) String() string {
+	switch {
+	case !l.Enabled:
+		return "disabled"
+	case l.Mode == "":
+		return "enabled, no default retention"
+	case l.Years != 0:
+		return fmt.Sprintf("%s for %d years", l.Mode, l.Years)
+	}
+	return fmt.Sprintf("%s for %d days", l.Mode, l.Days)
+}
+
+// lockUnknownCodes are the errors of buckets whose Object Lock configuration
+// cannot be read, because of permissions or a service that lacks the API
+var lockUnknownCodes = []string{"ObjectLockConfigurationNotFoundError", "AccessDenied", "NotImplemented", "MethodNotAllowed"}
+
+// GetBucketObjectLock fetches the bucket's Object Lock configuration and default retention.
+// A configuration that cannot be read is reported as disabled, the failed
+// call is only logged at debug level like every S3 call
+func (c *S3Client) GetBucketObjectLock(ctx context.Context, bucket string) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpGetObjectLock
+		mssg.Bucket = bucket
+
+		resp, err := c.clientFor(ctx, bucket).GetObjectLockConfiguration(ctx, &s3.GetObjectLockConfigurationInput{
+			Bucket: aws.String(bucket),
+		})
+		if slices.Contains(lockUnknownCodes, apiErrorCode(err)) {
+			return mssg, nil
+		}
+		if err != nil {
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+
+		conf := resp.ObjectLockConfiguration
+		if conf == nil {
+			return mssg, nil
+		}
+		mssg.ObjectLock.Enabled = conf.ObjectLockEnabled == types.ObjectLockEnabledEnabled
+		if conf.Rule != nil && conf.Rule.DefaultRetention != nil {
+			retention := conf.Rule.DefaultRetention
+			mssg.ObjectLock.Mode = retention.Mode
+			mssg.ObjectLock.Days = aws.ToInt32(retention.Days)
+			mssg.ObjectLock.Years = aws.ToInt32(retention.Years)
+		}
+		return mssg, nil
+	})
+}
+
+// RetentionInput sets the retention of one object. Current is the retention
+// the object has now, as returned by GetObjectMetadata
+type RetentionInput struct {
+	Bucket      string
+	Key         string
+	Mode        types.ObjectLockRetentionMode
+	RetainUntil time.Time
+	Current     S3ObjectMetadata
+}
+
+// SetObjectRetention sets or extends the retention of an object. Shortening
+// GOVERNANCE retention bypasses it, which needs s3:BypassGovernanceRetention
+func (c *S3Client) SetObjectRetention(ctx context.Context, input RetentionInput) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpPutObjectRetention
+		mssg.Bucket = input.Bucket
+
+		current := input.Current
+		shortening := !current.RetainUntil.IsZero() && input.RetainUntil.Before(current.RetainUntil)
+		if current.LockMode == types.ObjectLockModeCompliance && (shortening || input.Mode != types.ObjectLockRetentionModeCompliance) {
+			err := fmt.Errorf("COMPLIANCE retention on %s can only be extended", input.Key)
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		bypass := current.LockMode == types.ObjectLockModeGovernance &&
+			(shortening || input.Mode != types.ObjectLockRetentionModeGovernance)
+
+		_, err := c.clientFor(ctx, input.Bucket).PutObjectRetention(ctx, &s3.PutObjectRetentionInput{
+			Bucket: aws.String(input.Bucket),
+			Key:    aws.String(input.Key),
+			Retention: &types.ObjectLockRetention{
+				Mode:            input.Mode,
+				RetainUntilDate: aws.Time(input.RetainUntil),
+			},
+			BypassGovernanceRetention: aws.Bool(bypass),
+		})
+		if err != nil {
+			if bypass && apiErrorCode(err) == "AccessDenied" {
+				err = fmt.Errorf("shortening or changing GOVERNANCE retention on %s requires the s3:BypassGovernanceRetention permission: %w", input.Key, err)
+			}
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+
+		mssg.APIMessage.Status = fmt.Sprintf("S3: %s/%s is retained in %s mode until %s",
+			input.Bucket, input.Key, input.Mode, input.RetainUntil.Format("2006-01-02"))
+		return mssg, nil
+	})
+}
+
+// SetObjectLegalHold places or removes the legal hold on an object
+func (c *S3Client) SetObjectLegalHold(ctx context.Context, bucket, key string, on bool) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpPutObjectLegalHold
+		mssg.Bucket = bucket
+
+		status := types.ObjectLockLegalHoldStatusOff
+		if on {
+			status = types.ObjectLockLegalHoldStatusOn
+		}
+		_, err := c.clientFor(ctx, bucket).PutObjectLegalHold(ctx, &s3.PutObjectLegalHoldInput{
+			Bucket:    aws.String(bucket),
+			Key:       aws.String(key),
+			LegalHold: &types.ObjectLockLegalHold{Status: status},
+		})
+		if err != nil {
+			if apiErrorCode(err) == "AccessDenied" {
+				err = fmt.Errorf("changing the legal hold on %s requires the s3:PutObjectLegalHold permission: %w", key, err)
+			}
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+
+		mssg.APIMessage.Status = fmt.Sprintf("S3: legal hold on %s/%s is %s", bucket, key, status)
+		return mssg, nil
+	})
+}
+
+// ParseRetention parses "[governance|compliance] <YYYY-MM-DD|Nd>" into a mode and
+// retain-until date. Without a mode the object's current mode, then the
+// bucket default, then GOVERNANCE is used
+func ParseRetention(s string, current types.ObjectLockMode, bucketDefault types.ObjectLockRetentionMode, now time.Time) (types.ObjectLockRetentionMode, time.Time, error) {
+	mode := types.ObjectLockRetentionMode(current)
+	if mode == "" {
+		mode = bucketDefault
+	}
+	if mode == "" {
+		mode = types.ObjectLockRetentionModeGovernance
+	}
+
+	var until time.Time
+	for _, field := range strings.Fields(s) {
+		switch upper := types.ObjectLockRetentionMode(strings.ToUpper(field)); upper {
+		case types.ObjectLockRetentionModeGovernance, types.ObjectLockRetentionModeCompliance:
+			mode = upper
+			continue
+		}
+
+		if days, ok := strings.CutSuffix(field, "d"); ok {
+			n, err := strconv.Atoi(days)
+			if err != nil || n <= 0 {
+				return "", time.Time{}, fmt.Errorf("invalid number of days %q", field)
+			}
+			until = now.AddDate(0, 0, n)
+			continue
+		}
+		date, err := time.ParseInLocation("2006-01-02", field, time.Local)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("invalid retention %q, expected a mode, YYYY-MM-DD or a number of days like 30d", field)
+		}
+		until = date
+	}
+
+	if until.IsZero() {
+		return "", time.Time{}, errors.New("no retain-until date given")
+	}
+	if !until.After(now) {
+		return "", time.Time{}, fmt.Errorf("retain-until date %s is not in the future", until.Format("2006-01-02"))
+	}
+	return mode, until, nil
+}
+
+// apiErrorCode returns the S3 error code of err, or "" when it is not an API error
+func apiErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return ""
+}
@@ -0,0 +1,120 @@
+package s3
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetBucketObjectLock(t *testing.T) {
+	mock := &mockS3{
+		GetObjectLockConfigurationFunc: func(ctx context.Context, input *s3.GetObjectLockConfigurationInput, _ ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+			switch aws.ToString(input.Bucket) {
+			case "plain":
+				return nil, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"}
+			case "denied":
+				return nil, &smithy.GenericAPIError{Code: "AccessDenied"}
+			case "minio":
+				return nil, &smithy.GenericAPIError{Code: "NotImplemented"}
+			case "broken":
+				return nil, &smithy.GenericAPIError{Code: "InternalError"}
+			}
+			return &s3.GetObjectLockConfigurationOutput{
+				ObjectLockConfiguration: &types.ObjectLockConfiguration{
+					ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+					Rule: &types.ObjectLockRule{DefaultRetention: &types.DefaultRetention{
+						Mode: types.ObjectLockRetentionModeGovernance,
+						Days: aws.Int32(30),
+					}},
+				},
+			}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+
+	msg := client.GetBucketObjectLock(context.Background(), "locked")().(S3MenuMessage)
+	assert.NoError(t, msg.APIMessage.Err)
+	assert.Equal(t, "GOVERNANCE for 30 days", msg.ObjectLock.String())
+
+	for _, bucket := range []string{"plain", "denied", "minio"} {
+		msg = client.GetBucketObjectLock(context.Background(), bucket)().(S3MenuMessage)
+		assert.NoError(t, msg.APIMessage.Err, bucket)
+		assert.False(t, msg.ObjectLock.Enabled, bucket)
+	}
+
+	msg = client.GetBucketObjectLock(context.Background(), "broken")().(S3MenuMessage)
+	assert.Error(t, msg.APIMessage.Err)
+}
+
+func TestSetObjectRetention(t *testing.T) {
+	var got *s3.PutObjectRetentionInput
+	mock := &mockS3{
+		PutObjectRetentionFunc: func(ctx context.Context, input *s3.PutObjectRetentionInput, _ ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error) {
+			got = input
+			if aws.ToBool(input.BypassGovernanceRetention) {
+				return nil, &smithy.GenericAPIError{Code: "AccessDenied", Message: "Access Denied"}
+			}
+			return &s3.PutObjectRetentionOutput{}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+	now := time.Now()
+	current := S3ObjectMetadata{LockMode: types.ObjectLockModeGovernance, RetainUntil: now.AddDate(0, 0, 10)}
+
+	// extending needs no bypass
+	msg := client.SetObjectRetention(context.Background(), RetentionInput{
+		Bucket: "b", Key: "k", Mode: types.ObjectLockRetentionModeGovernance,
+		RetainUntil: now.AddDate(0, 0, 20), Current: current,
+	})().(S3MenuMessage)
+	assert.NoError(t, msg.APIMessage.Err)
+	assert.False(t, aws.ToBool(got.BypassGovernanceRetention))
+
+	// shortening bypasses governance and explains the missing permission
+	msg = client.SetObjectRetention(context.Background(), RetentionInput{
+		Bucket: "b", Key: "k", Mode: types.ObjectLockRetentionModeGovernance,
+		RetainUntil: now.AddDate(0, 0, 5), Current: current,
+	})().(S3MenuMessage)
+	assert.ErrorContains(t, msg.APIMessage.Err, "s3:BypassGovernanceRetention")
+
+	// compliance retention is never shortened
+	got = nil
+	msg = client.SetObjectRetention(context.Background(), RetentionInput{
+		Bucket: "b", Key: "k", Mode: types.ObjectLockRetentionModeCompliance,
+		RetainUntil: now.AddDate(0, 0, 5),
+		Current:     S3ObjectMetadata{LockMode: types.ObjectLockModeCompliance, RetainUntil: now.AddDate(0, 0, 10)},
+	})().(S3MenuMessage)
+	assert.Error(t, msg.APIMessage.Err)
+	assert.Nil(t, got, "no request should be sent")
+}
+
+func TestParseRetention(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 0, 0, time.Local)
+
+	mode, until, err := ParseRetention("30d", "", "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ObjectLockRetentionModeGovernance, mode)
+	assert.Equal(t, now.AddDate(0, 0, 30), until)
+
+	mode, until, err = ParseRetention("compliance 2025-06-30", types.ObjectLockModeGovernance, "", now)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ObjectLockRetentionModeCompliance, mode)
+	assert.Equal(t, 2025, until.Year())
+	assert.Equal(t, time.June, until.Month())
+
+	mode, _, err = ParseRetention("7d", "", types.ObjectLockRetentionModeCompliance, now)
+	assert.NoError(t, err)
+	assert.Equal(t, types.ObjectLockRetentionModeCompliance, mode, "bucket default mode")
+
+	_, _, err = ParseRetention("2024-01-01", "", "", now)
+	assert.Error(t, err, "date in the past")
+	_, _, err = ParseRetention("governance", "", "", now)
+	assert.Error(t, err, "no date")
+	_, _, err = ParseRetention("soon", "", "", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,152 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// S3SDK is the subset of the AWS SDK S3 client the application uses
+type S3SDK interface {
+	ListBuckets(ctx context.Context, input *s3.ListBucketsInput, optFns ...func(*s3.Options)) (*s3.ListBucketsOutput, error)
+	CreateBucket(ctx context.Context, input *s3.CreateBucketInput, optFns ...func(*s3.Options)) (*s3.CreateBucketOutput, error)
+	HeadBucket(ctx context.Context, input *s3.HeadBucketInput, optFns ...func(*s3.Options)) (*s3.HeadBucketOutput, error)
+	GetBucketLocation(ctx context.Context, input *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
+	ListObjectsV2(ctx context.Context, input *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	PutObject(ctx context.Context, input *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	GetObject(ctx context.Context, input *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, input *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	DeleteObjects(ctx context.Context, input *s3.DeleteObjectsInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectsOutput, error)
+	HeadObject(ctx context.Context, input *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	CopyObject(ctx context.Context, input *s3.CopyObjectInput, optFns ...func(*s3.Options)) (*s3.CopyObjectOutput, error)
+	PutObjectTagging(ctx context.Context, input *s3.PutObjectTaggingInput, optFns ...func(*s3.Options)) (*s3.PutObjectTaggingOutput, error)
+	ListMultipartUploads(ctx context.Context, input *s3.ListMultipartUploadsInput, optFns ...func(*s3.Options)) (*s3.ListMultipartUploadsOutput, error)
+	ListParts(ctx context.Context, input *s3.ListPartsInput, optFns ...func(*s3.Options)) (*s3.ListPartsOutput, error)
+	AbortMultipartUpload(ctx context.Context, input *s3.AbortMultipartUploadInput, optFns ...func(*s3.Options)) (*s3.AbortMultipartUploadOutput, error)
+	GetObjectLockConfiguration(ctx context.Context, input *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+	PutObjectRetention(ctx context.Context, input *s3.PutObjectRetentionInput, optFns ...func(*s3.Options)) (*s3.PutObjectRetentionOutput, error)
+	PutObjectLegalHold(ctx context.Context, input *s3.PutObjectLegalHoldInput, optFns ...func(*s3.Options)) (*s3.PutObjectLegalHoldOutput, error)
+}
+
+// regionPool resolves the region of each bucket and keeps one client per region
+type regionPool struct {
+	mu            sync.Mutex
+	defaultRegion string
+	regions       map[string]string // bucket -> region
+	clients       map[string]S3SDK  // region -> client
+	newClient     func(region string) S3SDK
+}
+
+// NewS3Client creates a client that routes bucket operations to the bucket's region.
+// optFns are applied to every regional client, after the region is set
+func NewS3Client(cfg aws.Config, optFns ...func(*s3.Options)) *S3Client {
+	newClient := func(region string) S3SDK {
+		regionCfg := cfg.Copy()
+		regionCfg.Region = region
+		return s3.NewFromConfig(regionCfg, optFns...)
+	}
+	defaultClient := newClient(cfg.Region)
+	return &S3Client{
+		Client: defaultClient,
+		pool:   newRegionPool(cfg.Region, defaultClient, newClient),
+	}
+}
+
+func newRegionPool(defaultRegion string, defaultClient S3SDK, newClient func(string) S3SDK) *regionPool {
+	return &regionPool{
+		defaultRegion: defaultRegion,
+		regions:       make(map[string]string),
+		clients:       map[string]S3SDK{defaultRegion: defaultClient},
+		newClient:     newClient,
+	}
+}
+
+// remember caches a bucket region learned from another call, e.g. ListBuckets
+func (p *regionPool) remember(bucket, region string) {
+	if p == nil || bucket == "" || region == "" {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.regions[bucket] = region
+}
+
+func (p *regionPool) clientForRegion(region string) S3SDK {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	client, ok := p.clients[region]
+	if !ok {
+		client = p.newClient(region)
+		p.clients[region] = client
+	}
+	return client
+}
+
+// regionOf returns the cached region of bucket or resolves it with HeadBucket,
+// falling back to GetBucketLocation when HeadBucket does not tell. A bucket
+// neither call resolves keeps the default region for the session, unless
+// ListBuckets names its region later
+func (p *regionPool) regionOf(ctx context.Context, bucket string) string {
+	p.mu.Lock()
+	region, ok := p.regions[bucket]
+	p.mu.Unlock()
+	if ok {
+		return region
+	}
+
+	defaultClient := p.clientForRegion(p.defaultRegion)
+	resp, err := defaultClient.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(bucket)})
+	if err == nil && resp.BucketRegion != nil {
+		region = *resp.BucketRegion
+	} else if err != nil {
+		// a bucket in another region answers with a redirect naming its region
+		region = regionFromError(err)
+	}
+
+	if region == "" {
+		loc, err := defaultClient.GetBucketLocation(ctx, &s3.GetBucketLocationInput{Bucket: aws.String(bucket)})
+		if err != nil {
+			if ctx.Err() == nil {
+				// a cancelled lookup is tried again, a refused one is not
+				p.remember(bucket, p.defaultRegion)
+			}
+			return p.defaultRegion
+		}
+		region = normaliseLocation(string(loc.LocationConstraint))
+	}
+
+	p.remember(bucket, region)
+	return region
+}
+
+func regionFromError(err error) string {
+	var respErr *awshttp.ResponseError
+	if errors.As(err, &respErr) && respErr.Response != nil {
+		return respErr.Response.Header.Get("X-Amz-Bucket-Region")
+	}
+	return ""
+}
+
+// normaliseLocation maps the legacy GetBucketLocation values to region names
+func normaliseLocation(location string) string {
+	switch location {
+	case "":
+		return "us-east-1"
+	case "EU":
+		return "eu-west-1"
+	}
+	return location
+}
+
+// clientFor returns the client for the bucket's region. Clients created
+// without a pool, e.g. in tests, always use their single client
+func (c *S3Client) clientFor(ctx context.Context, bucket string) S3SDK {
+	if c.pool == nil || bucket == "" {
+		return c.Client
+	}
+	return c.pool.clientForRegion(c.pool.regionOf(ctx, bucket))
+}
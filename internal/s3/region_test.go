@@ -0,0 +1,131 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+	"github.com/stretchr/testify/assert"
+)
+
+// pooledClient builds an S3Client whose regional clients are recorded in created
+func pooledClient(defaultClient S3SDK, created map[string]*mockS3) *S3Client {
+	return &S3Client{
+		Client: defaultClient,
+		pool: newRegionPool("us-east-1", defaultClient, func(region string) S3SDK {
+			m := &mockS3{}
+			created[region] = m
+			return m
+		}),
+	}
+}
+
+func TestRegionFromListBuckets(t *testing.T) {
+	defaultClient := &mockS3{
+		ListBucketsFunc: func(ctx context.Context, input *s3.ListBucketsInput, _ ...func(*s3.Options)) (*s3.ListBucketsOutput, error) {
+			return &s3.ListBucketsOutput{
+				Buckets: []types.Bucket{{Name: aws.String("eu-bucket"), BucketRegion: aws.String("eu-west-1")}},
+			}, nil
+		},
+	}
+	created := map[string]*mockS3{}
+	client := pooledClient(defaultClient, created)
+	client.ListBuckets(context.Background(), &s3.ListBucketsInput{})()
+
+	regional := client.clientFor(context.Background(), "eu-bucket")
+	assert.Same(t, created["eu-west-1"], regional)
+	// the pool reuses the regional client
+	assert.Same(t, regional, client.clientFor(context.Background(), "eu-bucket"))
+}
+
+func TestRegionFromHeadBucket(t *testing.T) {
+	calls := 0
+	defaultClient := &mockS3{
+		HeadBucketFunc: func(ctx context.Context, input *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+			calls++
+			return &s3.HeadBucketOutput{BucketRegion: aws.String("ap-south-1")}, nil
+		},
+	}
+	created := map[string]*mockS3{}
+	client := pooledClient(defaultClient, created)
+
+	regional := client.clientFor(context.Background(), "bucket")
+	assert.Same(t, created["ap-south-1"], regional)
+	client.clientFor(context.Background(), "bucket")
+	assert.Equal(t, 1, calls, "region should be cached")
+}
+
+func TestRegionFromRedirect(t *testing.T) {
+	defaultClient := &mockS3{
+		HeadBucketFunc: func(ctx context.Context, input *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+			resp := &http.Response{StatusCode: http.StatusMovedPermanently, Header: http.Header{}}
+			resp.Header.Set("X-Amz-Bucket-Region", "us-west-2")
+			return nil, &awshttp.ResponseError{
+				ResponseError: &smithyhttp.ResponseError{
+					Response: &smithyhttp.Response{Response: resp},
+					Err:      errors.New("moved permanently"),
+				},
+			}
+		},
+	}
+	created := map[string]*mockS3{}
+	client := pooledClient(defaultClient, created)
+
+	regional := client.clientFor(context.Background(), "bucket")
+	assert.Same(t, created["us-west-2"], regional)
+}
+
+func TestRegionFromBucketLocation(t *testing.T) {
+	defaultClient := &mockS3{
+		HeadBucketFunc: func(ctx context.Context, input *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+			return nil, errors.New("forbidden")
+		},
+		GetBucketLocationFunc: func(ctx context.Context, input *s3.GetBucketLocationInput, _ ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+			return &s3.GetBucketLocationOutput{LocationConstraint: types.BucketLocationConstraintEu}, nil
+		},
+	}
+	created := map[string]*mockS3{}
+	client := pooledClient(defaultClient, created)
+
+	regional := client.clientFor(context.Background(), "bucket")
+	assert.Same(t, created["eu-west-1"], regional)
+}
+
+func TestRegionLookupFailureIsCached(t *testing.T) {
+	calls := 0
+	defaultClient := &mockS3{
+		HeadBucketFunc: func(ctx context.Context, input *s3.HeadBucketInput, _ ...func(*s3.Options)) (*s3.HeadBucketOutput, error) {
+			calls++
+			return nil, errors.New("forbidden")
+		},
+		GetBucketLocationFunc: func(ctx context.Context, input *s3.GetBucketLocationInput, _ ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error) {
+			calls++
+			return nil, errors.New("access denied")
+		},
+	}
+	created := map[string]*mockS3{}
+	client := pooledClient(defaultClient, created)
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Same(t, defaultClient, client.clientFor(cancelled, "bucket"))
+	assert.Equal(t, 2, calls)
+
+	for range 3 {
+		assert.Same(t, defaultClient, client.clientFor(context.Background(), "bucket"))
+	}
+	assert.Equal(t, 4, calls, "the fallback region should be cached once the lookups fail")
+	assert.Empty(t, created)
+}
+
+func TestNormaliseLocation(t *testing.T) {
+	assert.Equal(t, "us-east-1", normaliseLocation(""))
+	assert.Equal(t, "eu-west-1", normaliseLocation("EU"))
+	assert.Equal(t, "ca-central-1", normaliseLocation("ca-central-1"))
+}
@@ -0,0 +1,201 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// SyncInput identifies both sides of a sync
+type SyncInput struct {
+	Bucket   string
+	Prefix   string // no leading or trailing "/"
+	LocalDir string
+	Options  internal.SyncOptions
+}
+
+func (in SyncInput) remoteKey(rel string) string {
+	if in.Prefix == "" {
+		return rel
+	}
+	return in.Prefix + "/" + rel
+}
+
+func (in SyncInput) localPath(rel string) (string, error) {
+	return internal.JoinLocal(in.LocalDir, rel)
+}
+
+// listSyncFiles lists every object under the prefix, following pagination
+func (c *S3Client) listSyncFiles(ctx context.Context, bucket, prefix string) ([]internal.SyncFile, error) {
+	listPrefix := prefix
+	if listPrefix != "" {
+		listPrefix += "/"
+	}
+
+	var files []internal.SyncFile
+	paginator := s3.NewListObjectsV2Paginator(c.clientFor(ctx, bucket), &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucket),
+		Prefix: aws.String(listPrefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range page.Contents {
+			rel := strings.TrimPrefix(aws.ToString(obj.Key), listPrefix)
+			// skip folder markers, they have no local counterpart
+			if rel == "" || strings.HasSuffix(rel, "/") {
+				continue
+			}
+			files = append(files, internal.SyncFile{
+				Path:     rel,
+				Size:     aws.ToInt64(obj.Size),
+				ModTime:  aws.ToTime(obj.LastModified),
+				Checksum: internal.ETagChecksum(aws.ToString(obj.ETag)),
+				ETag:     strings.Trim(aws.ToString(obj.ETag), "\""),
+			})
+		}
+	}
+	return files, nil
+}
+
+// localFiles splits the remote files into those that can be stored under the
+// local directory and the keys of those that would leave it
+func (in SyncInput) localFiles(remote []internal.SyncFile) ([]internal.SyncFile, []string) {
+	var skipped []string
+	files := remote[:0:0]
+	for _, f := range remote {
+		if _, err := in.localPath(f.Path); err != nil {
+			skipped = append(skipped, in.remoteKey(f.Path))
+			continue
+		}
+		files = append(files, f)
+	}
+	return files, skipped
+}
+
+// PlanSync lists both sides and computes the actions without touching anything
+func (c *S3Client) PlanSync(ctx context.Context, input SyncInput) tea.Cmd {
+	return c.Wrapper(func() (any, error) {
+		mssg := c.NewMessage()
+		mssg.Op = S3OpPlanSync
+		mssg.Bucket = input.Bucket
+
+		local, err := internal.ScanSyncDir(input.LocalDir)
+		if err != nil {
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		remote, err := c.listSyncFiles(ctx, input.Bucket, input.Prefix)
+		if err != nil {
+			mssg.APIMessage.Err = err
+			return mssg, err
+		}
+		remote, skipped := input.localFiles(remote)
+
+		mssg.SyncPlan = internal.PlanSync(local, remote, input.Options)
+		mssg.APIMessage.Status = fmt.Sprintf("Planned %d sync actions for %s/%s", len(mssg.SyncPlan), input.Bucket, input.Prefix)
+		if len(skipped) != 0 {
+			mssg.APIMessage.Status += fmt.Sprintf(", skipped %d keys outside the local directory: %s", len(skipped), strings.Join(skipped, ", "))
+			mssg.APIMessage.Severity = internal.SeverityWarn
+		}
+		return mssg, nil
+	})
+}
+
+// JobName is how the transfers view shows an action: the object, or the
+// file for local deletes
+func (in SyncInput) JobName(action internal.SyncAction) string {
+	if action.Type == internal.SyncDeleteLocal {
+		local, _ := in.localPath(action.Path)
+		return local
+	}
+	return in.Bucket + "/" + in.remoteKey(action.Path)
+}
+
+// SyncJob returns the transfer carrying out one action of a plan, to be
+// queued on the transfer manager. Uploads go through Upload, so they get a
+// content type and the encoding the compression rules pick
+func (c *S3Client) SyncJob(input SyncInput, action internal.SyncAction) transfer.RunFunc {
+	return func(ctx context.Context, tracker *transfer.Tracker) error {
+		return c.runSyncAction(ctx, input, action, tracker)
+	}
+}
+
+func (c *S3Client) runSyncAction(ctx context.Context, input SyncInput, action internal.SyncAction, tracker *transfer.Tracker) error {
+	key := input.remoteKey(action.Path)
+	local, err := input.localPath(action.Path)
+	if err != nil {
+		return err
+	}
+
+	switch action.Type {
+	case internal.SyncUpload:
+		put := &s3.PutObjectInput{
+			Bucket: aws.String(input.Bucket),
+			Key:    aws.String(key),
+		}
+		if encoding := internal.CompressionFor(input.Options.Compression, local); encoding != "" {
+			put.ContentEncoding = aws.String(encoding)
+		}
+		return c.Upload(ctx, put, local, tracker)
+
+	case internal.SyncDownload:
+		resp, err := c.clientFor(ctx, input.Bucket).GetObject(ctx, &s3.GetObjectInput{
+			Bucket: aws.String(input.Bucket),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.ContentLength != nil {
+			tracker.SetTotal(*resp.ContentLength)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+			return err
+		}
+		if err := saveFile(local, tracker.Reader(resp.Body)); err != nil {
+			return err
+		}
+		// keep the remote mtime so the next plan sees both sides as equal
+		if resp.LastModified != nil {
+			return os.Chtimes(local, *resp.LastModified, *resp.LastModified)
+		}
+		return nil
+
+	case internal.SyncDeleteLocal:
+		return os.Remove(local)
+
+	case internal.SyncDeleteRemote:
+		_, err := c.clientFor(ctx, input.Bucket).DeleteObject(ctx, &s3.DeleteObjectInput{
+			Bucket: aws.String(input.Bucket),
+			Key:    aws.String(key),
+		})
+		return err
+
+	case internal.SyncConflict:
+		return nil
+	}
+	return fmt.Errorf("unknown sync action %d", action.Type)
+}
+
+// CleanSyncPrefix normalises a user entered prefix to the form SyncInput expects
+func CleanSyncPrefix(prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return ""
+	}
+	return path.Clean(prefix)
+}
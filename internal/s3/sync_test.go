@@ -0,0 +1,53 @@
+package s3
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanSyncSkipsKeysLeavingLocalDir(t *testing.T) {
+	mock := &mockS3{
+		ListObjectsV2Func: func(ctx context.Context, input *s3.ListObjectsV2Input, _ ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+			var contents []types.Object
+			for _, key := range []string{"site/../../.bashrc", "site/a/../../x", "site/index.html"} {
+				contents = append(contents, types.Object{Key: aws.String(key), Size: aws.Int64(1)})
+			}
+			return &s3.ListObjectsV2Output{Contents: contents}, nil
+		},
+	}
+	client := &S3Client{Client: mock}
+	input := SyncInput{
+		Bucket:   "bucket",
+		Prefix:   "site",
+		LocalDir: t.TempDir(),
+		Options:  internal.SyncOptions{Direction: internal.SyncDown, Delete: internal.SyncDeleteExtraneous},
+	}
+
+	msg := client.PlanSync(context.Background(), input)().(S3MenuMessage)
+	assert.NoError(t, msg.APIMessage.Err)
+	assert.Equal(t, []internal.SyncAction{
+		{Type: internal.SyncDownload, Path: "index.html", Size: 1, Reason: "missing locally"},
+	}, msg.SyncPlan)
+	assert.Contains(t, msg.APIMessage.Status, "skipped 2 keys outside the local directory")
+	assert.Equal(t, internal.SeverityWarn, msg.APIMessage.Level())
+}
+
+func TestRunSyncActionRefusesKeysLeavingLocalDir(t *testing.T) {
+	root := t.TempDir()
+	outside := filepath.Join(root, "outside")
+	assert.NoError(t, os.WriteFile(outside, []byte("keep"), 0o644))
+	input := SyncInput{Bucket: "bucket", LocalDir: filepath.Join(root, "sync")}
+
+	err := (&S3Client{}).runSyncAction(context.Background(), input, internal.SyncAction{Type: internal.SyncDeleteLocal, Path: "../outside"}, nil)
+	assert.ErrorContains(t, err, "leaves the local directory")
+	_, err = os.Stat(outside)
+	assert.NoError(t, err, "the file outside the sync directory is untouched")
+}
@@ -0,0 +1,286 @@
+package internal
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SyncDirection decides which side of a sync is the source of truth
+type SyncDirection int
+
+const (
+	SyncUp     SyncDirection = iota // local directory -> bucket prefix
+	SyncDown                        // bucket prefix -> local directory
+	SyncMirror                      // two-way, newer side wins
+)
+
+func (d SyncDirection) String() string {
+	switch d {
+	case SyncUp:
+		return "up"
+	case SyncDown:
+		return "down"
+	case SyncMirror:
+		return "mirror"
+	}
+	return "unknown"
+}
+
+// SyncDeletePolicy decides what happens to files that only exist on the destination
+type SyncDeletePolicy int
+
+const (
+	SyncKeepExtraneous SyncDeletePolicy = iota
+	SyncDeleteExtraneous
+)
+
+func (p SyncDeletePolicy) String() string {
+	if p == SyncDeleteExtraneous {
+		return "delete extraneous"
+	}
+	return "keep extraneous"
+}
+
+type SyncActionType int
+
+const (
+	SyncUpload SyncActionType = iota
+	SyncDownload
+	SyncDeleteLocal
+	SyncDeleteRemote
+	SyncConflict // both sides differ and neither is newer, left alone
+)
+
+func (t SyncActionType) String() string {
+	switch t {
+	case SyncUpload:
+		return "upload"
+	case SyncDownload:
+		return "download"
+	case SyncDeleteLocal:
+		return "delete local"
+	case SyncDeleteRemote:
+		return "delete remote"
+	case SyncConflict:
+		return "conflict"
+	}
+	return "unknown"
+}
+
+// SyncFile describes one file on either side of a sync.
+// Path is relative to the sync root and always uses "/" as separator.
+// Checksum is a hex md5, empty when it is not known (e.g. multipart ETags)
+type SyncFile struct {
+	Path     string
+	Size     int64
+	ModTime  time.Time
+	Checksum string
+	ETag     string // raw ETag without quotes, only set for remote files
+}
+
+type SyncAction struct {
+	Type   SyncActionType
+	Path   string
+	Size   int64
+	Reason string
+}
+
+type SyncOptions struct {
+	Direction SyncDirection
+	Delete    SyncDeletePolicy
+	// Compression rules pick the encoding of uploads, as for any other upload.
+	// Compressed objects no longer match their file, so only a newer local
+	// file is uploaded again and they are never downloaded over it
+	Compression []CompressionRule
+}
+
+// PlanSync compares the local and remote listings and returns the actions needed
+// to bring them in line according to opts. Actions are sorted by path.
+// Delete policy is ignored for mirror since a two-way sync has no single destination
+func PlanSync(local, remote []SyncFile, opts SyncOptions) []SyncAction {
+	localMap := make(map[string]SyncFile, len(local))
+	for _, f := range local {
+		localMap[f.Path] = f
+	}
+	remoteMap := make(map[string]SyncFile, len(remote))
+	for _, f := range remote {
+		remoteMap[f.Path] = f
+	}
+
+	var actions []SyncAction
+	for path, l := range localMap {
+		r, ok := remoteMap[path]
+		if !ok {
+			switch opts.Direction {
+			case SyncUp, SyncMirror:
+				actions = append(actions, SyncAction{Type: SyncUpload, Path: path, Size: l.Size, Reason: "missing in bucket"})
+			case SyncDown:
+				if opts.Delete == SyncDeleteExtraneous {
+					actions = append(actions, SyncAction{Type: SyncDeleteLocal, Path: path, Size: l.Size, Reason: "not in bucket"})
+				}
+			}
+			continue
+		}
+
+		if opts.Direction != SyncDown && CompressionFor(opts.Compression, path) != "" {
+			if compareModTimes(l.ModTime, r.ModTime) > 0 {
+				actions = append(actions, SyncAction{Type: SyncUpload, Path: path, Size: l.Size, Reason: "local is newer"})
+			}
+			continue
+		}
+
+		differs, reason := syncFilesDiffer(l, r)
+		if !differs {
+			continue
+		}
+		newer := compareModTimes(l.ModTime, r.ModTime)
+		switch opts.Direction {
+		case SyncUp:
+			if reason != "" || newer > 0 {
+				actions = append(actions, SyncAction{Type: SyncUpload, Path: path, Size: l.Size, Reason: orReason(reason, "local is newer")})
+			}
+		case SyncDown:
+			if reason != "" || newer < 0 {
+				actions = append(actions, SyncAction{Type: SyncDownload, Path: path, Size: r.Size, Reason: orReason(reason, "remote is newer")})
+			}
+		case SyncMirror:
+			switch {
+			case newer > 0:
+				actions = append(actions, SyncAction{Type: SyncUpload, Path: path, Size: l.Size, Reason: orReason(reason, "local is newer")})
+			case newer < 0:
+				actions = append(actions, SyncAction{Type: SyncDownload, Path: path, Size: r.Size, Reason: orReason(reason, "remote is newer")})
+			default:
+				// the content differs but nothing tells which side is right
+				actions = append(actions, SyncAction{Type: SyncConflict, Path: path, Size: l.Size, Reason: reason + " with the same modification time"})
+			}
+		}
+	}
+
+	for path, r := range remoteMap {
+		if _, ok := localMap[path]; ok {
+			continue
+		}
+		switch opts.Direction {
+		case SyncDown, SyncMirror:
+			actions = append(actions, SyncAction{Type: SyncDownload, Path: path, Size: r.Size, Reason: "missing locally"})
+		case SyncUp:
+			if opts.Delete == SyncDeleteExtraneous {
+				actions = append(actions, SyncAction{Type: SyncDeleteRemote, Path: path, Size: r.Size, Reason: "not in local directory"})
+			}
+		}
+	}
+
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].Path == actions[j].Path {
+			return actions[i].Type < actions[j].Type
+		}
+		return actions[i].Path < actions[j].Path
+	})
+	return actions
+}
+
+// syncFilesDiffer reports whether two files with the same path need syncing.
+// The reason is empty when only the modification time tells them apart,
+// so callers can decide which side is newer
+func syncFilesDiffer(l, r SyncFile) (bool, string) {
+	if l.Size != r.Size {
+		return true, "size differs"
+	}
+	if l.Checksum != "" && r.Checksum != "" {
+		if l.Checksum != r.Checksum {
+			return true, "checksum differs"
+		}
+		return false, ""
+	}
+	return compareModTimes(l.ModTime, r.ModTime) != 0, ""
+}
+
+// compareModTimes compares modification times to the second, the precision
+// S3 keeps. It returns 1 when l is newer, -1 when r is and 0 otherwise
+func compareModTimes(l, r time.Time) int {
+	return l.Truncate(time.Second).Compare(r.Truncate(time.Second))
+}
+
+func orReason(reason, fallback string) string {
+	if reason != "" {
+		return reason
+	}
+	return fallback
+}
+
+// ScanSyncDir walks root and returns every regular file with its md5 checksum
+func ScanSyncDir(root string) ([]SyncFile, error) {
+	var files []SyncFile
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		sum, err := FileMD5(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, SyncFile{
+			Path:     filepath.ToSlash(rel),
+			Size:     info.Size(),
+			ModTime:  info.ModTime(),
+			Checksum: sum,
+		})
+		return nil
+	})
+	return files, err
+}
+
+// JoinLocal joins the "/" separated path rel of an object under root. Keys
+// that would leave root, such as "../x", "a/../../x" or absolute ones, are
+// refused so a hostile bucket cannot write or delete files outside of it
+func JoinLocal(root, rel string) (string, error) {
+	local := filepath.FromSlash(rel)
+	if !filepath.IsLocal(local) {
+		return "", fmt.Errorf("%q leaves the local directory", rel)
+	}
+	return filepath.Join(root, local), nil
+}
+
+// FileMD5 returns the hex md5 of the file at path
+func FileMD5(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ETagChecksum converts an S3 ETag into a comparable md5.
+// Multipart ETags are not an md5 of the content so they return ""
+func ETagChecksum(etag string) string {
+	etag = strings.Trim(etag, "\"")
+	if strings.Contains(etag, "-") {
+		return ""
+	}
+	return etag
+}
@@ -0,0 +1,148 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func actionsByPath(actions []SyncAction) map[string]SyncActionType {
+	m := make(map[string]SyncActionType)
+	for _, a := range actions {
+		m[a.Path] = a.Type
+	}
+	return m
+}
+
+func TestPlanSyncUp(t *testing.T) {
+	now := time.Now()
+	local := []SyncFile{
+		{Path: "same.txt", Size: 3, ModTime: now, Checksum: "abc"},
+		{Path: "changed.txt", Size: 3, ModTime: now, Checksum: "new"},
+		{Path: "new.txt", Size: 1, ModTime: now},
+	}
+	remote := []SyncFile{
+		{Path: "same.txt", Size: 3, ModTime: now.Add(-time.Hour), Checksum: "abc"},
+		{Path: "changed.txt", Size: 3, ModTime: now, Checksum: "old"},
+		{Path: "extra.txt", Size: 1, ModTime: now},
+	}
+
+	plan := PlanSync(local, remote, SyncOptions{Direction: SyncUp})
+	got := actionsByPath(plan)
+	assert.Len(t, plan, 2)
+	assert.Equal(t, SyncUpload, got["changed.txt"])
+	assert.Equal(t, SyncUpload, got["new.txt"])
+
+	plan = PlanSync(local, remote, SyncOptions{Direction: SyncUp, Delete: SyncDeleteExtraneous})
+	got = actionsByPath(plan)
+	assert.Len(t, plan, 3)
+	assert.Equal(t, SyncDeleteRemote, got["extra.txt"])
+}
+
+func TestPlanSyncDown(t *testing.T) {
+	now := time.Now()
+	local := []SyncFile{
+		{Path: "old.txt", Size: 2, ModTime: now.Add(-time.Hour)},
+		{Path: "extra.txt", Size: 1, ModTime: now},
+	}
+	remote := []SyncFile{
+		{Path: "old.txt", Size: 2, ModTime: now},
+		{Path: "missing.txt", Size: 5, ModTime: now},
+	}
+
+	plan := PlanSync(local, remote, SyncOptions{Direction: SyncDown, Delete: SyncDeleteExtraneous})
+	got := actionsByPath(plan)
+	assert.Len(t, plan, 3)
+	assert.Equal(t, SyncDownload, got["old.txt"])
+	assert.Equal(t, SyncDownload, got["missing.txt"])
+	assert.Equal(t, SyncDeleteLocal, got["extra.txt"])
+}
+
+func TestPlanSyncMirror(t *testing.T) {
+	now := time.Now()
+	local := []SyncFile{
+		{Path: "local-newer.txt", Size: 1, ModTime: now},
+		{Path: "remote-newer.txt", Size: 1, ModTime: now.Add(-time.Hour)},
+		{Path: "only-local.txt", Size: 1, ModTime: now},
+	}
+	remote := []SyncFile{
+		{Path: "local-newer.txt", Size: 2, ModTime: now.Add(-time.Hour)},
+		{Path: "remote-newer.txt", Size: 2, ModTime: now},
+		{Path: "only-remote.txt", Size: 1, ModTime: now},
+	}
+
+	plan := PlanSync(local, remote, SyncOptions{Direction: SyncMirror, Delete: SyncDeleteExtraneous})
+	got := actionsByPath(plan)
+	assert.Len(t, plan, 4)
+	assert.Equal(t, SyncUpload, got["local-newer.txt"])
+	assert.Equal(t, SyncDownload, got["remote-newer.txt"])
+	assert.Equal(t, SyncUpload, got["only-local.txt"])
+	assert.Equal(t, SyncDownload, got["only-remote.txt"])
+}
+
+func TestPlanSyncMirrorSameModTime(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	local := []SyncFile{
+		{Path: "edited.txt", Size: 1, ModTime: now.Add(300 * time.Millisecond), Checksum: "aa"},
+		{Path: "same.txt", Size: 1, ModTime: now.Add(900 * time.Millisecond)},
+	}
+	remote := []SyncFile{
+		{Path: "edited.txt", Size: 1, ModTime: now, Checksum: "bb"},
+		{Path: "same.txt", Size: 1, ModTime: now},
+	}
+
+	plan := PlanSync(local, remote, SyncOptions{Direction: SyncMirror})
+	assert.Equal(t, []SyncAction{
+		{Type: SyncConflict, Path: "edited.txt", Size: 1, Reason: "checksum differs with the same modification time"},
+	}, plan, "sub-second differences do not pick a winner, and a real difference is never dropped")
+}
+
+func TestPlanSyncCompressed(t *testing.T) {
+	now := time.Now()
+	local := []SyncFile{
+		{Path: "app.js", Size: 10, ModTime: now.Add(-time.Hour), Checksum: "aa"},
+		{Path: "style.css", Size: 10, ModTime: now, Checksum: "aa"},
+	}
+	remote := []SyncFile{
+		{Path: "app.js", Size: 4, ModTime: now, Checksum: "bb"},
+		{Path: "style.css", Size: 4, ModTime: now.Add(-time.Hour), Checksum: "bb"},
+	}
+	opts := SyncOptions{Direction: SyncMirror, Compression: []CompressionRule{{Extensions: []string{"js", "css"}, Encoding: EncodingGzip}}}
+
+	plan := PlanSync(local, remote, opts)
+	assert.Equal(t, []SyncAction{
+		{Type: SyncUpload, Path: "style.css", Size: 10, Reason: "local is newer"},
+	}, plan, "compressed objects differ from their file, only a newer file is uploaded")
+}
+
+func TestScanSyncDir(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "a.txt"), []byte("hello"), 0o644))
+
+	files, err := ScanSyncDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "sub/a.txt", files[0].Path)
+	assert.Equal(t, int64(5), files[0].Size)
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", files[0].Checksum)
+}
+
+func TestETagChecksum(t *testing.T) {
+	assert.Equal(t, "5d41402abc4b2a76b9719d911017c592", ETagChecksum("\"5d41402abc4b2a76b9719d911017c592\""))
+	assert.Equal(t, "", ETagChecksum("\"d41d8cd98f00b204e9800998ecf8427e-2\""))
+}
+
+func TestJoinLocal(t *testing.T) {
+	for _, rel := range []string{"index.html", "a/b/c.txt", "a/../b.txt"} {
+		_, err := JoinLocal("root", rel)
+		assert.NoError(t, err, rel)
+	}
+	for _, rel := range []string{"../.bashrc", "a/../../x", "/etc/passwd", "", ".."} {
+		_, err := JoinLocal("root", rel)
+		assert.Error(t, err, rel)
+	}
+}
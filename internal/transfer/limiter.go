@@ -0,0 +1,158 @@
+package transfer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// maxLimiterWait bounds a single wait so rate changes apply quickly
+const maxLimiterWait = 100 * time.Millisecond
+
+// Limiter is a token bucket capping throughput in bytes per second. A zero
+// rate is unlimited. The rate can be changed while transfers wait on it
+type Limiter struct {
+	mu     sync.Mutex
+	rate   int64
+	tokens float64
+	last   time.Time
+}
+
+func NewLimiter(rate int64) *Limiter {
+	return &Limiter{rate: rate, last: time.Now()}
+}
+
+// SetRate changes the limit, 0 removes it
+func (l *Limiter) SetRate(rate int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refill(time.Now())
+	l.rate = max(rate, 0)
+	l.tokens = min(l.tokens, float64(l.rate))
+}
+
+func (l *Limiter) Rate() int64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.rate
+}
+
+// refill adds the tokens earned since the last call, up to one second of burst. Callers hold l.mu
+func (l *Limiter) refill(now time.Time) {
+	l.tokens = min(l.tokens+now.Sub(l.last).Seconds()*float64(l.rate), float64(l.rate))
+	l.last = now
+}
+
+// Wait blocks until n bytes may pass or ctx is done. A nil limiter never blocks
+func (l *Limiter) Wait(ctx context.Context, n int) error {
+	if l == nil {
+		return nil
+	}
+	remaining := float64(n)
+	for remaining > 0 {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		l.refill(time.Now())
+		take := min(remaining, l.tokens)
+		l.tokens -= take
+		remaining -= take
+		wait := time.Duration(min(remaining, float64(l.rate)) / float64(l.rate) * float64(time.Second))
+		l.mu.Unlock()
+
+		if remaining <= 0 {
+			return nil
+		}
+		timer := time.NewTimer(min(wait, maxLimiterWait))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return nil
+}
+
+// ParseBytes parses sizes such as "512", "64K", "10MiB" or "1.5GB" with binary units
+func ParseBytes(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	upper := strings.ToUpper(s)
+	upper = strings.TrimSuffix(strings.TrimSuffix(upper, "/S"), "B")
+	upper = strings.TrimSuffix(upper, "I")
+
+	multiplier := int64(1)
+	if i := strings.IndexAny(upper, "KMGT"); i != -1 && i == len(upper)-1 {
+		multiplier = int64(1) << (10 * (strings.IndexByte("KMGT", upper[i]) + 1))
+		upper = upper[:i]
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(upper), 64)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid size %q, expected a number with an optional K, M, G or T unit", s)
+	}
+	return int64(value * float64(multiplier)), nil
+}
+
+// Window is a daily time window, which wraps past midnight when End is before Start
+type Window struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+// ParseWindow parses "HH:MM-HH:MM", e.g. "22:00-06:00"
+func ParseWindow(s string) (Window, error) {
+	start, end, found := strings.Cut(strings.TrimSpace(s), "-")
+	if !found {
+		return Window{}, fmt.Errorf("invalid window %q, expected HH:MM-HH:MM", s)
+	}
+	var w Window
+	for _, part := range []struct {
+		text string
+		dst  *time.Duration
+	}{{start, &w.Start}, {end, &w.End}} {
+		t, err := time.Parse("15:04", strings.TrimSpace(part.text))
+		if err != nil {
+			return Window{}, fmt.Errorf("invalid window %q, expected HH:MM-HH:MM", s)
+		}
+		*part.dst = time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+	}
+	return w, nil
+}
+
+func (w Window) String() string {
+	format := func(d time.Duration) string {
+		return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return format(w.Start) + "-" + format(w.End)
+}
+
+func sinceMidnight(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute +
+		time.Duration(t.Second())*time.Second
+}
+
+// Contains reports whether t falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	now := sinceMidnight(t)
+	if w.Start <= w.End {
+		return now >= w.Start && now < w.End
+	}
+	return now >= w.Start || now < w.End
+}
+
+// Until returns how long after t the window next opens
+func (w Window) Until(t time.Time) time.Duration {
+	d := w.Start - sinceMidnight(t)
+	if d < 0 {
+		d += 24 * time.Hour
+	}
+	return d
+}
@@ -0,0 +1,116 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiterThrottles(t *testing.T) {
+	l := NewLimiter(1000)
+	start := time.Now()
+	assert.NoError(t, l.Wait(context.Background(), 300))
+	assert.GreaterOrEqual(t, time.Since(start), 250*time.Millisecond)
+
+	l.SetRate(0)
+	start = time.Now()
+	assert.NoError(t, l.Wait(context.Background(), 1<<30))
+	assert.Less(t, time.Since(start), 50*time.Millisecond, "unlimited")
+
+	var nilLimiter *Limiter
+	assert.NoError(t, nilLimiter.Wait(context.Background(), 10))
+}
+
+func TestLimiterStopsOnCancel(t *testing.T) {
+	l := NewLimiter(10)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx, 1000), context.DeadlineExceeded)
+}
+
+func TestManagerJobLimit(t *testing.T) {
+	m := NewManager(1)
+	started := make(chan struct{})
+	id := m.Enqueue(Download, "slow", 0, func(ctx context.Context, tracker *Tracker) error {
+		<-started
+		_, err := io.Copy(io.Discard, tracker.Reader(bytes.NewReader(make([]byte, 200))))
+		return err
+	})
+	m.SetLimit(id, 1000)
+	start := time.Now()
+	close(started)
+
+	job := waitFor(t, m, id, Completed)
+	assert.Equal(t, int64(1000), job.Limit)
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+}
+
+func TestParseBytes(t *testing.T) {
+	for in, want := range map[string]int64{
+		"":       0,
+		"512":    512,
+		"64K":    64 << 10,
+		"10MiB":  10 << 20,
+		"1.5GB":  3 << 29,
+		"2mb/s":  2 << 20,
+		" 1 KiB": 1 << 10,
+	} {
+		got, err := ParseBytes(in)
+		assert.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+	for _, in := range []string{"fast", "-1M", "10X"} {
+		_, err := ParseBytes(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestWindow(t *testing.T) {
+	w, err := ParseWindow("22:00-06:30")
+	assert.NoError(t, err)
+	assert.Equal(t, "22:00-06:30", w.String())
+
+	day := time.Date(2025, 3, 1, 0, 0, 0, 0, time.Local)
+	assert.True(t, w.Contains(day.Add(23*time.Hour)))
+	assert.True(t, w.Contains(day.Add(6*time.Hour)))
+	assert.False(t, w.Contains(day.Add(12*time.Hour)))
+	assert.Equal(t, 10*time.Hour, w.Until(day.Add(12*time.Hour)))
+
+	office, _ := ParseWindow("09:00-17:00")
+	assert.True(t, office.Contains(day.Add(9*time.Hour)))
+	assert.False(t, office.Contains(day.Add(17*time.Hour)))
+	assert.Equal(t, 23*time.Hour, office.Until(day.Add(10*time.Hour)))
+
+	_, err = ParseWindow("22:00")
+	assert.Error(t, err)
+	_, err = ParseWindow("25:00-01:00")
+	assert.Error(t, err)
+}
+
+func TestManagerDefersLargeTransfers(t *testing.T) {
+	m := NewManager(2)
+	now := sinceMidnight(time.Now())
+	// a window that opened an hour from now, so it is closed
+	closed := Window{Start: (now + time.Hour) % (24 * time.Hour), End: (now + 2*time.Hour) % (24 * time.Hour)}
+	m.SetSchedule(&closed, 100)
+
+	run := func(ctx context.Context, tracker *Tracker) error { return nil }
+	small := m.Enqueue(Upload, "small", 10, run)
+	large := m.Enqueue(Upload, "large", 1000, run)
+
+	waitFor(t, m, small, Completed)
+	waitFor(t, m, large, Scheduled)
+
+	m.StartNow(large)
+	waitFor(t, m, large, Completed)
+
+	// lifting the schedule releases waiting transfers
+	other := m.Enqueue(Upload, "other", 1000, run)
+	waitFor(t, m, other, Scheduled)
+	m.SetSchedule(nil, 0)
+	waitFor(t, m, other, Completed)
+}
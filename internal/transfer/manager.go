@@ -0,0 +1,456 @@
+package transfer
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+)
+
+type JobKind int
+
+const (
+	Upload JobKind = iota
+	Download
+	Delete
+	Copy
+)
+
+func (k JobKind) String() string {
+	switch k {
+	case Upload:
+		return "upload"
+	case Delete:
+		return "delete"
+	case Copy:
+		return "copy"
+	}
+	return "download"
+}
+
+type JobState int
+
+const (
+	Queued JobState = iota
+	Running
+	Paused
+	Completed
+	Failed
+	Cancelled
+	Scheduled // waiting for the transfer window
+)
+
+func (s JobState) String() string {
+	switch s {
+	case Queued:
+		return "queued"
+	case Running:
+		return "running"
+	case Paused:
+		return "paused"
+	case Completed:
+		return "completed"
+	case Failed:
+		return "failed"
+	case Cancelled:
+		return "cancelled"
+	case Scheduled:
+		return "scheduled"
+	}
+	return "unknown"
+}
+
+// Finished reports whether the job is no longer queued or running
+func (s JobState) Finished() bool {
+	return s == Completed || s == Failed || s == Cancelled
+}
+
+// RunFunc performs the transfer. It must stop when ctx is done and should pipe
+// its data through the tracker so progress and pauses work
+type RunFunc func(ctx context.Context, tracker *Tracker) error
+
+// Job is a snapshot of a transfer, safe to read after Manager.Jobs returns it
+type Job struct {
+	ID         int
+	Kind       JobKind
+	Name       string
+	State      JobState
+	Total      int64
+	Done       int64
+	Err        error
+	Limit      int64 // bytes per second, 0 for no limit of its own
+	QueuedAt   time.Time
+	FinishedAt time.Time
+}
+
+// Percent returns the progress between 0 and 1, or 0 when the size is unknown
+func (j Job) Percent() float64 {
+	if j.Total <= 0 {
+		if j.State == Completed {
+			return 1
+		}
+		return 0
+	}
+	return float64(j.Done) / float64(j.Total)
+}
+
+// Event notifies listeners that a job changed
+type Event struct {
+	Job Job
+}
+
+type job struct {
+	Job
+	run     RunFunc
+	cancel  context.CancelFunc
+	active  bool // a worker is running this job
+	paused  bool
+	forced  bool // started outside the transfer window
+	limiter *Limiter
+	cond    *sync.Cond
+}
+
+// Manager runs queued transfers with bounded concurrency
+type Manager struct {
+	mu      sync.Mutex
+	jobs    []*job
+	nextID  int
+	workers int
+	running int
+	events  chan Event
+	pending []Job         // changes not yet delivered, the latest one per job
+	notify  chan struct{} // wakes deliver when pending fills
+	global  *Limiter
+	// transfers of at least deferAbove bytes only start inside window
+	window     *Window
+	deferAbove int64
+	wake       *time.Timer
+}
+
+var ErrCancelled = errors.New("transfer cancelled")
+
+func NewManager(workers int) *Manager {
+	if workers < 1 {
+		workers = 1
+	}
+	m := &Manager{
+		workers: workers,
+		nextID:  1,
+		events:  make(chan Event, 256),
+		notify:  make(chan struct{}, 1),
+		global:  NewLimiter(0),
+	}
+	go m.deliver()
+	return m
+}
+
+// SetGlobalLimit caps the combined throughput of all transfers, 0 removes the cap
+func (m *Manager) SetGlobalLimit(rate int64) {
+	m.global.SetRate(rate)
+}
+
+func (m *Manager) GlobalLimit() int64 {
+	return m.global.Rate()
+}
+
+// SetLimit caps the throughput of one transfer, 0 removes the cap
+func (m *Manager) SetLimit(id int, rate int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.find(id)
+	if j == nil {
+		return
+	}
+	j.limiter.SetRate(rate)
+	j.Limit = j.limiter.Rate()
+	m.emit(j)
+}
+
+// SetSchedule defers transfers of at least deferAbove bytes to the window.
+// A nil window runs every transfer straight away
+func (m *Manager) SetSchedule(window *Window, deferAbove int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.window = window
+	m.deferAbove = deferAbove
+	if m.wake != nil {
+		m.wake.Stop()
+		m.wake = nil
+	}
+	m.schedule()
+}
+
+// Schedule returns the transfer window and the size from which transfers wait for it
+func (m *Manager) Schedule() (*Window, int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.window, m.deferAbove
+}
+
+// StartNow lets a scheduled transfer run outside the window
+func (m *Manager) StartNow(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.find(id)
+	if j == nil || j.State != Scheduled {
+		return
+	}
+	j.forced = true
+	j.State = Queued
+	m.emit(j)
+	m.schedule()
+}
+
+// deferred reports whether the job has to wait for the window. Callers hold m.mu
+func (m *Manager) deferred(j *job, now time.Time) bool {
+	return m.window != nil && !j.forced && j.Total >= m.deferAbove && !m.window.Contains(now)
+}
+
+// Events delivers job changes. When the listener falls behind, the changes
+// of a job are merged so it only misses intermediate progress, never the
+// final state
+func (m *Manager) Events() <-chan Event {
+	return m.events
+}
+
+// emit queues a change of j for delivery without blocking. Callers hold m.mu
+func (m *Manager) emit(j *job) {
+	i := slices.IndexFunc(m.pending, func(p Job) bool { return p.ID == j.ID })
+	if i >= 0 {
+		m.pending[i] = j.Job
+	} else {
+		m.pending = append(m.pending, j.Job)
+	}
+	select {
+	case m.notify <- struct{}{}:
+	default:
+	}
+}
+
+// deliver sends the pending changes to Events, outside of m.mu so a slow
+// listener never blocks the transfers
+func (m *Manager) deliver() {
+	for range m.notify {
+		m.mu.Lock()
+		pending := m.pending
+		m.pending = nil
+		m.mu.Unlock()
+		for _, j := range pending {
+			m.events <- Event{Job: j}
+		}
+	}
+}
+
+// Enqueue adds a transfer to the queue and returns its id
+func (m *Manager) Enqueue(kind JobKind, name string, total int64, run RunFunc) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	j := &job{
+		Job: Job{
+			ID:       m.nextID,
+			Kind:     kind,
+			Name:     name,
+			State:    Queued,
+			Total:    total,
+			QueuedAt: time.Now(),
+		},
+		run:     run,
+		limiter: NewLimiter(0),
+	}
+	j.cond = sync.NewCond(&m.mu)
+	m.nextID++
+	m.jobs = append(m.jobs, j)
+	m.emit(j)
+	m.schedule()
+	return j.ID
+}
+
+// schedule starts queued jobs while there are free workers, holding large
+// ones back until the transfer window. Callers hold m.mu
+func (m *Manager) schedule() {
+	now := time.Now()
+	for _, j := range m.jobs {
+		if j.State != Queued && j.State != Scheduled {
+			continue
+		}
+		if m.deferred(j, now) {
+			if j.State != Scheduled {
+				j.State = Scheduled
+				m.emit(j)
+			}
+			if m.wake == nil {
+				m.wake = time.AfterFunc(m.window.Until(now), m.wakeUp)
+			}
+			continue
+		}
+		if m.running >= m.workers {
+			if j.State == Scheduled {
+				// the window is open, queue it behind the running jobs
+				j.State = Queued
+				m.emit(j)
+			}
+			continue
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		j.cancel = cancel
+		j.active = true
+		j.State = Running
+		j.Done = 0
+		j.Err = nil
+		m.running++
+		m.emit(j)
+		go m.runJob(ctx, j)
+	}
+}
+
+// wakeUp runs when the transfer window opens
+func (m *Manager) wakeUp() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.wake = nil
+	m.schedule()
+}
+
+func (m *Manager) runJob(ctx context.Context, j *job) {
+	err := j.run(ctx, &Tracker{m: m, j: j, ctx: ctx})
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.running--
+	j.active = false
+	j.paused = false
+	cancelled := ctx.Err() != nil
+	j.cancel()
+	j.FinishedAt = time.Now()
+	switch {
+	case cancelled:
+		j.State = Cancelled
+		j.Err = ErrCancelled
+	case err != nil:
+		j.State = Failed
+		j.Err = err
+	default:
+		j.State = Completed
+		if j.Total < j.Done {
+			j.Total = j.Done
+		}
+	}
+	m.emit(j)
+	m.schedule()
+}
+
+func (m *Manager) find(id int) *job {
+	for _, j := range m.jobs {
+		if j.ID == id {
+			return j
+		}
+	}
+	return nil
+}
+
+// Pause holds a queued job back or blocks a running one at its next read
+func (m *Manager) Pause(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.find(id)
+	if j == nil || j.State.Finished() || j.paused {
+		return
+	}
+	j.paused = true
+	j.State = Paused
+	m.emit(j)
+}
+
+func (m *Manager) Resume(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.find(id)
+	if j == nil || !j.paused {
+		return
+	}
+	j.paused = false
+	if j.active {
+		j.State = Running
+	} else {
+		j.State = Queued
+	}
+	j.cond.Broadcast()
+	m.emit(j)
+	m.schedule()
+}
+
+// Cancel stops a running job or drops a queued one
+func (m *Manager) Cancel(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.find(id)
+	if j == nil || j.State.Finished() {
+		return
+	}
+	if j.active {
+		j.cancel()
+		j.paused = false
+		j.cond.Broadcast()
+		return
+	}
+	j.State = Cancelled
+	j.Err = ErrCancelled
+	j.FinishedAt = time.Now()
+	m.emit(j)
+}
+
+// Retry puts a failed or cancelled job back in the queue
+func (m *Manager) Retry(id int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	j := m.find(id)
+	if j == nil || (j.State != Failed && j.State != Cancelled) {
+		return
+	}
+	j.State = Queued
+	j.Err = nil
+	j.Done = 0
+	j.paused = false
+	j.FinishedAt = time.Time{}
+	m.emit(j)
+	m.schedule()
+}
+
+// Clear removes every finished job from the list
+func (m *Manager) Clear() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := m.jobs[:0]
+	for _, j := range m.jobs {
+		if !j.State.Finished() {
+			jobs = append(jobs, j)
+		}
+	}
+	m.jobs = jobs
+}
+
+// Jobs returns a snapshot of every job in queue order
+func (m *Manager) Jobs() []Job {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	jobs := make([]Job, len(m.jobs))
+	for i, j := range m.jobs {
+		jobs[i] = j.Job
+	}
+	return jobs
+}
+
+// Active returns the number of jobs that are queued, running or paused
+func (m *Manager) Active() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	n := 0
+	for _, j := range m.jobs {
+		if !j.State.Finished() {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,141 @@
+package transfer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// waitFor polls the manager until the job reaches state or the test times out
+func waitFor(t *testing.T, m *Manager, id int, state JobState) Job {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, j := range m.Jobs() {
+			if j.ID == id && j.State == state {
+				return j
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("job %d never reached state %s", id, state)
+	return Job{}
+}
+
+func TestManagerCompletesJob(t *testing.T) {
+	m := NewManager(2)
+	id := m.Enqueue(Download, "bucket/key", 0, func(ctx context.Context, tracker *Tracker) error {
+		tracker.SetTotal(4)
+		_, err := io.Copy(io.Discard, tracker.Reader(bytes.NewReader([]byte("data"))))
+		return err
+	})
+
+	job := waitFor(t, m, id, Completed)
+	assert.Equal(t, int64(4), job.Done)
+	assert.Equal(t, 1.0, job.Percent())
+}
+
+func TestManagerBoundsConcurrency(t *testing.T) {
+	m := NewManager(1)
+	release := make(chan struct{})
+	first := m.Enqueue(Upload, "first", 0, func(ctx context.Context, tracker *Tracker) error {
+		<-release
+		return nil
+	})
+	second := m.Enqueue(Upload, "second", 0, func(ctx context.Context, tracker *Tracker) error {
+		return nil
+	})
+
+	waitFor(t, m, first, Running)
+	waitFor(t, m, second, Queued)
+	close(release)
+	waitFor(t, m, second, Completed)
+}
+
+func TestManagerCancelAndRetry(t *testing.T) {
+	m := NewManager(1)
+	attempts := 0
+	id := m.Enqueue(Download, "slow", 0, func(ctx context.Context, tracker *Tracker) error {
+		attempts++
+		if attempts > 1 {
+			return nil
+		}
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	waitFor(t, m, id, Running)
+	m.Cancel(id)
+	job := waitFor(t, m, id, Cancelled)
+	assert.ErrorIs(t, job.Err, ErrCancelled)
+
+	m.Retry(id)
+	waitFor(t, m, id, Completed)
+	assert.Equal(t, 2, attempts)
+}
+
+func TestManagerFailedJob(t *testing.T) {
+	m := NewManager(1)
+	id := m.Enqueue(Upload, "broken", 0, func(ctx context.Context, tracker *Tracker) error {
+		return errors.New("boom")
+	})
+
+	job := waitFor(t, m, id, Failed)
+	assert.EqualError(t, job.Err, "boom")
+
+	m.Clear()
+	assert.Empty(t, m.Jobs())
+}
+
+func TestManagerPauseQueuedJob(t *testing.T) {
+	m := NewManager(1)
+	release := make(chan struct{})
+	blocker := m.Enqueue(Upload, "blocker", 0, func(ctx context.Context, tracker *Tracker) error {
+		<-release
+		return nil
+	})
+	paused := m.Enqueue(Upload, "paused", 0, func(ctx context.Context, tracker *Tracker) error {
+		return nil
+	})
+
+	m.Pause(paused)
+	close(release)
+	waitFor(t, m, blocker, Completed)
+	waitFor(t, m, paused, Paused)
+
+	m.Resume(paused)
+	waitFor(t, m, paused, Completed)
+}
+
+func TestManagerKeepsFinalEvents(t *testing.T) {
+	m := NewManager(4)
+	const jobs = 400 // more changes than the events buffer holds
+	for i := range jobs {
+		m.Enqueue(Upload, fmt.Sprintf("file-%d", i), 0, func(ctx context.Context, tracker *Tracker) error {
+			return nil
+		})
+	}
+	for id := 1; id <= jobs; id++ {
+		waitFor(t, m, id, Completed)
+	}
+
+	// nobody listened so far, every job must still report its final state
+	completed := make(map[int]struct{})
+	timeout := time.After(2 * time.Second)
+	for len(completed) < jobs {
+		select {
+		case event := <-m.Events():
+			if event.Job.State == Completed {
+				completed[event.Job.ID] = struct{}{}
+			}
+		case <-timeout:
+			t.Fatalf("only %d of %d jobs reported completion", len(completed), jobs)
+		}
+	}
+}
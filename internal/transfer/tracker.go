@@ -0,0 +1,109 @@
+package transfer
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// progressInterval limits how often progress events are emitted per job
+const progressInterval = 100 * time.Millisecond
+
+// Tracker connects a running job to its manager. A nil tracker is valid and
+// leaves readers untouched so the same transfer code works outside the queue
+type Tracker struct {
+	m        *Manager
+	j        *job
+	ctx      context.Context
+	lastEmit time.Time
+}
+
+// SetTotal records the size of the transfer once it is known
+func (t *Tracker) SetTotal(n int64) {
+	if t == nil {
+		return
+	}
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.j.Total = n
+	t.m.emit(t.j)
+}
+
+// add blocks while the job is paused and adds n to the progress
+func (t *Tracker) add(n int) error {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	for t.j.paused && t.ctx.Err() == nil {
+		t.j.cond.Wait()
+	}
+	t.j.Done += int64(n)
+	if time.Since(t.lastEmit) >= progressInterval {
+		t.lastEmit = time.Now()
+		t.m.emit(t.j)
+	}
+	return t.ctx.Err()
+}
+
+// throttle waits for the job and global bandwidth limits to let n bytes through
+func (t *Tracker) throttle(n int) error {
+	if err := t.j.limiter.Wait(t.ctx, n); err != nil {
+		return err
+	}
+	return t.m.global.Wait(t.ctx, n)
+}
+
+func (t *Tracker) reset() {
+	t.m.mu.Lock()
+	defer t.m.mu.Unlock()
+	t.j.Done = 0
+}
+
+// Reader counts the bytes read from r towards the job progress
+func (t *Tracker) Reader(r io.Reader) io.Reader {
+	if t == nil {
+		return r
+	}
+	return &trackedReader{r: r, t: t}
+}
+
+// ReadSeeker is like Reader but keeps r seekable, which uploads need so the
+// SDK can compute the payload hash and retry
+func (t *Tracker) ReadSeeker(r io.ReadSeeker) io.ReadSeeker {
+	if t == nil {
+		return r
+	}
+	return &trackedReadSeeker{trackedReader: trackedReader{r: r, t: t}, s: r}
+}
+
+type trackedReader struct {
+	r io.Reader
+	t *Tracker
+}
+
+func (r *trackedReader) Read(p []byte) (int, error) {
+	if err := r.t.add(0); err != nil {
+		return 0, err
+	}
+	n, err := r.r.Read(p)
+	if limitErr := r.t.throttle(n); limitErr != nil {
+		return n, limitErr
+	}
+	if addErr := r.t.add(n); addErr != nil {
+		return n, addErr
+	}
+	return n, err
+}
+
+type trackedReadSeeker struct {
+	trackedReader
+	s io.Seeker
+}
+
+func (r *trackedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.s.Seek(offset, whence)
+	if err == nil && pos == 0 {
+		// the body is read again from the start, e.g. after hashing or on retry
+		r.t.reset()
+	}
+	return pos, err
+}
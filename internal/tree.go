@@ -16,6 +16,10 @@ type TreeNode struct {
 	childMap map[string]*TreeNode
 	Parent   *TreeNode
 	Level    int
+	IsDir    bool
+	Marker   bool // a zero-byte "dir/" object keeps the folder alive when it is empty
+	Archive  bool // an archive object whose members are listed as its children
+	Member   bool // a file or folder inside an archive, not an object of its own
 }
 
 func (n *TreeNode) DisplayChildren() string {
@@ -44,6 +48,39 @@ func (t *Tree) displayNode(node *TreeNode, level int) string {
 	return s
 }
 
+// Path returns the object key of the node, without the root "/"
+func (n *TreeNode) Path() string {
+	var parts []string
+	for node := n; node != nil && node.Parent != nil; node = node.Parent {
+		parts = append([]string{node.Value}, parts...)
+	}
+	return strings.Join(parts, "/")
+}
+
+// Key returns the object key backing the node, which ends in "/" for folder markers
+func (n *TreeNode) Key() string {
+	if n.IsDir {
+		return n.Path() + "/"
+	}
+	return n.Path()
+}
+
+// Leaves returns every node under n that is backed by an object: files and
+// folder markers. A file returns itself
+func (n *TreeNode) Leaves() []*TreeNode {
+	if !n.IsDir {
+		return []*TreeNode{n}
+	}
+	var leaves []*TreeNode
+	if n.Marker {
+		leaves = append(leaves, n)
+	}
+	for _, child := range n.Children {
+		leaves = append(leaves, child.Leaves()...)
+	}
+	return leaves
+}
+
 func (n *TreeNode) AddNode(path string, level int) {
 	before, after, found := strings.Cut(path, "/")
 	if n.childMap == nil {
@@ -60,17 +97,151 @@ func (n *TreeNode) AddNode(path string, level int) {
 		n.Children = append(n.Children, node)
 	} else {
 		//create dir node
-		if _, ok := n.childMap[before]; ok {
-			n.childMap[before].AddNode(after, level+1)
+		node, ok := n.childMap[before]
+		if !ok {
+			node = &TreeNode{Value: before, Level: level + 1, Parent: n}
+			n.childMap[before] = node
+			n.Children = append(n.Children, node)
+		}
+		node.IsDir = true
+		if after == "" {
+			// the key ends here, so it is the folder marker itself
+			node.Marker = true
 			return
 		}
-		node := &TreeNode{Value: before, Level: level + 1, Parent: n}
-		n.childMap[before] = node
-		n.Children = append(n.Children, node)
 		node.AddNode(after, level+1)
 	}
 }
 
+// AddArchive lists the members of the archive object n as its children, so
+// the archive can be browsed like a folder. Folder members end in "/"
+func (n *TreeNode) AddArchive(members []string) {
+	n.Archive = true
+	n.Children = nil
+	n.childMap = nil
+	for _, member := range members {
+		n.AddNode(member, n.Level)
+	}
+	for _, child := range n.Children {
+		child.markMember()
+	}
+}
+
+func (n *TreeNode) markMember() {
+	n.Member = true
+	for _, child := range n.Children {
+		child.markMember()
+	}
+}
+
+// ArchiveNode returns the archive n is a member of, n itself when it is an
+// expanded archive, or nil
+func (n *TreeNode) ArchiveNode() *TreeNode {
+	for node := n; node != nil; node = node.Parent {
+		if node.Archive {
+			return node
+		}
+		if !node.Member {
+			return nil
+		}
+	}
+	return nil
+}
+
+// MemberName returns the name of n inside its archive, with a trailing "/"
+// for folders, or "" outside archives
+func (n *TreeNode) MemberName() string {
+	archive := n.ArchiveNode()
+	if archive == nil || archive == n {
+		return ""
+	}
+	return strings.TrimPrefix(n.Key(), archive.Path()+"/")
+}
+
+// Remove deletes the object key from the tree. Folders left empty are removed
+// too, unless a folder marker keeps them
+func (t *Tree) Remove(key string) {
+	node := t.Root
+	for _, part := range strings.Split(strings.TrimSuffix(key, "/"), "/") {
+		node = node.childMap[part]
+		if node == nil {
+			return
+		}
+	}
+
+	if node.IsDir && strings.HasSuffix(key, "/") {
+		node.Marker = false
+		if len(node.Children) != 0 {
+			return
+		}
+	} else if node.IsDir {
+		return
+	}
+
+	for node.Parent != nil {
+		parent := node.Parent
+		delete(parent.childMap, node.Value)
+		for i, child := range parent.Children {
+			if child == node {
+				parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+				break
+			}
+		}
+		if parent.Parent == nil || parent.Marker || len(parent.Children) != 0 {
+			return
+		}
+		node = parent
+	}
+}
+
+// Find returns the folder at prefix, or the deepest existing folder on the
+// way to it. found reports whether the whole prefix exists
+func (t *Tree) Find(prefix string) (node *TreeNode, found bool) {
+	node = t.Root
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return node, true
+	}
+	for _, part := range strings.Split(prefix, "/") {
+		child, ok := node.childMap[part]
+		if !ok || !child.IsDir {
+			return node, false
+		}
+		node = child
+	}
+	return node, true
+}
+
+// Lookup returns the node at path, which may be a folder, a file or an archive member
+func (t *Tree) Lookup(path string) (*TreeNode, bool) {
+	if t.Root == nil {
+		return nil, false
+	}
+	node := t.Root
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return node, true
+	}
+	for _, part := range strings.Split(path, "/") {
+		child, ok := node.childMap[part]
+		if !ok {
+			return nil, false
+		}
+		node = child
+	}
+	return node, true
+}
+
+// Attached reports whether the node is still part of its tree
+func (n *TreeNode) Attached() bool {
+	for node := n; node.Parent != nil; node = node.Parent {
+		if node.Parent.childMap[node.Value] != node {
+			return false
+		}
+	}
+	return true
+}
+
 func CreateTree(objs []string) *Tree {
 	t := &Tree{}
 	t.Root = &TreeNode{
@@ -79,6 +250,7 @@ func CreateTree(objs []string) *Tree {
 		Children: []*TreeNode{},
 		childMap: make(map[string]*TreeNode),
 		Parent:   nil,
+		IsDir:    true,
 	}
 	for _, obj := range objs {
 		t.Root.AddNode(obj, 0)
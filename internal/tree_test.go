@@ -70,6 +70,128 @@ func TestAddNodeDuplicate(t *testing.T) {
 	assert.Equal(t, 1, len(root.Children))
 }
 
+func TestPathAndLeaves(t *testing.T) {
+	tree := CreateTree([]string{"dir1/sub/file1.txt", "dir1/file2.txt", "file3.txt"})
+	dir1 := tree.Root.childMap["dir1"]
+	sub := dir1.childMap["sub"]
+
+	assert.Equal(t, "dir1/sub/file1.txt", sub.childMap["file1.txt"].Path())
+	assert.Equal(t, "dir1", dir1.Path())
+	assert.Equal(t, "", tree.Root.Path())
+
+	var keys []string
+	for _, leaf := range dir1.Leaves() {
+		keys = append(keys, leaf.Path())
+	}
+	assert.ElementsMatch(t, []string{"dir1/sub/file1.txt", "dir1/file2.txt"}, keys)
+	assert.Len(t, tree.Root.Leaves(), 3)
+}
+
+func TestFolderMarkers(t *testing.T) {
+	tree := CreateTree([]string{"empty/", "docs/", "docs/a.txt", "logs/b.txt"})
+	empty := tree.Root.childMap["empty"]
+	assert.True(t, empty.IsDir)
+	assert.True(t, empty.Marker)
+	assert.Empty(t, empty.Children, "no blank child for the marker")
+	assert.Equal(t, "empty/", empty.Key())
+
+	var keys []string
+	for _, leaf := range tree.Root.Leaves() {
+		keys = append(keys, leaf.Key())
+	}
+	assert.ElementsMatch(t, []string{"empty/", "docs/", "docs/a.txt", "logs/b.txt"}, keys)
+}
+
+func TestRemove(t *testing.T) {
+	tree := CreateTree([]string{"docs/", "docs/a.txt", "logs/day/b.txt", "c.txt"})
+	docs := tree.Root.childMap["docs"]
+	logs := tree.Root.childMap["logs"]
+
+	// the marker keeps the folder
+	tree.Remove("docs/a.txt")
+	assert.True(t, docs.Attached())
+	assert.Empty(t, docs.Children)
+
+	// implicit folders vanish with their last object
+	tree.Remove("logs/day/b.txt")
+	assert.False(t, logs.Attached())
+	assert.Nil(t, tree.Root.childMap["logs"])
+
+	// removing the marker of an empty folder removes the folder
+	tree.Remove("docs/")
+	assert.False(t, docs.Attached())
+
+	tree.Remove("missing/key")
+	assert.Len(t, tree.Root.Children, 1)
+}
+
+func TestFind(t *testing.T) {
+	tree := CreateTree([]string{"logs/2024/01/a.log", "logs/readme.txt"})
+
+	node, found := tree.Find("logs/2024/")
+	assert.True(t, found)
+	assert.Equal(t, "logs/2024", node.Path())
+
+	node, found = tree.Find("logs/2025/01")
+	assert.False(t, found)
+	assert.Equal(t, "logs", node.Path(), "deepest existing folder")
+
+	_, found = tree.Find("logs/readme.txt")
+	assert.False(t, found, "files are not folders")
+
+	node, found = tree.Find("")
+	assert.True(t, found)
+	assert.Same(t, tree.Root, node)
+}
+
+func TestLookup(t *testing.T) {
+	tree := CreateTree([]string{"logs/2024/a.log", "app.zip"})
+
+	node, found := tree.Lookup("logs/2024/a.log")
+	assert.True(t, found)
+	assert.False(t, node.IsDir)
+
+	node, found = tree.Lookup("logs/2024/")
+	assert.True(t, found)
+	assert.Equal(t, "logs/2024", node.Path())
+
+	node.Parent.Parent.Children[1].AddArchive([]string{"bin/app"})
+	node, found = tree.Lookup("app.zip/bin/app")
+	assert.True(t, found)
+	assert.True(t, node.Member)
+
+	_, found = tree.Lookup("logs/2025")
+	assert.False(t, found)
+	_, found = (&Tree{}).Lookup("")
+	assert.False(t, found)
+}
+
+func TestAddArchive(t *testing.T) {
+	tree := CreateTree([]string{"builds/app.zip", "builds/readme.txt"})
+	archive, _ := tree.Find("builds")
+	archive = archive.Children[0]
+
+	archive.AddArchive([]string{"bin/", "bin/app", "LICENSE"})
+	assert.True(t, archive.Archive)
+	assert.False(t, archive.IsDir)
+	assert.Len(t, archive.Children, 2)
+	assert.Equal(t, []*TreeNode{archive}, archive.Leaves(), "members are not objects")
+
+	bin := archive.Children[0]
+	app := bin.Children[0]
+	assert.True(t, app.Member)
+	assert.Same(t, archive, app.ArchiveNode())
+	assert.Same(t, archive, archive.ArchiveNode())
+	assert.Nil(t, archive.Parent.ArchiveNode())
+	assert.Equal(t, "bin/app", app.MemberName())
+	assert.Equal(t, "bin/", bin.MemberName())
+	assert.Equal(t, "", archive.MemberName())
+
+	// listing again replaces the members
+	archive.AddArchive([]string{"other"})
+	assert.Len(t, archive.Children, 1)
+}
+
 func TestDisplayNodeNil(t *testing.T) {
 	tree := &Tree{}
 	result := tree.displayNode(nil, 1)
@@ -6,8 +6,65 @@ type APIMessage struct {
 	Err      error
 	Response any
 	Status   string
+	Severity Severity // inferred from the other fields when unset
+}
+
+// Severity is how much a status message matters to the user
+type Severity int
+
+const (
+	SeverityUnset Severity = iota
+	SeverityInfo
+	SeveritySuccess
+	SeverityWarn
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeveritySuccess:
+		return "success"
+	case SeverityWarn:
+		return "warn"
+	case SeverityError:
+		return "error"
+	}
+	return "unset"
+}
+
+// Level is the severity of the message: its own, or error when it carries an
+// error, success for a response and info otherwise
+func (m APIMessage) Level() Severity {
+	switch {
+	case m.Severity != SeverityUnset:
+		return m.Severity
+	case m.Err != nil:
+		return SeverityError
+	case m.Status == "" && m.Response != nil:
+		return SeveritySuccess
+	}
+	return SeverityInfo
+}
+
+// Text is what the message says
+func (m APIMessage) Text() string {
+	switch {
+	case m.Status != "":
+		return m.Status
+	case m.Err != nil:
+		return m.Err.Error()
+	case m.Response != nil:
+		if response, ok := m.Response.(string); ok {
+			return response
+		}
+		return "Invalid response type"
+	}
+	return ""
 }
 
 type AWSConfigMessage struct {
-	Config aws.Config
+	Config   aws.Config
+	Endpoint *Endpoint // nil for AWS
 }
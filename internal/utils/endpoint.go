@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+)
+
+// defaultSigningRegion is used for endpoints that do not care about the region
+const defaultSigningRegion = "us-east-1"
+
+// LoadEndpointConfig builds the AWS config used to talk to an S3-compatible endpoint
+func LoadEndpointConfig(ep internal.Endpoint) (aws.Config, error) {
+	if ep.URL == "" {
+		return aws.Config{}, fmt.Errorf("endpoint %q has no url", ep.Name)
+	}
+
+	region := ep.SigningRegion
+	if region == "" {
+		region = defaultSigningRegion
+	}
+	opts := []func(*config.LoadOptions) error{config.WithRegion(region)}
+
+	creds := ep.Credentials
+	switch creds.Source {
+	case "", internal.CredentialsDefault:
+	case internal.CredentialsStatic:
+		opts = append(opts, config.WithCredentialsProvider(aws.NewCredentialsCache(
+			credentials.NewStaticCredentialsProvider(creds.AccessKeyID, creds.SecretAccessKey, creds.SessionToken),
+		)))
+	case internal.CredentialsProfile:
+		opts = append(opts, config.WithSharedConfigProfile(creds.Profile))
+	case internal.CredentialsEnv:
+		accessKey, secretKey := os.Getenv(creds.AccessKeyEnv), os.Getenv(creds.SecretKeyEnv)
+		if accessKey == "" || secretKey == "" {
+			return aws.Config{}, fmt.Errorf("endpoint %q: %s and %s must be set", ep.Name, creds.AccessKeyEnv, creds.SecretKeyEnv)
+		}
+		opts = append(opts, config.WithCredentialsProvider(aws.NewCredentialsCache(
+			credentials.NewStaticCredentialsProvider(accessKey, secretKey, ""),
+		)))
+	default:
+		return aws.Config{}, fmt.Errorf("endpoint %q: unknown credentials source %q", ep.Name, creds.Source)
+	}
+
+	if ep.TLS.InsecureSkipVerify || ep.TLS.CAFile != "" {
+		tlsConfig, err := endpointTLSConfig(ep.TLS)
+		if err != nil {
+			return aws.Config{}, fmt.Errorf("endpoint %q: %w", ep.Name, err)
+		}
+		opts = append(opts, config.WithHTTPClient(awshttp.NewBuildableClient().WithTransportOptions(
+			func(tr *http.Transport) {
+				tr.TLSClientConfig = tlsConfig
+			})))
+	}
+
+	return config.LoadDefaultConfig(context.TODO(), opts...)
+}
+
+func endpointTLSConfig(opts internal.EndpointTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+	if opts.CAFile == "" {
+		return tlsConfig, nil
+	}
+
+	pem, err := os.ReadFile(ExpandHome(opts.CAFile))
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+	}
+	tlsConfig.RootCAs = pool
+	return tlsConfig, nil
+}
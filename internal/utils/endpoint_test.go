@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadEndpointConfigStatic(t *testing.T) {
+	cfg, err := LoadEndpointConfig(internal.Endpoint{
+		Name: "minio",
+		URL:  "http://localhost:9000",
+		Credentials: internal.EndpointCredentials{
+			Source:          internal.CredentialsStatic,
+			AccessKeyID:     "key",
+			SecretAccessKey: "secret",
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, defaultSigningRegion, cfg.Region)
+
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "key", creds.AccessKeyID)
+}
+
+func TestLoadEndpointConfigEnv(t *testing.T) {
+	ep := internal.Endpoint{
+		Name:          "r2",
+		URL:           "https://example.r2.cloudflarestorage.com",
+		SigningRegion: "auto",
+		Credentials: internal.EndpointCredentials{
+			Source:       internal.CredentialsEnv,
+			AccessKeyEnv: "TEST_R2_KEY",
+			SecretKeyEnv: "TEST_R2_SECRET",
+		},
+	}
+	_, err := LoadEndpointConfig(ep)
+	assert.Error(t, err, "unset variables")
+
+	t.Setenv("TEST_R2_KEY", "key")
+	t.Setenv("TEST_R2_SECRET", "secret")
+	cfg, err := LoadEndpointConfig(ep)
+	assert.NoError(t, err)
+	assert.Equal(t, "auto", cfg.Region)
+}
+
+func TestLoadEndpointConfigErrors(t *testing.T) {
+	_, err := LoadEndpointConfig(internal.Endpoint{Name: "nourl"})
+	assert.Error(t, err)
+
+	_, err = LoadEndpointConfig(internal.Endpoint{
+		Name:        "bad",
+		URL:         "http://localhost",
+		Credentials: internal.EndpointCredentials{Source: "vault"},
+	})
+	assert.Error(t, err)
+
+	_, err = LoadEndpointConfig(internal.Endpoint{
+		Name: "ca",
+		URL:  "https://localhost",
+		TLS:  internal.EndpointTLS{CAFile: "/does/not/exist.pem"},
+	})
+	assert.Error(t, err)
+}
@@ -0,0 +1,99 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// logFile is where logs go by default, inside AppDir
+const logFile = "debug.log"
+
+// LogConfig picks the level, format and output of the application log
+type LogConfig struct {
+	// Level is debug, info, warn or error. Empty is info
+	Level string `yaml:"level"`
+	// Format is text or json. Empty is text
+	Format string `yaml:"format"`
+	// Output is a file to append to. Empty is debug.log in the application
+	// directory. The terminal belongs to the interface, so stdout and stderr
+	// are refused
+	Output string `yaml:"output"`
+}
+
+// Over fills the settings c leaves empty from base
+func (c LogConfig) Over(base LogConfig) LogConfig {
+	if c.Level == "" {
+		c.Level = base.Level
+	}
+	if c.Format == "" {
+		c.Format = base.Format
+	}
+	if c.Output == "" {
+		c.Output = base.Output
+	}
+	return c
+}
+
+// Validate checks the level, format and output without opening the output
+func (c LogConfig) Validate() error {
+	if _, err := c.level(); err != nil {
+		return err
+	}
+	switch c.Output {
+	case "stdout", "stderr", "-", "/dev/stdout", "/dev/stderr", "/dev/tty":
+		return fmt.Errorf("log output %q would draw over the interface, give a file instead", c.Output)
+	}
+	switch strings.ToLower(c.Format) {
+	case "", "text", "json":
+		return nil
+	}
+	return fmt.Errorf("log format %q is not text or json", c.Format)
+}
+
+func (c LogConfig) level() (slog.Level, error) {
+	var level slog.Level
+	if c.Level == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return level, fmt.Errorf("log level %q is not debug, info, warn or error", c.Level)
+	}
+	return level, nil
+}
+
+// NewLogger opens the output of c and returns a logger writing to it. Closing
+// the returned closer closes the log file
+func NewLogger(c LogConfig) (*slog.Logger, io.Closer, error) {
+	if err := c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	level, _ := c.level()
+
+	path := ExpandHome(c.Output)
+	if path == "" {
+		dir, err := AppDir()
+		if err != nil {
+			return nil, nil, err
+		}
+		path = filepath.Join(dir, logFile)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, nil, fmt.Errorf("opening log file: %w", err)
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	if strings.ToLower(c.Format) == "json" {
+		return slog.New(slog.NewJSONHandler(f, opts)), f, nil
+	}
+	return slog.New(slog.NewTextHandler(f, opts)), f, nil
+}
+
+// DiscardLogger drops every record, for code that was not given a logger
+func DiscardLogger() *slog.Logger {
+	return slog.New(slog.DiscardHandler)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogConfigValidate(t *testing.T) {
+	assert.NoError(t, LogConfig{}.Validate())
+	assert.NoError(t, LogConfig{Level: "DEBUG", Format: "json"}.Validate())
+	assert.Error(t, LogConfig{Level: "verbose"}.Validate())
+	assert.Error(t, LogConfig{Format: "xml"}.Validate())
+	assert.Error(t, LogConfig{Output: "stdout"}.Validate(), "logs would draw over the interface")
+}
+
+func TestLogConfigOver(t *testing.T) {
+	flags := LogConfig{Level: "debug"}
+	file := LogConfig{Level: "warn", Format: "json"}
+	assert.Equal(t, LogConfig{Level: "debug", Format: "json"}, flags.Over(file))
+}
+
+func TestNewLogger(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	logger, closer, err := NewLogger(LogConfig{Level: "info", Format: "json", Output: path})
+	assert.NoError(t, err)
+	logger.Debug("hidden")
+	logger.Info("shown", "bucket", "logs")
+	assert.NoError(t, closer.Close())
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.NotContains(t, string(data), "hidden")
+	assert.Contains(t, string(data), `"msg":"shown","bucket":"logs"`)
+}
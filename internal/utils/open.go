@@ -0,0 +1,65 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// CacheDir returns the per-user cache directory of the application, creating it if needed
+func CacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	dir = filepath.Join(dir, appName)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// ObjectCacheDir returns the directory caching one version of an object. The
+// directory is keyed by bucket, key and ETag so a changed object gets a new one
+func ObjectCacheDir(cacheDir, bucket, key, etag string) string {
+	sum := sha256.Sum256([]byte(bucket + "\x00" + key + "\x00" + strings.Trim(etag, `"`)))
+	return filepath.Join(cacheDir, "objects", hex.EncodeToString(sum[:])[:32])
+}
+
+// CachedObject returns the path of the cached copy of the object and whether it exists
+func CachedObject(cacheDir, bucket, key, etag string) (string, bool) {
+	path := filepath.Join(ObjectCacheDir(cacheDir, bucket, key, etag), filepath.Base(key))
+	info, err := os.Stat(path)
+	return path, err == nil && info.Mode().IsRegular()
+}
+
+// OpenFile launches path with the opener command, or the platform default
+// when opener is empty. It does not wait for the application to exit
+func OpenFile(path, opener string) error {
+	args := strings.Fields(opener)
+	if len(args) == 0 {
+		switch runtime.GOOS {
+		case "darwin":
+			args = []string{"open"}
+		case "windows":
+			args = []string{"rundll32", "url.dll,FileProtocolHandler"}
+		default:
+			args = []string{"xdg-open"}
+		}
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return errors.New("no opener found: install " + args[0] + " or set opener in the config")
+	}
+
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
@@ -0,0 +1,32 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCachedObject(t *testing.T) {
+	cache := t.TempDir()
+
+	path, ok := CachedObject(cache, "bucket", "docs/report.pdf", `"abc"`)
+	assert.False(t, ok)
+	assert.Equal(t, "report.pdf", filepath.Base(path), "keeps the name for the opener")
+	assert.Equal(t, ObjectCacheDir(cache, "bucket", "docs/report.pdf", "abc"), filepath.Dir(path), "quotes are ignored")
+
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	assert.NoError(t, os.WriteFile(path, []byte("pdf"), 0o644))
+	_, ok = CachedObject(cache, "bucket", "docs/report.pdf", "abc")
+	assert.True(t, ok)
+
+	// a new version of the object is not served from the cache
+	_, ok = CachedObject(cache, "bucket", "docs/report.pdf", "def")
+	assert.False(t, ok)
+	assert.NotEqual(t, ObjectCacheDir(cache, "other", "docs/report.pdf", "abc"), filepath.Dir(path))
+}
+
+func TestOpenFileMissingOpener(t *testing.T) {
+	assert.Error(t, OpenFile("file.txt", "definitely-not-an-opener --flag"))
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompletePath(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "docs"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "data.csv"), nil, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, ".hidden"), nil, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "notes.txt"), nil, 0o644))
+
+	prefix := dir + string(filepath.Separator)
+	assert.Equal(t, []string{prefix + "data.csv", prefix + "docs" + string(filepath.Separator)}, CompletePath(prefix+"d"))
+	assert.Len(t, CompletePath(prefix), 3)
+	assert.Equal(t, []string{prefix + ".hidden"}, CompletePath(prefix+"."))
+	assert.Nil(t, CompletePath(filepath.Join(dir, "missing", "x")))
+}
+
+func TestStateRoundTrip(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	var missing map[string]string
+	assert.NoError(t, LoadState("missing.json", &missing))
+	assert.Nil(t, missing)
+
+	assert.NoError(t, SaveState("dirs.json", map[string]string{"bucket": "/tmp"}))
+	loaded := map[string]string{}
+	assert.NoError(t, LoadState("dirs.json", &loaded))
+	assert.Equal(t, "/tmp", loaded["bucket"])
+}
@@ -1,18 +1,11 @@
 package main
 
 import (
-	"fmt"
 	"os"
 
-	"github.com/Aearsears/fuzzy-guacamole/services"
-
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/Aearsears/fuzzy-guacamole/cmd"
 )
 
 func main() {
-	p := tea.NewProgram(services.InitTUI())
-	if _, err := p.Run(); err != nil {
-		fmt.Printf("Alas, there's been an error: %v", err)
-		os.Exit(1)
-	}
+	os.Exit(cmd.Execute(os.Args[1:]))
 }
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// bookmarksFile stores the saved bookmarks
+const bookmarksFile = "bookmarks.json"
+
+// AddBookmarkMessage asks the TUI to bookmark a location under the current profile
+type AddBookmarkMessage struct {
+	bucket string
+	prefix string
+}
+
+// OpenBookmarkMessage asks the TUI to switch to the bookmark's profile and open its location
+type OpenBookmarkMessage struct {
+	bookmark internal.Bookmark
+}
+
+func loadBookmarks() ([]internal.Bookmark, error) {
+	var bookmarks []internal.Bookmark
+	err := utils.LoadState(bookmarksFile, &bookmarks)
+	return bookmarks, err
+}
+
+type BookmarksMenu struct {
+	bookmarks []internal.Bookmark
+	cursor    int
+	err       error
+	theme     *Theme
+}
+
+func InitBookmarksMenu(theme *Theme) BookmarksMenu {
+	bookmarks, err := loadBookmarks()
+	return BookmarksMenu{bookmarks: bookmarks, err: err, theme: theme}
+}
+
+func (m BookmarksMenu) Init() tea.Cmd {
+	if m.err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("loading bookmarks: %w", m.err)})
+	}
+	return nil
+}
+
+func (m BookmarksMenu) KeyMap() help.KeyMap {
+	has := len(m.bookmarks) != 0
+	keys := []key.Binding{
+		when(keysOf(bookmarksMenu).Up, has), when(keysOf(bookmarksMenu).Down, has),
+		when(withHelp(keysOf(bookmarksMenu).Enter, "open"), has),
+		when(withHelp(keysOf(bookmarksMenu).Delete, "remove"), has),
+	}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys, scrollKeys(keysOf(bookmarksMenu), has)}}
+}
+
+func (m BookmarksMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if cursor, ok := scrollKey(keysOf(bookmarksMenu), msg, m.cursor, len(m.bookmarks), m.rows()); ok {
+			m.cursor = cursor
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, keysOf(bookmarksMenu).Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case key.Matches(msg, keysOf(bookmarksMenu).Down):
+			if m.cursor < len(m.bookmarks)-1 {
+				m.cursor++
+			}
+		case key.Matches(msg, keysOf(bookmarksMenu).Enter):
+			if len(m.bookmarks) != 0 {
+				return m, utils.SendMessage(OpenBookmarkMessage{bookmark: m.bookmarks[m.cursor]})
+			}
+		case key.Matches(msg, keysOf(bookmarksMenu).Delete):
+			if len(m.bookmarks) != 0 {
+				removed := m.bookmarks[m.cursor]
+				m.bookmarks = internal.RemoveBookmark(m.bookmarks, m.cursor)
+				if m.cursor > len(m.bookmarks)-1 {
+					m.cursor = max(len(m.bookmarks)-1, 0)
+				}
+				if err := utils.SaveState(bookmarksFile, m.bookmarks); err != nil {
+					return m, utils.SendMessage(internal.APIMessage{Err: err})
+				}
+				return m, utils.SendMessage(internal.APIMessage{
+					Status:   fmt.Sprintf("Removed bookmark %s", removed.Name),
+					Severity: internal.SeveritySuccess,
+				})
+			}
+		}
+	}
+	return m, nil
+}
+
+// rows is the number of bookmarks that fit below the title
+func (m BookmarksMenu) rows() int {
+	return listRows(2)
+}
+
+func (m BookmarksMenu) View() string {
+	var b strings.Builder
+	start, end := scrollWindow(m.cursor, len(m.bookmarks), m.rows())
+	b.WriteString(m.theme.Header.Render("Bookmarks") + " " + m.theme.Help.Render(scrollPosition(start, end, len(m.bookmarks))) + "\n\n")
+
+	if len(m.bookmarks) == 0 {
+		b.WriteString(m.theme.Doc.Render("No bookmarks. Press [b] in the S3 view to bookmark a bucket or folder.\n"))
+	}
+	for i := start; i < end; i++ {
+		bookmark := m.bookmarks[i]
+		cursor := " "
+		display := clip(fmt.Sprintf("%-40s %s", bookmark.Name, bookmark.Profile), innerWidth(windowWidth()))
+		if i == m.cursor {
+			cursor = m.theme.Cursor.Render(">")
+			display = m.theme.Selected.Render(display)
+		} else {
+			display = m.theme.Choice.Render(display)
+		}
+		b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+	}
+
+	return m.theme.Border.Render(b.String())
+}
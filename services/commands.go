@@ -0,0 +1,208 @@
+package services
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Command is an entry of the ":" command line
+type Command struct {
+	Name  string
+	Usage string // arguments, e.g. "<bucket>[/prefix]"
+	Help  string
+	// Complete returns the candidate arguments, it is nil for commands without any
+	Complete func(arg string) []string
+	Run      func(arg string) tea.Cmd
+}
+
+// commander views contribute commands to the command line while they are shown
+type commander interface {
+	Commands() []Command
+}
+
+// typing views have a focused text input, so keys like ":" are text for them
+type typing interface {
+	Typing() bool
+}
+
+// SwitchProfileMessage asks the TUI to use another profile or endpoint
+type SwitchProfileMessage struct {
+	profile string
+}
+
+// SwitchRegionMessage asks the TUI to use another region with the current profile
+type SwitchRegionMessage struct {
+	region string
+}
+
+// SwitchThemeMessage asks the TUI to restyle every view, with the next theme
+// when theme is empty
+type SwitchThemeMessage struct {
+	theme string
+}
+
+// regions lists the AWS regions offered by ":region" completion
+var regions = []string{
+	"af-south-1", "ap-east-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+	"ap-south-1", "ap-south-2", "ap-southeast-1", "ap-southeast-2", "ap-southeast-3",
+	"ap-southeast-4", "ca-central-1", "ca-west-1", "eu-central-1", "eu-central-2",
+	"eu-north-1", "eu-south-1", "eu-south-2", "eu-west-1", "eu-west-2", "eu-west-3",
+	"il-central-1", "me-central-1", "me-south-1", "sa-east-1",
+	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+}
+
+// commands returns the global commands followed by those of the current view.
+// A view command replaces a global one with the same name
+func (m TUI) commands() []Command {
+	byName := make(map[string]Command)
+	for _, c := range m.globalCommands() {
+		byName[c.Name] = c
+	}
+	if view, ok := m.views[m.state].(commander); ok {
+		for _, c := range view.Commands() {
+			byName[c.Name] = c
+		}
+	}
+
+	commands := make([]Command, 0, len(byName))
+	for _, c := range byName {
+		commands = append(commands, c)
+	}
+	sort.Slice(commands, func(i, j int) bool {
+		return commands[i].Name < commands[j].Name
+	})
+	return commands
+}
+
+func (m TUI) globalCommands() []Command {
+	switchTo := func(menu SessionState) func(string) tea.Cmd {
+		return func(string) tea.Cmd {
+			return utils.SendMessage(SwitchMenuMessage{menu: menu})
+		}
+	}
+	return []Command{
+		{
+			Name:     "profile",
+			Usage:    "<profile>",
+			Help:     "switch to an AWS profile or S3-compatible endpoint",
+			Complete: func(string) []string { return m.profileNames() },
+			Run: func(arg string) tea.Cmd {
+				if arg == "" {
+					return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("usage: :profile <profile>")})
+				}
+				return utils.SendMessage(SwitchProfileMessage{profile: arg})
+			},
+		},
+		{
+			Name:     "region",
+			Usage:    "<region>",
+			Help:     "use another region with the current profile",
+			Complete: func(string) []string { return regions },
+			Run: func(arg string) tea.Cmd {
+				if arg == "" {
+					return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("usage: :region <region>")})
+				}
+				return utils.SendMessage(SwitchRegionMessage{region: arg})
+			},
+		},
+		{
+			Name:     "bucket",
+			Usage:    "<bucket>[/prefix]",
+			Help:     "open a bucket, optionally at a prefix",
+			Complete: func(string) []string { return m.bucketNames() },
+			Run: func(arg string) tea.Cmd {
+				bucket, prefix, _ := strings.Cut(strings.Trim(arg, "/"), "/")
+				if bucket == "" {
+					return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("usage: :bucket <bucket>[/prefix]")})
+				}
+				return utils.SendMessage(OpenBookmarkMessage{
+					bookmark: internal.NewBookmark(m.profile, bucket, prefix),
+				})
+			},
+		},
+		{Name: "s3", Help: "open the S3 view", Run: switchTo(s3Menu)},
+		{Name: "profiles", Help: "open the profile list", Run: switchTo(profileMenu)},
+		{Name: "bookmarks", Help: "open the bookmarks", Run: switchTo(bookmarksMenu)},
+		{Name: "transfers", Help: "open the transfer queue", Run: switchTo(transfersMenu)},
+		{Name: "messages", Help: "open the log of status messages", Run: switchTo(messagesMenu)},
+		{Name: "menu", Help: "open the main menu", Run: switchTo(mainMenu)},
+		{
+			Name:     "theme",
+			Usage:    "[theme]",
+			Help:     "switch the color theme, or cycle to the next one",
+			Complete: func(string) []string { return m.appConfig.ThemeNames() },
+			Run: func(arg string) tea.Cmd {
+				return utils.SendMessage(SwitchThemeMessage{theme: arg})
+			},
+		},
+		{
+			Name:     "keymap",
+			Usage:    "[file]",
+			Help:     "write the default key bindings to a file, to customise in the config",
+			Complete: utils.CompletePath,
+			Run: func(arg string) tea.Cmd {
+				path := utils.ExpandHome(arg)
+				if path == "" {
+					dir, err := utils.AppDir()
+					if err != nil {
+						return utils.SendMessage(internal.APIMessage{Err: err})
+					}
+					path = filepath.Join(dir, keymapFile)
+				}
+				if err := DumpKeymap(path); err != nil {
+					return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("writing keymap: %w", err)})
+				}
+				return utils.SendMessage(internal.APIMessage{Status: fmt.Sprintf("Wrote the default keymap to %s", path), Severity: internal.SeveritySuccess})
+			},
+		},
+		{Name: "quit", Help: "quit the application", Run: func(string) tea.Cmd { return tea.Quit }},
+	}
+}
+
+// profileNames lists the AWS profiles followed by the configured endpoints
+func (m TUI) profileNames() []string {
+	var names []string
+	for name := range GetProfiles(m.logger) {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, ep := range m.appConfig.Endpoints {
+		names = append(names, ep.Name)
+	}
+	return names
+}
+
+// bucketNames lists the buckets of the S3 view, when it has loaded them
+func (m TUI) bucketNames() []string {
+	view, ok := m.views[s3Menu].(S3Menu)
+	if !ok {
+		return nil
+	}
+	names := make([]string, 0, len(view.buckets))
+	for _, b := range view.buckets {
+		names = append(names, *b.Name)
+	}
+	return names
+}
+
+// useConfig switches to cfg and endpoint, rebuilding the views that hold clients
+func (m TUI) useConfig(profile string, cfg aws.Config, endpoint *internal.Endpoint) (TUI, tea.Cmd) {
+	m.profile, m.config, m.endpoint = profile, cfg, endpoint
+	delete(m.views, profileMenu)
+	if m.state == profileMenu {
+		m.state = mainMenu
+	}
+	var cmd tea.Cmd
+	if m.views[s3Menu] != nil {
+		m.views[s3Menu] = InitS3Menu(m.config, m.endpoint, m.appConfig, m.logger, m.theme)
+		cmd = m.views[s3Menu].Init()
+	}
+	return m, cmd
+}
@@ -0,0 +1,499 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/s3"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// OpenCompareMessage asks the TUI to open the compare view with a bucket prefix on the left
+type OpenCompareMessage struct {
+	client s3.S3API
+	bucket string
+	prefix string
+}
+
+// fields of the compare form, in display order
+const (
+	compareFieldLeftProfile = iota
+	compareFieldLeftBucket
+	compareFieldLeftPrefix
+	compareFieldRightProfile
+	compareFieldRightBucket
+	compareFieldRightPrefix
+	compareFieldRun
+)
+
+var compareFieldNames = []string{"Left profile", "Left bucket", "Left prefix", "Right profile", "Right bucket", "Right prefix", "Compare"}
+
+// actions the compare view input prompt can be answering
+const (
+	compareInputField = iota
+	compareInputExport
+	compareInputConfirmCopy
+)
+
+// compareLocation is one side of the compare form
+type compareLocation struct {
+	profile string
+	bucket  string
+	prefix  string
+}
+
+type CompareMenu struct {
+	profile     string   // profile of client
+	client      s3.S3API // client of the current profile
+	clients     map[string]s3.S3API
+	clientFor   func(profile string) (s3.S3API, error)
+	left        compareLocation
+	right       compareLocation
+	input       s3.DiffInput // sides of the last comparison
+	entries     []internal.DiffEntry
+	counts      internal.DiffCounts
+	reviewing   bool // false = editing the form, true = reviewing the differences
+	cursor      int
+	prompt      textinput.Model
+	promptFor   int
+	editField   int
+	copyToRight bool // direction of the pending copy
+	loading     bool
+	spinner     spinner.Model
+	theme       *Theme
+}
+
+// InitCompareMenu opens the form with the location on the left and the same
+// profile on the right. clientFor creates clients for other profiles
+func InitCompareMenu(profile string, client s3.S3API, bucket, prefix string, clientFor func(string) (s3.S3API, error), theme *Theme) CompareMenu {
+	prompt := textinput.New()
+	prompt.Prompt = "$ "
+	prompt.CharLimit = 250
+	prompt.Width = 50
+
+	return CompareMenu{
+		theme:     theme,
+		profile:   profile,
+		client:    client,
+		clients:   map[string]s3.S3API{profile: client},
+		clientFor: clientFor,
+		left:      compareLocation{profile: profile, bucket: bucket, prefix: prefix},
+		right:     compareLocation{profile: profile},
+		cursor:    compareFieldRightBucket,
+		prompt:    prompt,
+		spinner:   CreateSpinner(),
+	}
+}
+
+func (m CompareMenu) Init() tea.Cmd {
+	return nil
+}
+
+func (m CompareMenu) Typing() bool {
+	return m.prompt.Focused()
+}
+
+func (m CompareMenu) KeyMap() help.KeyMap {
+	if m.prompt.Focused() {
+		return promptKeys(compareMenu)
+	}
+	if m.loading {
+		return viewKeyMap{}
+	}
+	if !m.reviewing {
+		keys := []key.Binding{keysOf(compareMenu).Up, keysOf(compareMenu).Down, withHelp(keysOf(compareMenu).Enter, "edit/compare")}
+		return viewKeyMap{short: keys, full: [][]key.Binding{keys}}
+	}
+	has := len(m.entries) != 0
+	nav := []key.Binding{when(keysOf(compareMenu).Up, has), when(keysOf(compareMenu).Down, has), withHelp(keysOf(compareMenu).Backspace, "back to options")}
+	actions := []key.Binding{
+		when(keysOf(compareMenu).CopyRight, m.counts.LeftOnly != 0),
+		when(keysOf(compareMenu).CopyLeft, m.counts.RightOnly != 0),
+		when(withHelp(keysOf(compareMenu).Export, "export CSV"), has),
+	}
+	return viewKeyMap{short: append(nav, actions...), full: [][]key.Binding{nav, scrollKeys(keysOf(compareMenu), has), actions}}
+}
+
+// Location returns "differences" while they are reviewed, "" on the form
+func (m CompareMenu) Location() string {
+	if m.reviewing {
+		return "differences"
+	}
+	return ""
+}
+
+// Restore goes back to the form, or to the differences of the last comparison
+func (m CompareMenu) Restore(place string) (tea.Model, tea.Cmd) {
+	if m.loading {
+		return m, nil
+	}
+	switch {
+	case place == "" && m.reviewing:
+		m.reviewing = false
+		m.cursor = compareFieldRun
+	case place != "" && !m.reviewing && m.input.Left.Bucket != "":
+		m.reviewing = true
+		m.cursor = 0
+	}
+	return m, nil
+}
+
+func (m CompareMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	var cmds []tea.Cmd
+	if m.loading {
+		m.spinner, cmd = m.spinner.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	switch msg := msg.(type) {
+	case s3.S3MenuMessage:
+		switch msg.Op {
+		case s3.S3OpDiff:
+			m.loading = false
+			if msg.APIMessage.Err != nil {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Err: msg.APIMessage.Err}))
+				break
+			}
+			m.entries = msg.Diff
+			m.counts = msg.DiffCounts
+			m.reviewing = true
+			m.cursor = 0
+			cmds = append(cmds, utils.SendMessage(internal.APIMessage{Status: msg.APIMessage.Status, Severity: internal.SeveritySuccess}))
+		}
+
+	case tea.KeyMsg:
+		if m.prompt.Focused() {
+			if key.Matches(msg, keysOf(compareMenu).Enter) {
+				value := strings.TrimSpace(m.prompt.Value())
+				m.prompt.SetValue("")
+				m.prompt.Blur()
+				cmds = append(cmds, m.submitPrompt(value))
+				break
+			}
+			if key.Matches(msg, keysOf(compareMenu).Backspace) && m.prompt.Value() == "" {
+				m.prompt.Blur()
+				break
+			}
+			m.prompt, cmd = m.prompt.Update(msg)
+			cmds = append(cmds, cmd)
+			break
+		}
+		if m.loading {
+			break
+		}
+
+		if m.reviewing {
+			if cursor, ok := scrollKey(keysOf(compareMenu), msg, m.cursor, len(m.entries), m.rows()); ok {
+				m.cursor = cursor
+				break
+			}
+			switch {
+			case key.Matches(msg, keysOf(compareMenu).Up):
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			case key.Matches(msg, keysOf(compareMenu).Down):
+				if m.cursor < len(m.entries)-1 {
+					m.cursor++
+				}
+			case key.Matches(msg, keysOf(compareMenu).Export):
+				if len(m.entries) != 0 {
+					m.promptFor = compareInputExport
+					m.prompt.Placeholder = "Export differences to CSV file..."
+					m.prompt.SetValue(fmt.Sprintf("diff-%s-%s.csv", m.input.Left.Bucket, time.Now().Format("20060102-150405")))
+					m.prompt.Focus()
+					cmds = append(cmds, textinput.Blink)
+				}
+			case key.Matches(msg, keysOf(compareMenu).CopyRight):
+				cmds = append(cmds, m.confirmCopy(true))
+			case key.Matches(msg, keysOf(compareMenu).CopyLeft):
+				cmds = append(cmds, m.confirmCopy(false))
+			case key.Matches(msg, keysOf(compareMenu).Backspace):
+				m.reviewing = false
+				m.cursor = compareFieldRun
+			}
+			break
+		}
+
+		switch {
+		case key.Matches(msg, keysOf(compareMenu).Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case key.Matches(msg, keysOf(compareMenu).Down):
+			if m.cursor < compareFieldRun {
+				m.cursor++
+			}
+		case key.Matches(msg, keysOf(compareMenu).Enter):
+			if m.cursor == compareFieldRun {
+				cmds = append(cmds, m.compare())
+			} else {
+				m.promptFor = compareInputField
+				m.editField = m.cursor
+				m.prompt.Placeholder = fmt.Sprintf("Enter %s...", strings.ToLower(compareFieldNames[m.cursor]))
+				m.prompt.SetValue(*m.field(m.cursor))
+				m.prompt.Focus()
+				cmds = append(cmds, textinput.Blink)
+			}
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// field returns the form value behind a field
+func (m *CompareMenu) field(field int) *string {
+	switch field {
+	case compareFieldLeftProfile:
+		return &m.left.profile
+	case compareFieldLeftBucket:
+		return &m.left.bucket
+	case compareFieldLeftPrefix:
+		return &m.left.prefix
+	case compareFieldRightProfile:
+		return &m.right.profile
+	case compareFieldRightBucket:
+		return &m.right.bucket
+	case compareFieldRightPrefix:
+		return &m.right.prefix
+	}
+	return new(string)
+}
+
+func (m *CompareMenu) submitPrompt(value string) tea.Cmd {
+	switch m.promptFor {
+	case compareInputField:
+		*m.field(m.editField) = value
+	case compareInputExport:
+		return m.export(value)
+	case compareInputConfirmCopy:
+		if value != "y" {
+			return nil
+		}
+		return m.queueCopies()
+	}
+	return nil
+}
+
+// side resolves the client of a form location
+func (m *CompareMenu) side(loc compareLocation) (s3.DiffSide, error) {
+	if loc.bucket == "" {
+		return s3.DiffSide{}, fmt.Errorf("both sides need a bucket")
+	}
+	profile := loc.profile
+	if profile == "" {
+		profile = m.profile
+	}
+	client, ok := m.clients[profile]
+	if !ok {
+		var err error
+		client, err = m.clientFor(profile)
+		if err != nil {
+			return s3.DiffSide{}, fmt.Errorf("profile %s: %w", profile, err)
+		}
+		m.clients[profile] = client
+	}
+	return s3.DiffSide{Client: client, Bucket: loc.bucket, Prefix: s3.CleanSyncPrefix(loc.prefix)}, nil
+}
+
+func (m *CompareMenu) compare() tea.Cmd {
+	left, err := m.side(m.left)
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: err})
+	}
+	right, err := m.side(m.right)
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: err})
+	}
+	m.input = s3.DiffInput{Left: left, Right: right}
+	m.loading = true
+	return tea.Batch(m.spinner.Tick,
+		m.client.Diff(context.Background(), m.input),
+		utils.SendMessage(internal.APIMessage{
+			Status: fmt.Sprintf("Comparing %s with %s...", left, right),
+		}))
+}
+
+// queueCopies hands the copy of every key missing on the target side over
+// to the transfer manager
+func (m *CompareMenu) queueCopies() tea.Cmd {
+	from, to, status := m.input.Right, m.input.Left, internal.DiffRightOnly
+	if m.copyToRight {
+		from, to, status = m.input.Left, m.input.Right, internal.DiffLeftOnly
+	}
+	queued := 0
+	for _, e := range m.entries {
+		if e.Status != status {
+			continue
+		}
+		size := e.Right.Size
+		if m.copyToRight {
+			size = e.Left.Size
+		}
+		TransferManager.Enqueue(transfer.Copy, path.Join(to.String(), e.Path), size, m.client.CopyJob(from, to, e.Path))
+		queued++
+	}
+	return utils.SendMessage(internal.APIMessage{
+		Status: fmt.Sprintf("Queued %d copies to %s, compare again once they finish", queued, to),
+	})
+}
+
+// confirmCopy asks before copying the keys missing on one side from the other
+func (m *CompareMenu) confirmCopy(toRight bool) tea.Cmd {
+	status, target := internal.DiffRightOnly, m.input.Left
+	if toRight {
+		status, target = internal.DiffLeftOnly, m.input.Right
+	}
+	n := len(internal.DiffPaths(m.entries, status))
+	if n == 0 {
+		return utils.SendMessage(internal.APIMessage{Status: fmt.Sprintf("Nothing is missing from %s", target), Severity: internal.SeverityWarn})
+	}
+	m.copyToRight = toRight
+	m.promptFor = compareInputConfirmCopy
+	m.prompt.Placeholder = fmt.Sprintf("Copy %d missing keys to %s? [y/n]", n, target)
+	m.prompt.Focus()
+	return textinput.Blink
+}
+
+func (m CompareMenu) export(path string) tea.Cmd {
+	if path == "" {
+		return nil
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: err})
+	}
+	err = internal.WriteDiffCSV(file, m.entries)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("exporting %s: %w", path, err)})
+	}
+	return utils.SendMessage(internal.APIMessage{
+		Status:   fmt.Sprintf("Exported %d differences to %s", len(m.entries), path),
+		Severity: internal.SeveritySuccess,
+	})
+}
+
+func (m CompareMenu) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.Header.Render("Compare") + "\n\n")
+
+	if m.loading {
+		b.WriteString(m.theme.Doc.Render(fmt.Sprintf("%s Working...\n", m.theme.Spinner.Render(m.spinner.View()))))
+	} else if m.reviewing {
+		m.viewDiff(&b)
+	} else {
+		for i, name := range compareFieldNames {
+			cursor := " "
+			display := name
+			if i != compareFieldRun {
+				value := *m.field(i)
+				if value == "" && (i == compareFieldLeftProfile || i == compareFieldRightProfile) {
+					value = m.profile
+				}
+				display = clip(fmt.Sprintf("%-14s %s", name+":", value), innerWidth(windowWidth()))
+			}
+			if i == m.cursor {
+				cursor = m.theme.Cursor.Render(">")
+				display = m.theme.Selected.Render(display)
+			} else {
+				display = m.theme.Choice.Render(display)
+			}
+			b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+		}
+	}
+
+	menu := m.theme.Border.Render(b.String())
+	if m.prompt.Focused() {
+		menu += "\n" + m.prompt.View()
+	}
+	return menu
+}
+
+// rows is the number of differences that fit below the title, the counts and
+// the column headings, leaving a line for the prompt
+func (m CompareMenu) rows() int {
+	return listRows(6)
+}
+
+// viewDiff renders the differences side by side, or one per line with a
+// marker for the side they are on when the terminal is narrow
+func (m CompareMenu) viewDiff(b *strings.Builder) {
+	start, end := scrollWindow(m.cursor, len(m.entries), m.rows())
+	b.WriteString(fmt.Sprintf("%d left only, %d right only, %d differ, %d identical",
+		m.counts.LeftOnly, m.counts.RightOnly, m.counts.Changed, m.counts.Same))
+	if m.counts.Unverified != 0 {
+		b.WriteString(fmt.Sprintf(" (%d unverified multipart)", m.counts.Unverified))
+	}
+	if position := scrollPosition(start, end, len(m.entries)); position != "" {
+		b.WriteString("  " + m.theme.Help.Render(position))
+	}
+	b.WriteString("\n\n")
+	if len(m.entries) == 0 {
+		b.WriteString(m.theme.Doc.Render("Both sides are identical.\n"))
+		return
+	}
+
+	inner := innerWidth(windowWidth())
+	if narrow() {
+		b.WriteString(clip(fmt.Sprintf(" < %s  > %s", m.input.Left, m.input.Right), inner+1) + "\n")
+		for i := start; i < end; i++ {
+			entry := m.entries[i]
+			var display string
+			switch entry.Status {
+			case internal.DiffLeftOnly:
+				display = fmt.Sprintf("< %s (%s)", entry.Path, formatBytes(entry.Left.Size))
+			case internal.DiffRightOnly:
+				display = fmt.Sprintf("> %s (%s)", entry.Path, formatBytes(entry.Right.Size))
+			default:
+				display = fmt.Sprintf("~ %s %s", entry.Path, entry.Reason)
+			}
+			b.WriteString(m.row(i, clip(display, inner)))
+		}
+		return
+	}
+
+	width := (inner - 3) / 2
+	b.WriteString(fmt.Sprintf(" %-*s | %s\n", width, clip(m.input.Left.String(), width), clip(m.input.Right.String(), width)))
+	for i := start; i < end; i++ {
+		entry := m.entries[i]
+		var left, right string
+		if entry.Status != internal.DiffRightOnly {
+			left = fmt.Sprintf("%s (%s)", entry.Path, formatBytes(entry.Left.Size))
+		}
+		if entry.Status != internal.DiffLeftOnly {
+			right = fmt.Sprintf("%s (%s)", entry.Path, formatBytes(entry.Right.Size))
+		}
+		if entry.Status == internal.DiffChanged {
+			right += " " + entry.Reason
+		}
+
+		b.WriteString(m.row(i, fmt.Sprintf("%-*s | %s", width, clip(left, width), clip(right, width))))
+	}
+}
+
+// row renders a difference line with the cursor when it is selected
+func (m CompareMenu) row(i int, display string) string {
+	cursor := " "
+	if i == m.cursor {
+		cursor = m.theme.Cursor.Render(">")
+		display = m.theme.Selected.Render(display)
+	} else {
+		display = m.theme.Choice.Render(display)
+	}
+	return fmt.Sprintf("%s%s\n", cursor, display)
+}
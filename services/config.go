@@ -1,5 +1,240 @@
 package services
 
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// configFile is the name of the config file inside the application directory
+const configFile = "config.yaml"
+
 type Config struct {
-	debugPath string
+	Log utils.LogConfig `yaml:"log"`
+	// Profile names the AWS profile to use at startup, empty for the default
+	// credential chain. It cannot be set together with Endpoint
+	Profile string `yaml:"profile"`
+	// Region overrides the region of the profile or endpoint used at startup
+	Region string `yaml:"region"`
+	// StartView names the view shown at startup, see StartViews. Empty is main
+	StartView string `yaml:"start_view"`
+	// Endpoint names the endpoint to use at startup, empty for AWS
+	Endpoint  string              `yaml:"endpoint"`
+	Endpoints []internal.Endpoint `yaml:"endpoints"`
+	// Opener is the command objects are opened with, e.g. "zathura". Empty uses
+	// xdg-open, or open on macOS
+	Opener string `yaml:"opener"`
+	// Compression rules pick the content encoding of uploads by extension
+	Compression []internal.CompressionRule `yaml:"compression"`
+	Transfers   TransferConfig             `yaml:"transfers"`
+	Keys        KeyConfig                  `yaml:"keys"`
+	// Theme names the theme to start with, see ThemeNames
+	Theme  string        `yaml:"theme"`
+	Themes []ThemeConfig `yaml:"themes"`
+}
+
+// KeyConfig rebinds keys. Each section maps action names to their keys, e.g.
+//
+//	keys:
+//	  global:
+//	    quit: [ctrl+c]
+//	  transfers:
+//	    cancel: [X]
+//
+// The global section applies to every view, the others to one view only. An
+// empty list unbinds the action
+type KeyConfig map[string]map[string][]string
+
+// defaultWorkers is how many transfers run at once when Workers is unset
+const defaultWorkers = 3
+
+// TransferConfig throttles and schedules uploads and downloads
+type TransferConfig struct {
+	// Workers is how many transfers, sync actions included, run at once. 0
+	// uses defaultWorkers
+	Workers int `yaml:"workers"`
+	// BandwidthLimit caps all transfers together, per second, e.g. "10MiB"
+	BandwidthLimit string `yaml:"bandwidth_limit"`
+	// Window is the daily time window large transfers wait for, e.g. "22:00-06:00"
+	Window string `yaml:"window"`
+	// DeferAbove is the size from which transfers wait for the window
+	DeferAbove string `yaml:"defer_above"`
+}
+
+// parse converts the fields into the global limit, the window, nil when
+// there is none, and the size from which transfers wait for it
+func (c TransferConfig) parse() (int64, *transfer.Window, int64, error) {
+	if c.Workers < 0 {
+		return 0, nil, 0, fmt.Errorf("transfers.workers: %d is negative", c.Workers)
+	}
+	limit, err := transfer.ParseBytes(c.BandwidthLimit)
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("transfers.bandwidth_limit: %w", err)
+	}
+	deferAbove, err := transfer.ParseBytes(c.DeferAbove)
+	if err != nil {
+		return 0, nil, 0, fmt.Errorf("transfers.defer_above: %w", err)
+	}
+	var window *transfer.Window
+	if c.Window != "" {
+		w, err := transfer.ParseWindow(c.Window)
+		if err != nil {
+			return 0, nil, 0, fmt.Errorf("transfers.window: %w", err)
+		}
+		window = &w
+	}
+	return limit, window, deferAbove, nil
+}
+
+// Validate checks every field
+func (c TransferConfig) Validate() error {
+	_, _, _, err := c.parse()
+	return err
+}
+
+// NewManager returns a transfer manager running the configured number of workers
+func (c TransferConfig) NewManager() *transfer.Manager {
+	if c.Workers > 0 {
+		return transfer.NewManager(c.Workers)
+	}
+	return transfer.NewManager(defaultWorkers)
+}
+
+// Apply sets the limits and schedule on the manager
+func (c TransferConfig) Apply(manager *transfer.Manager) error {
+	limit, window, deferAbove, err := c.parse()
+	if err != nil {
+		return err
+	}
+	manager.SetGlobalLimit(limit)
+	manager.SetSchedule(window, deferAbove)
+	return nil
+}
+
+// DefaultConfigPath returns the config file in the application directory
+func DefaultConfigPath() (string, error) {
+	dir, err := utils.AppDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configFile), nil
+}
+
+// LoadConfig reads the YAML config at path. A missing file gives an empty
+// config. The config is not validated, so that flags and the environment can
+// override it first, see Validate
+func LoadConfig(path string) (Config, error) {
+	var cfg Config
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("parsing %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the config as a whole, after flags and the environment
+// have been applied to it
+func (c Config) Validate() error {
+	seen := make(map[string]struct{})
+	for _, ep := range c.Endpoints {
+		if ep.Name == "" {
+			return fmt.Errorf("endpoint with url %q has no name", ep.URL)
+		}
+		if _, ok := seen[ep.Name]; ok {
+			return fmt.Errorf("endpoint %q is defined twice", ep.Name)
+		}
+		seen[ep.Name] = struct{}{}
+	}
+	if c.Endpoint != "" && c.FindEndpoint(c.Endpoint) == nil {
+		return fmt.Errorf("endpoint %q is not defined", c.Endpoint)
+	}
+	if c.Endpoint != "" && c.Profile != "" {
+		return fmt.Errorf("profile %q and endpoint %q are both set, pick one", c.Profile, c.Endpoint)
+	}
+	if _, ok := startView(c.StartView); !ok {
+		return fmt.Errorf("start view %q is not one of %s", c.StartView, strings.Join(StartViews(), ", "))
+	}
+	if err := internal.ValidateCompressionRules(c.Compression); err != nil {
+		return err
+	}
+	if _, _, err := c.Keys.build(); err != nil {
+		return err
+	}
+	if err := c.validateThemes(); err != nil {
+		return err
+	}
+	if err := c.Log.Validate(); err != nil {
+		return err
+	}
+	return c.Transfers.Validate()
+}
+
+func (c Config) validateThemes() error {
+	seen := make(map[string]struct{})
+	for _, t := range c.Themes {
+		if t.Name == "" {
+			return fmt.Errorf("theme without a name")
+		}
+		if _, ok := builtinColors(t.Name); ok {
+			return fmt.Errorf("theme %q replaces a built-in theme", t.Name)
+		}
+		if _, ok := seen[t.Name]; ok {
+			return fmt.Errorf("theme %q is defined twice", t.Name)
+		}
+		seen[t.Name] = struct{}{}
+		if _, err := c.LoadTheme(t.Name); err != nil {
+			return err
+		}
+	}
+	if c.Theme != "" {
+		if _, err := c.LoadTheme(c.Theme); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startViews are the views that can be shown without opening a bucket first
+var startViews = []SessionState{mainMenu, s3Menu, profileMenu, bookmarksMenu, transfersMenu, messagesMenu}
+
+// StartViews names the views StartView can pick
+func StartViews() []string {
+	names := make([]string, len(startViews))
+	for i, state := range startViews {
+		names[i] = state.String()
+	}
+	return names
+}
+
+// startView resolves a StartView name, empty being the main menu
+func startView(name string) (SessionState, bool) {
+	if name == "" {
+		return mainMenu, true
+	}
+	state, ok := keyViews[name]
+	return state, ok && slices.Contains(startViews, state)
+}
+
+// FindEndpoint returns the endpoint called name, or nil
+func (c Config) FindEndpoint(name string) *internal.Endpoint {
+	for i := range c.Endpoints {
+		if c.Endpoints[i].Name == name {
+			return &c.Endpoints[i]
+		}
+	}
+	return nil
 }
@@ -0,0 +1,56 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigValidate(t *testing.T) {
+	endpoints := []internal.Endpoint{{Name: "localstack", URL: "http://localhost:4566"}}
+	tests := []struct {
+		name string
+		cfg  Config
+		err  string
+	}{
+		{"empty", Config{}, ""},
+		{"profile", Config{Profile: "prod", Region: "eu-west-1"}, ""},
+		{"endpoint", Config{Endpoint: "localstack", Endpoints: endpoints}, ""},
+		{"undefined endpoint", Config{Endpoint: "minio"}, `endpoint "minio" is not defined`},
+		{"profile and endpoint", Config{Profile: "prod", Endpoint: "localstack", Endpoints: endpoints}, "pick one"},
+		{"start view", Config{StartView: "transfers"}, ""},
+		{"unknown start view", Config{StartView: "nope"}, `start view "nope"`},
+		{"start view needing a bucket", Config{StartView: "sync"}, `start view "sync"`},
+		{"log level", Config{Log: utils.LogConfig{Level: "verbose"}}, `log level "verbose"`},
+		{"transfers", Config{Transfers: TransferConfig{BandwidthLimit: "10MiB", Window: "22:00-06:00", DeferAbove: "1GiB"}}, ""},
+		{"transfer window", Config{Transfers: TransferConfig{Window: "late"}}, "transfers.window"},
+		{"bandwidth limit", Config{Transfers: TransferConfig{BandwidthLimit: "fast"}}, "transfers.bandwidth_limit"},
+		{"workers", Config{Transfers: TransferConfig{Workers: 8}}, ""},
+		{"negative workers", Config{Transfers: TransferConfig{Workers: -1}}, "transfers.workers"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestStartView(t *testing.T) {
+	for name, want := range map[string]SessionState{"": mainMenu, "main": mainMenu, "s3": s3Menu, "messages": messagesMenu} {
+		state, ok := startView(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, want, state, name)
+	}
+	for _, name := range []string{"compare", "multipart", "sync", "nope"} {
+		_, ok := startView(name)
+		assert.False(t, ok, name)
+	}
+	assert.Equal(t, []string{"main", "s3", "profiles", "bookmarks", "transfers", "messages"}, StartViews())
+}
@@ -1,10 +1,10 @@
 package services
 
 import (
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
-	"github.com/charmbracelet/lipgloss"
 )
 
 /* CONSTANTS */
@@ -14,144 +14,339 @@ var (
 	P *tea.Program
 	// WindowSize store the size of the terminal window
 	WindowSize tea.WindowSizeMsg
+	// TransferManager queues uploads and downloads for every view
+	TransferManager *transfer.Manager
 )
 
 func CreateSpinner() spinner.Model {
 	s := spinner.New()
 	s.Spinner = spinner.Dot
-	s.Style = lipgloss.NewStyle().Foreground(lipgloss.Color("205"))
 	return s
 }
 
-/* STYLING */
-
-// DocStyle styling for viewports
-var DocStyle = lipgloss.NewStyle().Margin(0, 2).Render
-
-// HelpStyle styling for help context menu
-var HelpStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("241")).Render
-
-// ErrStyle provides styling for error messages
-var ErrStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("#bd534b")).Render
-
-// AlertStyle provides styling for alert messages
-var AlertStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("62")).Render
-
-var HeaderStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#7D56F4")). // Purple text
-	Background(lipgloss.Color("#1a1a1a")). // Dark background
-	Bold(true).
-	PaddingLeft(1).
-	Render
-
-var ProfileStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("12")). // Red text
-	Align(lipgloss.Right)
-
-var ChoiceStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("7")). // Grey color for text
-	Render
-
-var CursorStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("12")). // Red for the cursor
-	Render
-
-var SelectedStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("10")). // Green text for selected
-	Italic(true)
-
-var FooterStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("8")). // Light grey footer
-	Render
-
-var BorderStyle = lipgloss.NewStyle().
-	Border(lipgloss.RoundedBorder()).
-	BorderForeground(lipgloss.Color("#5A5A5A")) // Grey border
-
-// StatusBarStyle provides styling for the network activity status bar
-var StatusBarStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#5A5A5A")). // Grey border
-	Align(lipgloss.Left).
-	Render
-
-// StatusBarSuccessStyle indicates successful network operations
-var StatusBarSuccessStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#0F4D0F")).
-	Align(lipgloss.Left).
-	Render
+type keymap struct {
+	Up           key.Binding
+	Down         key.Binding
+	Left         key.Binding
+	Right        key.Binding
+	Create       key.Binding
+	Enter        key.Binding
+	Delete       key.Binding
+	Back         key.Binding
+	Quit         key.Binding
+	Backspace    key.Binding
+	Sync         key.Binding
+	Transfers    key.Binding
+	Pause        key.Binding
+	Cancel       key.Binding
+	Retry        key.Binding
+	Clear        key.Binding
+	Mark         key.Binding
+	SelectAll    key.Binding
+	Invert       key.Binding
+	Copy         key.Binding
+	Tag          key.Binding
+	StorageClass key.Binding
+	SaveDir      key.Binding
+	Multipart    key.Binding
+	OlderThan    key.Binding
+	Retention    key.Binding
+	LegalHold    key.Binding
+	NewFolder    key.Binding
+	Open         key.Binding
+	Bookmark     key.Binding
+	GlobalLimit  key.Binding
+	JobLimit     key.Binding
+	StartNow     key.Binding
+	Compare      key.Binding
+	Export       key.Binding
+	CopyRight    key.Binding
+	CopyLeft     key.Binding
+	Expand       key.Binding
+	Forward      key.Binding
+	Command      key.Binding
+	Refresh      key.Binding
+	Filter       key.Binding
+	Help         key.Binding
+	PageUp       key.Binding
+	PageDown     key.Binding
+	Home         key.Binding
+	End          key.Binding
+	Messages     key.Binding
+	Dismiss      key.Binding
+}
 
-// StatusBarErrorStyle indicates failed network operations
-var StatusBarErrorStyle = lipgloss.NewStyle().
-	Foreground(lipgloss.Color("#bd534b")).
-	Align(lipgloss.Left).
-	Render
+// keyAction is a binding with the name config files use for it
+type keyAction struct {
+	name    string
+	binding *key.Binding
+}
 
-type keymap struct {
-	Up        key.Binding
-	Down      key.Binding
-	Left      key.Binding
-	Right     key.Binding
-	Create    key.Binding
-	Enter     key.Binding
-	Rename    key.Binding
-	Delete    key.Binding
-	Back      key.Binding
-	Quit      key.Binding
-	Backspace key.Binding
+// actions lists every binding of the keymap by name
+func (k *keymap) actions() []keyAction {
+	return []keyAction{
+		{"up", &k.Up},
+		{"down", &k.Down},
+		{"left", &k.Left},
+		{"right", &k.Right},
+		{"create", &k.Create},
+		{"enter", &k.Enter},
+		{"delete", &k.Delete},
+		{"back", &k.Back},
+		{"quit", &k.Quit},
+		{"backspace", &k.Backspace},
+		{"sync", &k.Sync},
+		{"transfers", &k.Transfers},
+		{"pause", &k.Pause},
+		{"cancel", &k.Cancel},
+		{"retry", &k.Retry},
+		{"clear", &k.Clear},
+		{"mark", &k.Mark},
+		{"select_all", &k.SelectAll},
+		{"invert", &k.Invert},
+		{"copy", &k.Copy},
+		{"tag", &k.Tag},
+		{"storage_class", &k.StorageClass},
+		{"save_dir", &k.SaveDir},
+		{"multipart", &k.Multipart},
+		{"older_than", &k.OlderThan},
+		{"retention", &k.Retention},
+		{"legal_hold", &k.LegalHold},
+		{"new_folder", &k.NewFolder},
+		{"open", &k.Open},
+		{"bookmark", &k.Bookmark},
+		{"global_limit", &k.GlobalLimit},
+		{"job_limit", &k.JobLimit},
+		{"start_now", &k.StartNow},
+		{"compare", &k.Compare},
+		{"export", &k.Export},
+		{"copy_right", &k.CopyRight},
+		{"copy_left", &k.CopyLeft},
+		{"expand", &k.Expand},
+		{"forward", &k.Forward},
+		{"command", &k.Command},
+		{"refresh", &k.Refresh},
+		{"filter", &k.Filter},
+		{"help", &k.Help},
+		{"page_up", &k.PageUp},
+		{"page_down", &k.PageDown},
+		{"home", &k.Home},
+		{"end", &k.End},
+		{"messages", &k.Messages},
+		{"dismiss", &k.Dismiss},
+	}
 }
 
 func (k keymap) List() []key.Binding {
-	return []key.Binding{
-		k.Up, k.Down, k.Left, k.Right,
-		k.Create, k.Enter, k.Rename, k.Delete,
-		k.Back, k.Quit, k.Backspace,
+	actions := k.actions()
+	bindings := make([]key.Binding, len(actions))
+	for i, action := range actions {
+		bindings[i] = *action.binding
 	}
+	return bindings
 }
 
-// Keymap reusable key mappings shared across models
-var Keymap = keymap{
-	Up: key.NewBinding(
-		key.WithKeys("up", "k"),
-		key.WithHelp("up/k", "up"),
-	),
-	Down: key.NewBinding(
-		key.WithKeys("down", "j"),
-		key.WithHelp("down/j", "down"),
-	),
-	Left: key.NewBinding(
-		key.WithKeys("left", "h"),
-		key.WithHelp("left/h", "left"),
-	),
-	Right: key.NewBinding(
-		key.WithKeys("right", "l"),
-		key.WithHelp("right/l", "right"),
-	),
-	Create: key.NewBinding(
-		key.WithKeys("c"),
-		key.WithHelp("c", "create"),
-	),
-	Enter: key.NewBinding(
-		key.WithKeys("enter"),
-		key.WithHelp("enter", "select"),
-	),
-	Rename: key.NewBinding(
-		key.WithKeys("r"),
-		key.WithHelp("r", "rename"),
-	),
-	Delete: key.NewBinding(
-		key.WithKeys("d"),
-		key.WithHelp("d", "delete"),
-	),
-	Back: key.NewBinding(
-		key.WithKeys("esc"),
-		key.WithHelp("esc", "back to main menu"),
-	),
-	Quit: key.NewBinding(
-		key.WithKeys("ctrl+c", "q"),
-		key.WithHelp("ctrl+c/q", "quit"),
-	),
-	Backspace: key.NewBinding(
-		key.WithKeys("backspace"),
-		key.WithHelp("backspace", "back to previous menu"),
-	),
+// Keymap reusable key mappings shared across models, with the global
+// overrides of the config applied
+var Keymap = defaultKeymap()
+
+func defaultKeymap() keymap {
+	return keymap{
+		Up: key.NewBinding(
+			key.WithKeys("up", "k"),
+			key.WithHelp("up/k", "up"),
+		),
+		Down: key.NewBinding(
+			key.WithKeys("down", "j"),
+			key.WithHelp("down/j", "down"),
+		),
+		Left: key.NewBinding(
+			key.WithKeys("left", "h"),
+			key.WithHelp("left/h", "left"),
+		),
+		Right: key.NewBinding(
+			key.WithKeys("right", "l"),
+			key.WithHelp("right/l", "right"),
+		),
+		Create: key.NewBinding(
+			key.WithKeys("c"),
+			key.WithHelp("c", "create"),
+		),
+		Enter: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "select"),
+		),
+		Delete: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "delete"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc", "alt+left"),
+			key.WithHelp("esc", "back"),
+		),
+		Quit: key.NewBinding(
+			key.WithKeys("ctrl+c", "q"),
+			key.WithHelp("ctrl+c/q", "quit"),
+		),
+		Backspace: key.NewBinding(
+			key.WithKeys("backspace"),
+			key.WithHelp("backspace", "back to previous menu"),
+		),
+		Sync: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "sync"),
+		),
+		Transfers: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "transfers"),
+		),
+		Pause: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "pause/resume"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "cancel"),
+		),
+		Retry: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "retry"),
+		),
+		Clear: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "clear finished"),
+		),
+		Mark: key.NewBinding(
+			key.WithKeys(" "),
+			key.WithHelp("space", "mark"),
+		),
+		SelectAll: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "mark all"),
+		),
+		Invert: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "invert marks"),
+		),
+		Copy: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy"),
+		),
+		Tag: key.NewBinding(
+			key.WithKeys("g"),
+			key.WithHelp("g", "tag"),
+		),
+		StorageClass: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "storage class"),
+		),
+		SaveDir: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "download directory"),
+		),
+		Multipart: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "multipart uploads"),
+		),
+		OlderThan: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "abort older than"),
+		),
+		Retention: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "set retention"),
+		),
+		LegalHold: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "toggle legal hold"),
+		),
+		NewFolder: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "new folder"),
+		),
+		Open: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "open"),
+		),
+		Bookmark: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "bookmark"),
+		),
+		GlobalLimit: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", "global bandwidth limit"),
+		),
+		JobLimit: key.NewBinding(
+			key.WithKeys("b"),
+			key.WithHelp("b", "transfer bandwidth limit"),
+		),
+		StartNow: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "start now"),
+		),
+		Compare: key.NewBinding(
+			key.WithKeys("="),
+			key.WithHelp("=", "compare"),
+		),
+		Export: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "export"),
+		),
+		CopyRight: key.NewBinding(
+			key.WithKeys(">"),
+			key.WithHelp(">", "copy missing to right"),
+		),
+		CopyLeft: key.NewBinding(
+			key.WithKeys("<"),
+			key.WithHelp("<", "copy missing to left"),
+		),
+		Expand: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "browse archive"),
+		),
+		Forward: key.NewBinding(
+			key.WithKeys("alt+right", "ctrl+f"),
+			key.WithHelp("ctrl+f", "forward"),
+		),
+		Command: key.NewBinding(
+			key.WithKeys(":"),
+			key.WithHelp(":", "command"),
+		),
+		Refresh: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "refresh"),
+		),
+		Filter: key.NewBinding(
+			key.WithKeys("/"),
+			key.WithHelp("/", "filter"),
+		),
+		Help: key.NewBinding(
+			key.WithKeys("?"),
+			key.WithHelp("?", "help"),
+		),
+		PageUp: key.NewBinding(
+			key.WithKeys("pgup"),
+			key.WithHelp("pgup", "page up"),
+		),
+		PageDown: key.NewBinding(
+			key.WithKeys("pgdown"),
+			key.WithHelp("pgdown", "page down"),
+		),
+		Home: key.NewBinding(
+			key.WithKeys("home"),
+			key.WithHelp("home", "first"),
+		),
+		End: key.NewBinding(
+			key.WithKeys("end"),
+			key.WithHelp("end", "last"),
+		),
+		Messages: key.NewBinding(
+			key.WithKeys("ctrl+l"),
+			key.WithHelp("ctrl+l", "messages"),
+		),
+		Dismiss: key.NewBinding(
+			key.WithKeys("ctrl+x"),
+			key.WithHelp("ctrl+x", "dismiss error"),
+		),
+	}
 }
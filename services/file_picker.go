@@ -0,0 +1,252 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// FilePickedMessage reports the path chosen in a FilePicker
+type FilePickedMessage struct {
+	Path  string
+	IsDir bool
+}
+
+// FilePickerCancelledMessage reports that the picker was closed without a choice
+type FilePickerCancelledMessage struct{}
+
+// useDirEntry is the pseudo entry that chooses the current directory in dir mode
+const useDirEntry = "[use this directory]"
+
+// FilePicker browses the local filesystem to choose a file or a directory
+type FilePicker struct {
+	dir      string
+	entries  []os.DirEntry
+	cursor   int
+	pickDirs bool // true chooses a directory, false chooses a file
+	width    int  // entries are cut to fit, 0 leaves them whole
+	input    textinput.Model
+	err      error
+	state    SessionState // view whose keys drive the picker
+	theme    *Theme
+}
+
+func InitFilePicker(dir string, pickDirs bool, state SessionState, theme *Theme) FilePicker {
+	input := textinput.New()
+	input.Prompt = "path: "
+	input.Placeholder = "type a path, [tab] to complete"
+	input.CharLimit = 1024
+	input.Width = 60
+	input.ShowSuggestions = true
+
+	abs, err := filepath.Abs(utils.ExpandHome(dir))
+	if err != nil {
+		abs = dir
+	}
+	p := FilePicker{pickDirs: pickDirs, input: input, state: state, theme: theme}
+	p.readDir(abs)
+	return p
+}
+
+func (p *FilePicker) readDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		p.err = err
+		return
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].IsDir() != entries[j].IsDir() {
+			return entries[i].IsDir()
+		}
+		return entries[i].Name() < entries[j].Name()
+	})
+	if p.pickDirs {
+		dirs := entries[:0]
+		for _, e := range entries {
+			if e.IsDir() {
+				dirs = append(dirs, e)
+			}
+		}
+		entries = dirs
+	}
+	p.dir = dir
+	p.entries = entries
+	p.cursor = 0
+	p.err = nil
+}
+
+// rows are the entries shown in the list: an optional use-this-directory
+// entry, the parent directory and then the directory contents
+func (p FilePicker) rows() []string {
+	var rows []string
+	if p.pickDirs {
+		rows = append(rows, useDirEntry)
+	}
+	rows = append(rows, "..")
+	for _, e := range p.entries {
+		name := e.Name()
+		if e.IsDir() {
+			name += string(filepath.Separator)
+		}
+		rows = append(rows, name)
+	}
+	return rows
+}
+
+func (p FilePicker) Init() tea.Cmd {
+	return nil
+}
+
+func (p FilePicker) KeyMap() help.KeyMap {
+	k := keysOf(p.state)
+	if p.input.Focused() {
+		keys := []key.Binding{
+			withHelp(k.Enter, "choose"),
+			key.NewBinding(key.WithKeys("tab"), key.WithHelp("tab", "complete")),
+			withHelp(k.Backspace, "cancel"),
+		}
+		return viewKeyMap{short: keys, full: [][]key.Binding{keys}}
+	}
+	nav := []key.Binding{k.Up, k.Down, withHelp(k.Left, "parent"), withHelp(k.Right, "open")}
+	actions := []key.Binding{
+		withHelp(k.Enter, "choose"),
+		withHelp(k.Filter, "type a path"),
+		withHelp(k.Backspace, "cancel"),
+	}
+	return viewKeyMap{short: append(nav, actions...), full: [][]key.Binding{nav, scrollKeys(k, true), actions}}
+}
+
+func (p FilePicker) Update(msg tea.Msg) (FilePicker, tea.Cmd) {
+	var cmd tea.Cmd
+
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return p, nil
+	}
+	k := keysOf(p.state)
+
+	if p.input.Focused() {
+		switch {
+		case key.Matches(keyMsg, k.Enter):
+			typed := utils.ExpandHome(strings.TrimSpace(p.input.Value()))
+			p.input.SetValue("")
+			p.input.Blur()
+			return p.choose(typed)
+		case key.Matches(keyMsg, k.Backspace) && p.input.Value() == "":
+			p.input.Blur()
+			return p, nil
+		}
+		p.input, cmd = p.input.Update(keyMsg)
+		p.input.SetSuggestions(utils.CompletePath(p.input.Value()))
+		return p, cmd
+	}
+
+	rows := p.rows()
+	if cursor, ok := scrollKey(k, keyMsg, p.cursor, len(rows), p.height()); ok {
+		p.cursor = cursor
+		return p, nil
+	}
+	switch {
+	case key.Matches(keyMsg, k.Up):
+		if p.cursor > 0 {
+			p.cursor--
+		}
+	case key.Matches(keyMsg, k.Down):
+		if p.cursor < len(rows)-1 {
+			p.cursor++
+		}
+	case key.Matches(keyMsg, k.Left):
+		p.readDir(filepath.Dir(p.dir))
+	case key.Matches(keyMsg, k.Right), key.Matches(keyMsg, k.Enter):
+		row := rows[p.cursor]
+		switch {
+		case row == useDirEntry:
+			return p, utils.SendMessage(FilePickedMessage{Path: p.dir, IsDir: true})
+		case row == "..":
+			p.readDir(filepath.Dir(p.dir))
+		default:
+			return p.choose(filepath.Join(p.dir, row))
+		}
+	case key.Matches(keyMsg, k.Backspace):
+		return p, utils.SendMessage(FilePickerCancelledMessage{})
+	case key.Matches(keyMsg, k.Filter):
+		p.input.SetValue(p.dir + string(filepath.Separator))
+		p.input.SetSuggestions(utils.CompletePath(p.input.Value()))
+		p.input.Focus()
+		return p, textinput.Blink
+	}
+	return p, nil
+}
+
+// choose opens directories and picks files, depending on the mode
+func (p FilePicker) choose(path string) (FilePicker, tea.Cmd) {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.dir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return p, utils.SendMessage(internal.APIMessage{Err: err})
+	}
+	if info.IsDir() {
+		p.readDir(filepath.Clean(path))
+		return p, nil
+	}
+	if p.pickDirs {
+		return p, utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("%s is not a directory", path)})
+	}
+	return p, utils.SendMessage(FilePickedMessage{Path: path})
+}
+
+// height is the number of entries shown below the title and the directory,
+// leaving room for the path input
+func (p FilePicker) height() int {
+	return listRows(5)
+}
+
+func (p FilePicker) View() string {
+	var b strings.Builder
+	title := "Choose a file to upload"
+	if p.pickDirs {
+		title = "Choose the download directory"
+	}
+	rows := p.rows()
+	start, end := scrollWindow(p.cursor, len(rows), p.height())
+	dir := p.dir
+	if p.width > 0 {
+		dir = clip(dir, p.width)
+	}
+	b.WriteString(p.theme.Header.Render(title) + " " + p.theme.Help.Render(scrollPosition(start, end, len(rows))) + "\n")
+	b.WriteString(p.theme.Choice.Render(dir) + "\n\n")
+
+	if p.err != nil {
+		b.WriteString(p.theme.Err.Render(p.err.Error()) + "\n")
+	}
+	for i := start; i < end; i++ {
+		cursor := " "
+		display := rows[i]
+		if p.width > 0 {
+			display = clip(display, p.width-1)
+		}
+		if i == p.cursor {
+			cursor = p.theme.Cursor.Render(">")
+			display = p.theme.Selected.Render(display)
+		} else {
+			display = p.theme.Choice.Render(display)
+		}
+		b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+	}
+
+	if p.input.Focused() {
+		b.WriteString("\n" + p.input.View() + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"slices"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// maxHistory bounds how many locations back navigation remembers
+const maxHistory = 100
+
+// location is one entry of the navigation history: a view and, for views
+// with their own hierarchy, the place inside it
+type location struct {
+	state SessionState
+	place string
+}
+
+// navigable views have places of their own that the history can return to
+type navigable interface {
+	// Location returns the current place, "" for the top of the view
+	Location() string
+	// Restore moves the view back to a place returned by Location
+	Restore(place string) (tea.Model, tea.Cmd)
+}
+
+// history is the back and forward stack of visited locations. It is a value
+// like the views holding it, so the stacks are clipped before appending and
+// never share their room with an earlier copy
+type history struct {
+	back    []location
+	forward []location
+	current location
+}
+
+// visit records loc as the current location. Moving somewhere new drops the forward stack
+func (h history) visit(loc location) history {
+	if loc == h.current {
+		return h
+	}
+	h.back = append(slices.Clip(h.back[max(len(h.back)-maxHistory+1, 0):]), h.current)
+	h.forward = nil
+	h.current = loc
+	return h
+}
+
+// goBack returns the previous location, making it the current one
+func (h history) goBack() (history, location, bool) {
+	if len(h.back) == 0 {
+		return h, h.current, false
+	}
+	loc := h.back[len(h.back)-1]
+	h.back = h.back[:len(h.back)-1]
+	h.forward = append(slices.Clip(h.forward), h.current)
+	h.current = loc
+	return h, loc, true
+}
+
+// goForward returns the location left by the last goBack, making it the current one
+func (h history) goForward() (history, location, bool) {
+	if len(h.forward) == 0 {
+		return h, h.current, false
+	}
+	loc := h.forward[len(h.forward)-1]
+	h.forward = h.forward[:len(h.forward)-1]
+	h.back = append(slices.Clip(h.back), h.current)
+	h.current = loc
+	return h, loc, true
+}
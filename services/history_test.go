@@ -0,0 +1,116 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistory(t *testing.T) {
+	main := location{state: mainMenu}
+	bucket := location{state: s3Menu, place: "bucket/"}
+	object := location{state: s3Menu, place: "bucket/a.txt"}
+	transfers := location{state: transfersMenu}
+
+	tests := []struct {
+		name    string
+		steps   func(h history) history
+		current location
+		back    []location
+		forward []location
+	}{
+		{"visit", func(h history) history {
+			return h.visit(bucket).visit(object)
+		}, object, []location{main, bucket}, nil},
+		{"same location", func(h history) history {
+			return h.visit(bucket).visit(bucket)
+		}, bucket, []location{main}, nil},
+		{"back", func(h history) history {
+			h, _, _ = h.visit(bucket).visit(object).goBack()
+			return h
+		}, bucket, []location{main}, []location{object}},
+		{"back and forward", func(h history) history {
+			h, _, _ = h.visit(bucket).visit(object).goBack()
+			h, _, _ = h.goForward()
+			return h
+		}, object, []location{main, bucket}, []location{}},
+		{"visit drops forward", func(h history) history {
+			h, _, _ = h.visit(bucket).visit(object).goBack()
+			return h.visit(transfers)
+		}, transfers, []location{main, bucket}, nil},
+		{"nothing back", func(h history) history {
+			h, _, _ = h.goBack()
+			return h
+		}, main, nil, nil},
+		{"nothing forward", func(h history) history {
+			h, _, _ = h.visit(bucket).goForward()
+			return h
+		}, bucket, []location{main}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := tt.steps(history{current: main})
+			assert.Equal(t, tt.current, h.current)
+			assert.Equal(t, len(tt.back), len(h.back))
+			assert.Equal(t, len(tt.forward), len(h.forward))
+			for i := range tt.back {
+				assert.Equal(t, tt.back[i], h.back[i])
+			}
+			for i := range tt.forward {
+				assert.Equal(t, tt.forward[i], h.forward[i])
+			}
+		})
+	}
+}
+
+func TestHistoryBound(t *testing.T) {
+	h := history{current: location{state: mainMenu}}
+	for i := range maxHistory + 10 {
+		h = h.visit(location{state: s3Menu, place: string(rune('a' + i%26))})
+		h = h.visit(location{state: transfersMenu})
+	}
+	assert.Len(t, h.back, maxHistory)
+}
+
+func TestHistoryCopiesDoNotShareStacks(t *testing.T) {
+	h := history{current: location{state: mainMenu}}.
+		visit(location{state: s3Menu}).
+		visit(location{state: transfersMenu})
+	back, _, _ := h.goBack()
+
+	// a different visit from the earlier copy must not change the later one
+	h.visit(location{state: messagesMenu})
+	_ = back.visit(location{state: bookmarksMenu})
+	assert.Equal(t, []location{{state: mainMenu}, {state: s3Menu}}, h.back)
+	assert.Equal(t, location{state: transfersMenu}, h.current)
+
+	again, loc, ok := h.goBack()
+	assert.True(t, ok)
+	assert.Equal(t, location{state: s3Menu}, loc)
+	assert.Equal(t, []location{{state: mainMenu}}, again.back)
+}
+
+func TestNavigableSubViews(t *testing.T) {
+	sync := InitSyncMenu(nil, "bucket", "logs/", nil, nil)
+	assert.Equal(t, "bucket/logs", sync.Location())
+	sync.plan = []internal.SyncAction{}
+	sync.reviewing = true
+	assert.Equal(t, "bucket/logs/plan", sync.Location())
+
+	model, _ := sync.Restore("bucket/logs")
+	sync = model.(SyncMenu)
+	assert.False(t, sync.reviewing)
+	model, _ = sync.Restore("bucket/logs/plan")
+	assert.True(t, model.(SyncMenu).reviewing)
+
+	compare := InitCompareMenu("default", nil, "bucket", "", nil, nil)
+	assert.Equal(t, "", compare.Location())
+	model, _ = compare.Restore("differences")
+	assert.False(t, model.(CompareMenu).reviewing, "nothing was compared yet")
+
+	multipart := InitMultipartMenu(nil, "bucket", nil)
+	assert.Equal(t, "bucket", multipart.Location())
+	model, _ = multipart.Restore("bucket")
+	assert.Equal(t, "bucket", model.(MultipartMenu).Location())
+}
@@ -0,0 +1,301 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"gopkg.in/yaml.v3"
+)
+
+// keymapFile is where ":keymap" writes the default bindings when no path is given
+const keymapFile = "keymap.yaml"
+
+// keysGlobal is the section of the keys config that applies to every view
+const keysGlobal = "global"
+
+// keyViews names the views in the keys config
+var keyViews = map[string]SessionState{
+	"main":      mainMenu,
+	"s3":        s3Menu,
+	"profiles":  profileMenu,
+	"sync":      syncMenu,
+	"transfers": transfersMenu,
+	"multipart": multipartMenu,
+	"bookmarks": bookmarksMenu,
+	"compare":   compareMenu,
+	"messages":  messagesMenu,
+}
+
+// globalActions are handled by the TUI on every view
+var globalActions = []string{"quit", "transfers", "messages", "dismiss", "back", "forward", "command", "help"}
+
+// scrollActions move the cursor of the lists in every view
+var scrollActions = []string{"page_up", "page_down", "home", "end"}
+
+// viewActions are the actions each view handles. Keys may be reused across
+// views, but not within one
+var viewActions = map[SessionState][]string{
+	mainMenu:      {"up", "down", "enter", "backspace", "filter"},
+	profileMenu:   {"up", "down", "enter", "filter"},
+	bookmarksMenu: {"up", "down", "enter", "delete"},
+	s3Menu: {
+		"up", "down", "left", "right", "enter", "backspace", "create", "refresh",
+		"bookmark", "sync", "compare", "multipart", "new_folder", "save_dir",
+		"mark", "select_all", "invert", "copy", "tag", "storage_class", "delete",
+		"retention", "legal_hold", "open", "expand", "filter",
+	},
+	syncMenu:      {"up", "down", "enter", "backspace"},
+	transfersMenu: {"up", "down", "enter", "backspace", "pause", "cancel", "retry", "clear", "global_limit", "job_limit", "start_now"},
+	multipartMenu: {"up", "down", "enter", "backspace", "mark", "delete", "older_than", "refresh"},
+	compareMenu:   {"up", "down", "enter", "backspace", "export", "copy_right", "copy_left"},
+	messagesMenu:  {"up", "down", "export"},
+}
+
+// viewKeymaps are the keymaps of the views with overrides of their own
+var viewKeymaps = map[SessionState]keymap{}
+
+// keysOf returns the bindings of a view
+func keysOf(state SessionState) keymap {
+	if k, ok := viewKeymaps[state]; ok {
+		return k
+	}
+	return Keymap
+}
+
+// Apply rebinds the keys and makes the keymaps current
+func (c KeyConfig) Apply() error {
+	global, views, err := c.build()
+	if err != nil {
+		return err
+	}
+	Keymap, viewKeymaps = global, views
+	return nil
+}
+
+// build applies the global overrides to the defaults, then each view's
+// overrides on top, and checks every view for keys bound twice
+func (c KeyConfig) build() (keymap, map[SessionState]keymap, error) {
+	global := defaultKeymap()
+	if err := global.rebind(c[keysGlobal]); err != nil {
+		return global, nil, fmt.Errorf("keys.%s: %w", keysGlobal, err)
+	}
+
+	names := make([]string, 0, len(keyViews))
+	for name := range keyViews {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	views := make(map[SessionState]keymap)
+	for name, overrides := range c {
+		if name == keysGlobal {
+			continue
+		}
+		state, ok := keyViews[name]
+		if !ok {
+			return global, nil, fmt.Errorf("keys.%s: unknown view, expected %s or one of %s",
+				name, keysGlobal, strings.Join(names, ", "))
+		}
+		k := global
+		if err := k.rebind(overrides); err != nil {
+			return global, nil, fmt.Errorf("keys.%s: %w", name, err)
+		}
+		views[state] = k
+	}
+
+	var conflicts []string
+	for _, name := range names {
+		k, ok := views[keyViews[name]]
+		if !ok {
+			k = global
+		}
+		actions := slices.Concat(viewActions[keyViews[name]], scrollActions, globalActions)
+		for _, conflict := range k.conflicts(actions) {
+			conflicts = append(conflicts, fmt.Sprintf("keys.%s: %s", name, conflict))
+		}
+	}
+	if len(conflicts) != 0 {
+		return global, nil, fmt.Errorf("conflicting key bindings: %s", strings.Join(conflicts, "; "))
+	}
+	return global, views, nil
+}
+
+// rebind replaces the keys of the named actions. An empty list unbinds the action
+func (k *keymap) rebind(overrides map[string][]string) error {
+	actions := k.actions()
+	names := make([]string, 0, len(overrides))
+	for name := range overrides {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		i := slices.IndexFunc(actions, func(a keyAction) bool { return a.name == name })
+		if i < 0 {
+			return fmt.Errorf("unknown action %q", name)
+		}
+		binding := actions[i].binding
+		keys := overrides[name]
+		if len(keys) == 0 {
+			*binding = key.NewBinding(key.WithDisabled())
+			continue
+		}
+		shown := make([]string, len(keys))
+		for j, k := range keys {
+			if k == "" {
+				return fmt.Errorf("%s: empty key", name)
+			}
+			shown[j] = k
+			if k == " " {
+				shown[j] = "space"
+			}
+		}
+		*binding = key.NewBinding(
+			key.WithKeys(keys...),
+			key.WithHelp(strings.Join(shown, "/"), binding.Help().Desc),
+		)
+	}
+	return nil
+}
+
+// conflicts describes the keys bound to more than one of the named actions
+func (k *keymap) conflicts(names []string) []string {
+	var conflicts []string
+	owners := make(map[string]string)
+	for _, action := range k.actions() {
+		if !slices.Contains(names, action.name) || !action.binding.Enabled() {
+			continue
+		}
+		for _, pressed := range action.binding.Keys() {
+			if owner, ok := owners[pressed]; ok {
+				conflicts = append(conflicts, fmt.Sprintf("%q is bound to both %s and %s", pressed, owner, action.name))
+				continue
+			}
+			owners[pressed] = action.name
+		}
+	}
+	return conflicts
+}
+
+// DefaultKeyConfig returns the built-in bindings in the format of the keys config
+func DefaultKeyConfig() KeyConfig {
+	k := defaultKeymap()
+	bindings := make(map[string][]string)
+	for _, action := range k.actions() {
+		bindings[action.name] = action.binding.Keys()
+	}
+	return KeyConfig{keysGlobal: bindings}
+}
+
+// DumpKeymap writes the built-in bindings to path as the keys section of a
+// config file, ready to be edited
+func DumpKeymap(path string) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	section := struct {
+		Keys KeyConfig `yaml:"keys"`
+	}{DefaultKeyConfig()}
+	if err := enc.Encode(section); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0o644)
+}
+
+// viewKeyMap is the help.KeyMap of a view in its current state. Bindings that
+// do nothing there are disabled so help leaves them out
+type viewKeyMap struct {
+	short []key.Binding
+	full  [][]key.Binding
+}
+
+func (k viewKeyMap) ShortHelp() []key.Binding {
+	return k.short
+}
+
+func (k viewKeyMap) FullHelp() [][]key.Binding {
+	return k.full
+}
+
+// keyed is implemented by views that describe their key bindings
+type keyed interface {
+	KeyMap() help.KeyMap
+}
+
+// handles reports whether a view whose keymap is keys acts on msg. A key bound
+// to one of the view's actions only reaches the view while keys has a binding
+// for it enabled, so the keys help leaves out do nothing. Other keys, such as
+// text typed into an input, always pass
+func handles(keys help.KeyMap, state SessionState, msg tea.KeyMsg) bool {
+	k := keysOf(state)
+	actions := slices.Concat(viewActions[state], scrollActions)
+	for _, action := range k.actions() {
+		if !slices.Contains(actions, action.name) || !key.Matches(msg, *action.binding) {
+			continue
+		}
+		for _, group := range keys.FullHelp() {
+			if key.Matches(msg, group...) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// when returns a copy of the binding that is disabled unless ok. Unbound
+// actions stay disabled
+func when(b key.Binding, ok bool) key.Binding {
+	b.SetEnabled(ok && b.Enabled())
+	return b
+}
+
+// withHelp returns a copy of the binding with the description it has in a view
+func withHelp(b key.Binding, desc string) key.Binding {
+	b.SetHelp(b.Help().Key, desc)
+	return b
+}
+
+// promptKeys are the bindings while a view's input prompt is focused
+func promptKeys(state SessionState) viewKeyMap {
+	k := keysOf(state)
+	keys := []key.Binding{withHelp(k.Enter, "submit"), withHelp(k.Backspace, "cancel")}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys}}
+}
+
+// globalKeys are the bindings the TUI handles on every view
+func (m TUI) globalKeys() []key.Binding {
+	view, ok := m.views[m.state].(typing)
+	free := !ok || !view.Typing()
+	k := keysOf(m.state)
+	return []key.Binding{
+		when(k.Help, free),
+		when(k.Command, free),
+		k.Transfers,
+		k.Messages,
+		when(k.Dismiss, m.statusBar.Sticky()),
+		when(k.Back, free),
+		when(k.Forward, free && len(m.history.forward) != 0),
+		k.Quit,
+	}
+}
+
+// keyMap combines the bindings of the current view with the global ones
+func (m TUI) keyMap() viewKeyMap {
+	keys := viewKeyMap{}
+	if view, ok := m.views[m.state].(keyed); ok {
+		keys.short = view.KeyMap().ShortHelp()
+		keys.full = view.KeyMap().FullHelp()
+	}
+	global := m.globalKeys()
+	keys.short = append(keys.short[:len(keys.short):len(keys.short)], global...)
+	keys.full = append(keys.full[:len(keys.full):len(keys.full)], global)
+	return keys
+}
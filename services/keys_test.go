@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/charmbracelet/bubbles/help"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyConfigBuild(t *testing.T) {
+	tests := []struct {
+		name  string
+		cfg   KeyConfig
+		err   string
+		check func(t *testing.T, global keymap, views map[SessionState]keymap)
+	}{
+		{"defaults", nil, "", func(t *testing.T, global keymap, views map[SessionState]keymap) {
+			assert.Equal(t, []string{"r"}, global.Refresh.Keys())
+			assert.Empty(t, views)
+		}},
+		{"global override", KeyConfig{"global": {"refresh": {"f5", "ctrl+r"}}}, "", func(t *testing.T, global keymap, views map[SessionState]keymap) {
+			assert.Equal(t, []string{"f5", "ctrl+r"}, global.Refresh.Keys())
+			assert.Equal(t, "f5/ctrl+r", global.Refresh.Help().Key)
+			assert.Empty(t, views)
+		}},
+		{"view override", KeyConfig{"s3": {"refresh": {"f5"}}}, "", func(t *testing.T, global keymap, views map[SessionState]keymap) {
+			assert.Equal(t, []string{"r"}, global.Refresh.Keys())
+			assert.Equal(t, []string{"f5"}, views[s3Menu].Refresh.Keys())
+			assert.NotContains(t, views, multipartMenu)
+		}},
+		{"view override on top of global", KeyConfig{"global": {"filter": {"f"}}, "main": {"enter": {"l"}}}, "", func(t *testing.T, global keymap, views map[SessionState]keymap) {
+			assert.Equal(t, []string{"f"}, views[mainMenu].Filter.Keys())
+			assert.Equal(t, []string{"l"}, views[mainMenu].Enter.Keys())
+			assert.Equal(t, []string{"enter"}, global.Enter.Keys())
+		}},
+		{"unbind", KeyConfig{"global": {"refresh": {}}}, "", func(t *testing.T, global keymap, views map[SessionState]keymap) {
+			assert.False(t, global.Refresh.Enabled())
+		}},
+		{"key reused across views", KeyConfig{"main": {"filter": {"r"}}}, "", nil},
+		{"unknown action", KeyConfig{"global": {"nope": {"n"}}}, `keys.global: unknown action "nope"`, nil},
+		{"unknown view action", KeyConfig{"s3": {"nope": {"n"}}}, `keys.s3: unknown action "nope"`, nil},
+		{"unknown view", KeyConfig{"nope": {"refresh": {"r"}}}, "keys.nope: unknown view", nil},
+		{"empty key", KeyConfig{"global": {"refresh": {""}}}, "refresh: empty key", nil},
+		{"global conflict", KeyConfig{"global": {"refresh": {"d"}}}, `keys.s3: "d" is bound to both`, nil},
+		{"view conflict", KeyConfig{"transfers": {"pause": {"x"}}}, `keys.transfers: "x" is bound to both`, nil},
+		{"conflict with a global action", KeyConfig{"bookmarks": {"delete": {"q"}}}, `keys.bookmarks: "q" is bound to both`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			global, views, err := tt.cfg.build()
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			if assert.NoError(t, err) && tt.check != nil {
+				tt.check(t, global, views)
+			}
+		})
+	}
+}
+
+// applyKeys makes cfg the current keymaps until the test ends
+func applyKeys(t *testing.T, cfg KeyConfig) {
+	t.Helper()
+	global, views := Keymap, viewKeymaps
+	t.Cleanup(func() { Keymap, viewKeymaps = global, views })
+	assert.NoError(t, cfg.Apply())
+}
+
+func TestComponentsUseViewKeys(t *testing.T) {
+	applyKeys(t, KeyConfig{"s3": {"enter": {"ctrl+o"}, "dismiss": {"X"}}})
+	theme, _ := Config{}.LoadTheme("")
+	ctrlO := tea.KeyMsg{Type: tea.KeyCtrlO}
+
+	s3Filter, _ := InitListFilter(s3Menu, &theme).Open()
+	s3Filter, _ = s3Filter.Update(ctrlO)
+	assert.False(t, s3Filter.Active(), "ctrl+o applies the filter in s3")
+
+	mainFilter, _ := InitListFilter(mainMenu, &theme).Open()
+	mainFilter, _ = mainFilter.Update(ctrlO)
+	assert.True(t, mainFilter.Active(), "ctrl+o is not bound in main")
+
+	WindowSize.Width = 80
+	model, _ := InitStatusBar(&theme).Update(internal.APIMessage{Err: errors.New("boom")})
+	bar := model.(StatusBar)
+	assert.Contains(t, bar.For(s3Menu).View(), "X dismiss")
+	assert.Contains(t, bar.For(mainMenu).View(), keysOf(mainMenu).Dismiss.Help().Key+" dismiss")
+}
+
+func TestHandles(t *testing.T) {
+	empty := BookmarksMenu{}.KeyMap()
+	full := BookmarksMenu{bookmarks: []internal.Bookmark{{Bucket: "b"}}}.KeyMap()
+	runes := func(s string) tea.KeyMsg { return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)} }
+	tests := []struct {
+		name string
+		keys help.KeyMap
+		msg  tea.KeyMsg
+		want bool
+	}{
+		{"enabled action", full, runes("d"), true},
+		{"disabled action", empty, runes("d"), false},
+		{"disabled scroll", empty, tea.KeyMsg{Type: tea.KeyEnd}, false},
+		{"action of another view", empty, runes("r"), true},
+		{"global action", empty, runes("q"), true},
+		{"unbound key", empty, runes("z"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, handles(tt.keys, bookmarksMenu, tt.msg))
+		})
+	}
+}
@@ -0,0 +1,106 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/reflow/truncate"
+)
+
+const (
+	// chromeHeight is the lines the TUI draws around every view: the header,
+	// the status bar and the help line
+	chromeHeight = 3
+	// frameSize is what a bordered box adds to each dimension
+	frameSize = 2
+	// narrowWidth is the terminal width below which side by side panes are
+	// stacked, or only the focused one is shown
+	narrowWidth = 80
+	// minRows keeps lists usable in very short terminals
+	minRows = 3
+)
+
+// windowWidth is the terminal width, or a classic 80 columns before the first resize
+func windowWidth() int {
+	if WindowSize.Width <= 0 {
+		return 80
+	}
+	return WindowSize.Width
+}
+
+func windowHeight() int {
+	if WindowSize.Height <= 0 {
+		return 24
+	}
+	return WindowSize.Height
+}
+
+func narrow() bool {
+	return windowWidth() < narrowWidth
+}
+
+// listRows is how many list rows fit in a bordered view that uses reserved
+// lines for its title, hints and prompt
+func listRows(reserved int) int {
+	return max(windowHeight()-chromeHeight-frameSize-reserved, minRows)
+}
+
+// innerWidth is the room for text in a bordered box spanning width cells,
+// less the column of the cursor
+func innerWidth(width int) int {
+	return max(width-frameSize-1, 10)
+}
+
+// scrollWindow returns the rows [start, end) to show so the cursor stays in
+// view, centred once the list scrolls
+func scrollWindow(cursor, total, rows int) (int, int) {
+	if total <= rows {
+		return 0, total
+	}
+	start := min(max(cursor-rows/2, 0), total-rows)
+	return start, start + rows
+}
+
+// scrollPosition describes the visible part of a list, empty when all of it fits
+func scrollPosition(start, end, total int) string {
+	if start == 0 && end == total {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d of %d", start+1, end, total)
+}
+
+// scrollKey moves a list cursor for the page, home and end keys. ok reports
+// whether msg was one of them
+func scrollKey(k keymap, msg tea.KeyMsg, cursor, total, rows int) (int, bool) {
+	switch {
+	case key.Matches(msg, k.PageUp):
+		cursor -= rows
+	case key.Matches(msg, k.PageDown):
+		cursor += rows
+	case key.Matches(msg, k.Home):
+		cursor = 0
+	case key.Matches(msg, k.End):
+		cursor = total - 1
+	default:
+		return cursor, false
+	}
+	return min(max(cursor, 0), max(total-1, 0)), true
+}
+
+// scrollKeys are the help entries of scrollKey
+func scrollKeys(k keymap, ok bool) []key.Binding {
+	return []key.Binding{when(k.PageUp, ok), when(k.PageDown, ok), when(k.Home, ok), when(k.End, ok)}
+}
+
+// clip cuts s to at most width cells, ending in an ellipsis when it was cut
+func clip(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	return truncate.StringWithTail(s, uint(width), "…")
+}
@@ -0,0 +1,93 @@
+package services
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScrollWindow(t *testing.T) {
+	tests := []struct {
+		name                string
+		cursor, total, rows int
+		start, end          int
+	}{
+		{"empty list", 0, 0, 5, 0, 0},
+		{"fits", 2, 4, 5, 0, 4},
+		{"exactly fits", 4, 5, 5, 0, 5},
+		{"cursor at the top", 0, 20, 5, 0, 5},
+		{"cursor near the top", 2, 20, 5, 0, 5},
+		{"cursor centred", 10, 20, 5, 8, 13},
+		{"cursor near the bottom", 18, 20, 5, 15, 20},
+		{"cursor at the bottom", 19, 20, 5, 15, 20},
+		{"single row", 7, 20, 1, 7, 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end := scrollWindow(tt.cursor, tt.total, tt.rows)
+			assert.Equal(t, tt.start, start)
+			assert.Equal(t, tt.end, end)
+			assert.LessOrEqual(t, end-start, tt.rows)
+			if tt.total != 0 {
+				assert.True(t, start <= tt.cursor && tt.cursor < end, "cursor %d outside [%d, %d)", tt.cursor, start, end)
+			}
+		})
+	}
+}
+
+func TestScrollPosition(t *testing.T) {
+	assert.Equal(t, "", scrollPosition(0, 4, 4))
+	assert.Equal(t, "", scrollPosition(0, 0, 0))
+	assert.Equal(t, "9-13 of 20", scrollPosition(8, 13, 20))
+}
+
+func TestScrollKey(t *testing.T) {
+	k := defaultKeymap()
+	pgup, pgdown := tea.KeyMsg{Type: tea.KeyPgUp}, tea.KeyMsg{Type: tea.KeyPgDown}
+	home, end := tea.KeyMsg{Type: tea.KeyHome}, tea.KeyMsg{Type: tea.KeyEnd}
+	tests := []struct {
+		name                string
+		msg                 tea.KeyMsg
+		cursor, total, rows int
+		want                int
+		ok                  bool
+	}{
+		{"page down", pgdown, 2, 20, 5, 7, true},
+		{"page down past the end", pgdown, 17, 20, 5, 19, true},
+		{"page up", pgup, 12, 20, 5, 7, true},
+		{"page up past the start", pgup, 3, 20, 5, 0, true},
+		{"home", home, 12, 20, 5, 0, true},
+		{"end", end, 2, 20, 5, 19, true},
+		{"end of an empty list", end, 0, 0, 5, 0, true},
+		{"page down in an empty list", pgdown, 0, 0, 5, 0, true},
+		{"other key", tea.KeyMsg{Type: tea.KeyDown}, 2, 20, 5, 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cursor, ok := scrollKey(k, tt.msg, tt.cursor, tt.total, tt.rows)
+			assert.Equal(t, tt.want, cursor)
+			assert.Equal(t, tt.ok, ok)
+		})
+	}
+}
+
+func TestClip(t *testing.T) {
+	tests := []struct {
+		s     string
+		width int
+		want  string
+	}{
+		{"bucket", 10, "bucket"},
+		{"bucket", 6, "bucket"},
+		{"bucket-name", 6, "bucke…"},
+		{"bucket", 1, "…"},
+		{"bucket", 0, ""},
+		{"bucket", -3, ""},
+		{"", 5, ""},
+		{"日本語のファイル", 7, "日本語…"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, clip(tt.s, tt.width), "clip(%q, %d)", tt.s, tt.width)
+	}
+}
@@ -0,0 +1,168 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"unicode/utf8"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// ListFilter narrows a list to the items fuzzy matching a pattern, as the
+// pattern is typed. Views keep their cursor on the index of the original item
+// and move it between the rows left visible, so actions still hit the entry
+// under the cursor
+type ListFilter struct {
+	input   textinput.Model
+	items   []string
+	matches []internal.FuzzyMatch
+	state   SessionState // view whose keys drive the filter
+	theme   *Theme
+}
+
+func InitListFilter(state SessionState, theme *Theme) ListFilter {
+	input := textinput.New()
+	input.Prompt = "/"
+	input.CharLimit = 100
+	input.Width = 40
+	return ListFilter{input: input, state: state, theme: theme}
+}
+
+// Active reports whether the pattern is being typed
+func (f ListFilter) Active() bool {
+	return f.input.Focused()
+}
+
+// Pattern is the applied filter, empty when every item is shown
+func (f ListFilter) Pattern() string {
+	return f.input.Value()
+}
+
+// Open starts typing the pattern, keeping the one already applied
+func (f ListFilter) Open() (ListFilter, tea.Cmd) {
+	f.input.CursorEnd()
+	f.input.Focus()
+	return f, textinput.Blink
+}
+
+// Clear drops the pattern and shows every item again
+func (f ListFilter) Clear() ListFilter {
+	f.input.SetValue("")
+	f.input.Blur()
+	return f.SetItems(f.items)
+}
+
+// SetItems replaces the items and filters them with the current pattern
+func (f ListFilter) SetItems(items []string) ListFilter {
+	f.items = items
+	f.matches = internal.FuzzyFilter(f.Pattern(), items)
+	return f
+}
+
+// Update handles a key while the pattern is being typed. Enter keeps the
+// filter, esc or backspace on an empty pattern clears it
+func (f ListFilter) Update(msg tea.KeyMsg) (ListFilter, tea.Cmd) {
+	k := keysOf(f.state)
+	switch {
+	case key.Matches(msg, k.Enter):
+		f.input.Blur()
+		return f, nil
+	case key.Matches(msg, k.Back), key.Matches(msg, k.Backspace) && f.Pattern() == "":
+		return f.Clear(), nil
+	}
+	var cmd tea.Cmd
+	f.input, cmd = f.input.Update(msg)
+	return f.SetItems(f.items), cmd
+}
+
+// Len is the number of visible rows
+func (f ListFilter) Len() int {
+	return len(f.matches)
+}
+
+// Index is the index of the item shown on row
+func (f ListFilter) Index(row int) int {
+	return f.matches[row].Index
+}
+
+// Row is the row showing the item at index, -1 when it is filtered out
+func (f ListFilter) Row(index int) int {
+	for row, match := range f.matches {
+		if match.Index == index {
+			return row
+		}
+	}
+	return -1
+}
+
+// Move returns the index of the item delta rows away from the one at index,
+// stopping at either end. A filtered out index moves to the first row, so
+// Move(index, 0) puts a cursor back on a visible item
+func (f ListFilter) Move(index, delta int) int {
+	if len(f.matches) == 0 {
+		return index
+	}
+	row := f.Row(index)
+	if row < 0 {
+		return f.Index(0)
+	}
+	return f.Index(min(max(row+delta, 0), len(f.matches)-1))
+}
+
+// Scroll is scrollKey over the visible rows, taking and returning item indexes
+func (f ListFilter) Scroll(k keymap, msg tea.KeyMsg, index, rows int) (int, bool) {
+	row, ok := scrollKey(k, msg, max(f.Row(index), 0), len(f.matches), rows)
+	if !ok || len(f.matches) == 0 {
+		return index, ok
+	}
+	return f.Index(row), true
+}
+
+// Highlight renders s, which starts with the text of the item on row, in
+// style with the characters the pattern matched picked out. Matches past the
+// part of the item s shows, as when clip cut it short, are left out
+func (f ListFilter) Highlight(row int, s string, style lipgloss.Style) string {
+	match := f.matches[row]
+	shown := commonPrefix(s, match.Str)
+	positions := match.Positions
+	if i := slices.IndexFunc(positions, func(p int) bool { return p >= shown }); i >= 0 {
+		positions = positions[:i]
+	}
+	return highlightMatches(s, positions, style, f.theme.Match)
+}
+
+// commonPrefix is the length in bytes of the whole runes a and b start with
+func commonPrefix(a, b string) int {
+	n := 0
+	for n < len(a) && n < len(b) {
+		ra, size := utf8.DecodeRuneInString(a[n:])
+		if rb, _ := utf8.DecodeRuneInString(b[n:]); ra != rb {
+			break
+		}
+		n += size
+	}
+	return n
+}
+
+// View is the pattern being typed, or a note of the applied filter. It is
+// empty when nothing is filtered
+func (f ListFilter) View() string {
+	if f.Active() {
+		return f.input.View()
+	}
+	if f.Pattern() == "" {
+		return ""
+	}
+	return f.theme.Help.Render(fmt.Sprintf("/%s  %d of %d", f.Pattern(), len(f.matches), len(f.items)))
+}
+
+func (f ListFilter) KeyMap() help.KeyMap {
+	k := keysOf(f.state)
+	keys := []key.Binding{withHelp(k.Enter, "apply filter"), withHelp(k.Back, "clear filter")}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys}}
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"testing"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/stretchr/testify/assert"
+)
+
+// typeFilter opens a filter over items and types pattern into it
+func typeFilter(t *testing.T, items []string, pattern string) ListFilter {
+	t.Helper()
+	theme, _ := Config{}.LoadTheme("")
+	f, _ := InitListFilter(mainMenu, &theme).SetItems(items).Open()
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(pattern)})
+	return f
+}
+
+func TestListFilterRows(t *testing.T) {
+	items := []string{"alpha", "beta", "gamma", "delta"}
+	f := typeFilter(t, items, "ta")
+	assert.True(t, f.Active())
+	assert.Equal(t, "ta", f.Pattern())
+	if !assert.Equal(t, 2, f.Len()) {
+		return
+	}
+
+	indexes := []int{f.Index(0), f.Index(1)}
+	assert.ElementsMatch(t, []int{1, 3}, indexes)
+	for row, index := range indexes {
+		assert.Equal(t, row, f.Row(index))
+	}
+	assert.Equal(t, -1, f.Row(0), "alpha is filtered out")
+
+	assert.Equal(t, indexes[0], f.Move(0, 0), "a filtered out cursor moves to the first row")
+	assert.Equal(t, indexes[1], f.Move(indexes[0], 1))
+	assert.Equal(t, indexes[1], f.Move(indexes[1], 1), "the cursor stops at the last row")
+	assert.Equal(t, indexes[0], f.Move(indexes[1], -5), "the cursor stops at the first row")
+
+	none := typeFilter(t, items, "zz")
+	assert.Equal(t, 0, none.Len())
+	assert.Equal(t, 2, none.Move(2, 1), "nothing visible keeps the cursor")
+}
+
+func TestListFilterKeys(t *testing.T) {
+	items := []string{"alpha", "beta", "gamma", "delta"}
+
+	f := typeFilter(t, items, "ta")
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyEnter})
+	assert.False(t, f.Active(), "enter applies the filter")
+	assert.Equal(t, "ta", f.Pattern())
+	assert.Equal(t, 2, f.Len())
+	assert.Contains(t, f.View(), "2 of 4")
+
+	f = f.SetItems(append(items, "theta"))
+	assert.Equal(t, 3, f.Len(), "new items are filtered with the applied pattern")
+
+	f, _ = f.Open()
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	assert.False(t, f.Active(), "esc clears the filter")
+	assert.Equal(t, "", f.Pattern())
+	assert.Equal(t, 5, f.Len())
+	assert.Equal(t, "", f.View())
+
+	f = typeFilter(t, items, "t")
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.True(t, f.Active(), "backspace deletes from the pattern")
+	assert.Equal(t, 4, f.Len())
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyBackspace})
+	assert.False(t, f.Active(), "backspace on an empty pattern clears the filter")
+}
+
+func TestListFilterHighlight(t *testing.T) {
+	// the match style brackets what it renders, so the picked out runes show
+	// without colours
+	theme := Theme{Match: lipgloss.NewStyle().Transform(func(s string) string { return "[" + s + "]" })}
+	plain := lipgloss.NewStyle()
+	f := InitListFilter(mainMenu, &theme).SetItems([]string{"bucket-zeta"})
+	f, _ = f.Open()
+	f, _ = f.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("bz")})
+	if !assert.Equal(t, 1, f.Len()) {
+		return
+	}
+
+	assert.Equal(t, "[b]ucket-[z]eta", f.Highlight(0, "bucket-zeta", plain))
+	assert.Equal(t, "[b]ucket-[z]eta 2024-01-01", f.Highlight(0, "bucket-zeta 2024-01-01", plain), "text after the item is left plain")
+	assert.Equal(t, "[b]ucket-…", f.Highlight(0, clip("bucket-zeta", 8), plain), "a match cut off by clip does not light up the ellipsis")
+}
@@ -2,11 +2,10 @@ package services
 
 import (
 	"fmt"
-	"regexp"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
-	"github.com/charmbracelet/bubbles/textinput"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
@@ -17,6 +16,7 @@ type GoBackMessage struct{}
 var services = []string{
 	"S3",                // Object storage
 	"Profiles",          // AWS credential profiles
+	"Bookmarks",         // Saved bucket locations
 	"EC2",               // Virtual servers
 	"Lambda",            // Serverless functions
 	"DynamoDB",          // NoSQL database
@@ -37,42 +37,39 @@ var services = []string{
 	"CodePipeline",      // CI/CD pipeline
 }
 
+// serviceStates maps the services with a view to it. The others stay on the main menu
+var serviceStates = map[string]SessionState{
+	"S3":        s3Menu,
+	"Profiles":  profileMenu,
+	"Bookmarks": bookmarksMenu,
+}
+
 type MenuItem struct {
 	name  string
 	state SessionState
 }
 
 type MainMenu struct {
-	choices         []MenuItem
-	filteredChoices []MenuItem
-	filterValue     string
-	cursor          int
-	selected        map[int]struct{}
-	input           textinput.Model
+	choices []MenuItem
+	cursor  int // index into choices
+	filter  ListFilter
+	theme   *Theme
 }
 
-func InitialMenu() MainMenu {
+func InitialMenu(theme *Theme) MainMenu {
 	menuItems := make([]MenuItem, len(services))
 	for i, service := range services {
 		menuItems[i] = MenuItem{
 			name:  service,
-			state: SessionState(i + 1),
+			state: serviceStates[service],
 		}
 	}
 
-	input := textinput.New()
-	input.Prompt = "$ "
-	input.Placeholder = ""
-	input.CharLimit = 250
-	input.Width = 50
-
 	return MainMenu{
-		choices:         menuItems,
-		filteredChoices: menuItems,
-		cursor:          0,
-		selected:        make(map[int]struct{}),
-		input:           input,
-		filterValue:     "",
+		theme:   theme,
+		choices: menuItems,
+		cursor:  0,
+		filter:  InitListFilter(mainMenu, theme).SetItems(services),
 	}
 }
 
@@ -80,87 +77,66 @@ func (m MainMenu) Init() tea.Cmd {
 	return nil
 }
 
+func (m MainMenu) Typing() bool {
+	return m.filter.Active()
+}
+
+func (m MainMenu) KeyMap() help.KeyMap {
+	if m.filter.Active() {
+		return m.filter.KeyMap()
+	}
+	has := m.filter.Len() != 0
+	keys := []key.Binding{
+		when(keysOf(mainMenu).Up, has), when(keysOf(mainMenu).Down, has),
+		when(withHelp(keysOf(mainMenu).Enter, "open"), has), keysOf(mainMenu).Filter,
+	}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys, scrollKeys(keysOf(mainMenu), has)}}
+}
+
 func (m MainMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
-	var cmds []tea.Cmd
 
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if m.input.Focused() {
-			// filter choices based on input
-			if key.Matches(msg, Keymap.Enter) {
-				m.filterValue = m.input.Value()
-
-				if m.filterValue != "" {
-					var filtered []MenuItem
-					re, err := regexp.Compile("(?i)" + m.filterValue) // Case-insensitive
-					if err == nil {
-						for _, c := range m.choices {
-							if re.MatchString(c.name) {
-								filtered = append(filtered, c)
-							}
-						}
-						m.filteredChoices = filtered
-					}
-					m.cursor = 0 // Reset cursor when filtering
-				} else {
-					m.filteredChoices = m.choices
-				}
-				m.input.SetValue("")
-				m.input.Blur()
-			}
-			if key.Matches(msg, Keymap.Backspace) {
-				m.filterValue = ""
-				m.filteredChoices = m.choices
-				m.input.SetValue("")
-				m.input.Blur()
-			}
-			// only log keypresses for the input field when it's focused
-			m.input, cmd = m.input.Update(msg)
-			cmds = append(cmds, cmd)
-		} else {
-			switch {
-			case key.Matches(msg, Keymap.Up):
-				if m.cursor > 0 {
-					m.cursor--
-				}
-
-			case key.Matches(msg, Keymap.Down):
-				if m.cursor < len(m.choices)-1 {
-					m.cursor++
-				}
+		if m.filter.Active() {
+			m.filter, cmd = m.filter.Update(msg)
+			m.cursor = m.filter.Move(m.cursor, 0)
+			return m, cmd
+		}
+		if cursor, ok := m.filter.Scroll(keysOf(mainMenu), msg, m.cursor, listRows(1)); ok {
+			m.cursor = cursor
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, keysOf(mainMenu).Up):
+			m.cursor = m.filter.Move(m.cursor, -1)
 
-			case key.Matches(msg, Keymap.Enter):
-				if len(m.filteredChoices) == 0 {
-					return m, nil // No choices to select from
+		case key.Matches(msg, keysOf(mainMenu).Down):
+			m.cursor = m.filter.Move(m.cursor, 1)
 
-				}
+		case key.Matches(msg, keysOf(mainMenu).Enter):
+			if m.filter.Len() == 0 {
+				return m, nil // No choices to select from
+			}
 
-				selected := m.filteredChoices[m.cursor]
-				return m, func() tea.Msg {
-					return SwitchMenuMessage{
-						selected.state}
-				}
+			selected := m.choices[m.cursor]
+			return m, func() tea.Msg {
+				return SwitchMenuMessage{
+					selected.state}
 			}
-		}
 
-		switch msg.String() {
-		case "/":
-			m.input.Focus()
-			return m, textinput.Blink
+		case key.Matches(msg, keysOf(mainMenu).Filter):
+			m.filter, cmd = m.filter.Open()
+			return m, cmd
 		}
-
 	}
 
-	return m, tea.Batch(cmds...)
+	return m, nil
 }
 
 func (m MainMenu) View() string {
 	// Get available width
-	width := WindowSize.Width
-	if width <= 0 {
-		width = 80 // Default if window size not available
-	}
+	width := windowWidth()
 
 	// Determine number of columns based on width
 	numColumns := 3
@@ -171,17 +147,19 @@ func (m MainMenu) View() string {
 	}
 
 	// Calculate items per column
-	totalItems := len(m.filteredChoices)
+	totalItems := m.filter.Len()
 	itemsPerCol := (totalItems + numColumns - 1) / numColumns
 
 	// Calculate column width
 	contentWidth := width - 4 // Account for borders
 	colWidth := contentWidth / numColumns
 
-	// Build rows for the layout
+	// Build rows for the layout, scrolled to the row of the cursor
 	var rows []string
+	cursorRow := max(m.filter.Row(m.cursor), 0)
+	start, end := scrollWindow(cursorRow%max(itemsPerCol, 1), itemsPerCol, listRows(1))
 
-	for rowIdx := 0; rowIdx < itemsPerCol; rowIdx++ {
+	for rowIdx := start; rowIdx < end; rowIdx++ {
 		var rowContent string
 
 		for colIdx := 0; colIdx < numColumns; colIdx++ {
@@ -189,16 +167,17 @@ func (m MainMenu) View() string {
 			itemIdx := rowIdx + colIdx*itemsPerCol
 
 			if itemIdx < totalItems {
-				choice := m.filteredChoices[itemIdx]
+				choice := m.choices[m.filter.Index(itemIdx)]
 
 				// Style based on selection
 				cursor := "  " // no cursor
 				display := ""
-				if m.cursor == itemIdx {
-					cursor = CursorStyle("> ")
-					display = SelectedStyle.Render(choice.name)
+				name := clip(choice.name, colWidth-3)
+				if cursorRow == itemIdx {
+					cursor = m.theme.Cursor.Render("> ")
+					display = m.filter.Highlight(itemIdx, name, m.theme.Selected)
 				} else {
-					display = ChoiceStyle(choice.name)
+					display = m.filter.Highlight(itemIdx, name, m.theme.Choice)
 				}
 
 				// Add to row with fixed width
@@ -219,12 +198,12 @@ func (m MainMenu) View() string {
 
 	menu := ""
 	if len(rows) == 0 {
-		rows = append(rows, DocStyle("No services available."))
+		rows = append(rows, m.theme.Doc.Render("No services available."))
 	}
 	menu = strings.Join(rows, "\n")
 
-	if m.input.Focused() {
-		menu += "\n" + m.input.View() // Add the input field at the bottom
+	if filter := m.filter.View(); filter != "" {
+		menu += "\n" + filter // Add the filter at the bottom
 	}
-	return BorderStyle.Render(menu)
+	return m.theme.Border.Render(menu)
 }
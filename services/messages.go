@@ -0,0 +1,165 @@
+package services
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/muesli/reflow/wordwrap"
+)
+
+// messageDetailLines is the height of the details of the selected message
+const messageDetailLines = 6
+
+// MessagesMenu is the log of status messages, newest last, with the full
+// text and error details of the one under the cursor
+type MessagesMenu struct {
+	log    *internal.MessageLog
+	cursor int
+	follow bool // the cursor stays on the newest message as more arrive
+	theme  *Theme
+}
+
+func InitMessagesMenu(log *internal.MessageLog, theme *Theme) MessagesMenu {
+	return MessagesMenu{log: log, follow: true, theme: theme}
+}
+
+func (m MessagesMenu) Init() tea.Cmd {
+	return nil
+}
+
+// selected is the index of the message under the cursor
+func (m MessagesMenu) selected() int {
+	if m.follow {
+		return m.log.Len() - 1
+	}
+	return min(m.cursor, m.log.Len()-1)
+}
+
+func (m MessagesMenu) KeyMap() help.KeyMap {
+	k := keysOf(messagesMenu)
+	has := m.log.Len() != 0
+	keys := []key.Binding{
+		when(k.Up, has), when(k.Down, has),
+		when(withHelp(k.Export, "export log"), has),
+	}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys, scrollKeys(k, has)}}
+}
+
+func (m MessagesMenu) Commands() []Command {
+	return []Command{{
+		Name:     "export",
+		Usage:    "[file]",
+		Help:     "write the message log to a file, for bug reports",
+		Complete: utils.CompletePath,
+		Run:      func(arg string) tea.Cmd { return exportMessages(m.log, utils.ExpandHome(arg)) },
+	}}
+}
+
+func (m MessagesMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		last := m.log.Len() - 1
+		cursor, ok := scrollKey(keysOf(messagesMenu), msg, m.selected(), m.log.Len(), m.rows())
+		switch {
+		case ok:
+		case key.Matches(msg, keysOf(messagesMenu).Up):
+			cursor = max(cursor-1, 0)
+		case key.Matches(msg, keysOf(messagesMenu).Down):
+			cursor = min(cursor+1, max(last, 0))
+		case key.Matches(msg, keysOf(messagesMenu).Export):
+			if last >= 0 {
+				return m, exportMessages(m.log, "")
+			}
+			return m, nil
+		default:
+			return m, nil
+		}
+		m.cursor = cursor
+		m.follow = cursor == last
+	}
+	return m, nil
+}
+
+// exportMessages writes the log to path, or to a new file in the app
+// directory when path is empty
+func exportMessages(log *internal.MessageLog, path string) tea.Cmd {
+	if path == "" {
+		dir, err := utils.AppDir()
+		if err != nil {
+			return utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		path = filepath.Join(dir, fmt.Sprintf("messages-%s.log", time.Now().Format("20060102-150405")))
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: err})
+	}
+	_, err = log.WriteTo(file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("exporting %s: %w", path, err)})
+	}
+	return utils.SendMessage(internal.APIMessage{
+		Status:   fmt.Sprintf("Exported %d messages to %s", log.Len(), path),
+		Severity: internal.SeveritySuccess,
+	})
+}
+
+// rows is the number of messages that fit between the title and the details
+func (m MessagesMenu) rows() int {
+	return listRows(4 + messageDetailLines)
+}
+
+func (m MessagesMenu) View() string {
+	var b strings.Builder
+	entries := m.log.Entries()
+	selected := m.selected()
+	start, end := scrollWindow(selected, len(entries), m.rows())
+	b.WriteString(m.theme.Header.Render("Messages") + " " + m.theme.Help.Render(scrollPosition(start, end, len(entries))) + "\n\n")
+
+	if len(entries) == 0 {
+		b.WriteString(m.theme.Doc.Render("No messages yet.\n"))
+		return m.theme.Border.Render(b.String())
+	}
+	width := innerWidth(windowWidth())
+	for i := start; i < end; i++ {
+		entry := entries[i]
+		cursor := " "
+		severity := m.theme.Severity(entry.Severity).Render(fmt.Sprintf("%-7s", strings.ToUpper(entry.Severity.String())))
+		text := clip(firstLine(entry.Text), width-len("15:04:05 ERROR   "))
+		if i == selected {
+			cursor = m.theme.Cursor.Render(">")
+			text = m.theme.Selected.Render(text)
+		} else {
+			text = m.theme.Choice.Render(text)
+		}
+		b.WriteString(fmt.Sprintf("%s%s %s %s\n", cursor, m.theme.Help.Render(entry.Time.Format("15:04:05")), severity, text))
+	}
+
+	// the whole message and its error details
+	entry := entries[selected]
+	details := wordwrap.String(entry.Text, width)
+	if entry.Detail != "" {
+		details += "\n" + entry.Detail
+	}
+	lines := strings.Split(details, "\n")
+	if len(lines) > messageDetailLines {
+		lines = append(lines[:messageDetailLines-1], "…")
+	}
+	b.WriteString("\n" + m.theme.Help.Render(entry.Time.Format(time.RFC3339)) + "\n")
+	for _, line := range lines {
+		b.WriteString(clip(line, width) + "\n")
+	}
+
+	return m.theme.Border.Render(b.String())
+}
@@ -0,0 +1,329 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/s3"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// OpenMultipartMessage asks the TUI to open the multipart upload view for a bucket
+type OpenMultipartMessage struct {
+	client s3.S3API
+	bucket string
+}
+
+// actions the multipart view input prompt can be answering
+const (
+	multipartInputConfirm = iota
+	multipartInputDays
+)
+
+type MultipartMenu struct {
+	s3Client    s3.S3API
+	bucket      string
+	uploads     []s3.MultipartUpload
+	cursor      int
+	marked      map[string]struct{} // upload IDs marked for abort
+	pending     []s3.MultipartUpload
+	input       textinput.Model
+	inputAction int
+	loading     bool
+	spinner     spinner.Model
+	theme       *Theme
+}
+
+func InitMultipartMenu(client s3.S3API, bucket string, theme *Theme) MultipartMenu {
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.CharLimit = 10
+	input.Width = 50
+
+	return MultipartMenu{
+		theme:    theme,
+		s3Client: client,
+		bucket:   bucket,
+		marked:   make(map[string]struct{}),
+		input:    input,
+		loading:  true,
+		spinner:  CreateSpinner(),
+	}
+}
+
+func (m MultipartMenu) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick,
+		m.s3Client.ListMultipartUploads(context.Background(), m.bucket),
+		utils.SendMessage(internal.APIMessage{
+			Status: fmt.Sprintf("Listing multipart uploads in %s...", m.bucket),
+		}))
+}
+
+func (m MultipartMenu) Typing() bool {
+	return m.input.Focused()
+}
+
+func (m MultipartMenu) KeyMap() help.KeyMap {
+	if m.input.Focused() {
+		return promptKeys(multipartMenu)
+	}
+	has := !m.loading && len(m.uploads) != 0
+	nav := []key.Binding{when(keysOf(multipartMenu).Up, has), when(keysOf(multipartMenu).Down, has), when(keysOf(multipartMenu).Mark, has)}
+	actions := []key.Binding{
+		when(withHelp(keysOf(multipartMenu).Delete, "abort"), has),
+		when(keysOf(multipartMenu).OlderThan, has),
+		when(keysOf(multipartMenu).Refresh, !m.loading),
+	}
+	return viewKeyMap{short: append(nav, actions...), full: [][]key.Binding{nav, scrollKeys(keysOf(multipartMenu), has), actions}}
+}
+
+// Location returns the bucket whose uploads are listed
+func (m MultipartMenu) Location() string {
+	return m.bucket
+}
+
+// Restore lists the uploads of the bucket at place when the view has moved
+// on to another bucket since
+func (m MultipartMenu) Restore(place string) (tea.Model, tea.Cmd) {
+	if place == m.bucket || place == "" {
+		return m, nil
+	}
+	m = InitMultipartMenu(m.s3Client, place, m.theme)
+	return m, m.Init()
+}
+
+func (m MultipartMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	var cmds []tea.Cmd
+	if m.loading {
+		m.spinner, cmd = m.spinner.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	switch msg := msg.(type) {
+	case s3.S3MenuMessage:
+		switch msg.Op {
+		case s3.S3OpListMultipartUploads:
+			m.loading = false
+			if msg.APIMessage.Err != nil {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Err: msg.APIMessage.Err}))
+				break
+			}
+			m.uploads = msg.Uploads
+			m.marked = make(map[string]struct{})
+			if m.cursor > len(m.uploads)-1 {
+				m.cursor = max(len(m.uploads)-1, 0)
+			}
+			cmds = append(cmds, utils.SendMessage(internal.APIMessage{Status: msg.APIMessage.Status, Severity: internal.SeveritySuccess}))
+		case s3.S3OpAbortMultipartUploads:
+			if msg.APIMessage.Err != nil {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Err: msg.APIMessage.Err}))
+			} else {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Status: msg.APIMessage.Status, Severity: internal.SeveritySuccess}))
+			}
+			cmds = append(cmds, m.s3Client.ListMultipartUploads(context.Background(), m.bucket))
+		}
+
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			if key.Matches(msg, keysOf(multipartMenu).Enter) {
+				value := strings.TrimSpace(m.input.Value())
+				m.input.SetValue("")
+				m.input.Blur()
+				cmds = append(cmds, m.submitInput(value))
+				break
+			}
+			if key.Matches(msg, keysOf(multipartMenu).Backspace) && m.input.Value() == "" {
+				m.input.Blur()
+				break
+			}
+			m.input, cmd = m.input.Update(msg)
+			cmds = append(cmds, cmd)
+			break
+		}
+		if m.loading {
+			break
+		}
+
+		if cursor, ok := scrollKey(keysOf(multipartMenu), msg, m.cursor, len(m.uploads), m.rows()); ok {
+			m.cursor = cursor
+			break
+		}
+		switch {
+		case key.Matches(msg, keysOf(multipartMenu).Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case key.Matches(msg, keysOf(multipartMenu).Down):
+			if m.cursor < len(m.uploads)-1 {
+				m.cursor++
+			}
+		case key.Matches(msg, keysOf(multipartMenu).Mark):
+			if len(m.uploads) != 0 {
+				id := m.uploads[m.cursor].UploadID
+				if _, ok := m.marked[id]; ok {
+					delete(m.marked, id)
+				} else {
+					m.marked[id] = struct{}{}
+				}
+				if m.cursor < len(m.uploads)-1 {
+					m.cursor++
+				}
+			}
+		case key.Matches(msg, keysOf(multipartMenu).Delete):
+			cmds = append(cmds, m.confirmAbort(m.targetUploads()))
+		case key.Matches(msg, keysOf(multipartMenu).OlderThan):
+			m.input.Placeholder = "Abort uploads older than how many days?"
+			m.inputAction = multipartInputDays
+			m.input.Focus()
+			cmds = append(cmds, textinput.Blink)
+		}
+		if key.Matches(msg, keysOf(multipartMenu).Refresh) {
+			m.loading = true
+			cmds = append(cmds, m.spinner.Tick,
+				m.s3Client.ListMultipartUploads(context.Background(), m.bucket))
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// targetUploads returns the marked uploads, or the one under the cursor when nothing is marked
+func (m MultipartMenu) targetUploads() []s3.MultipartUpload {
+	if len(m.uploads) == 0 {
+		return nil
+	}
+	if len(m.marked) == 0 {
+		return []s3.MultipartUpload{m.uploads[m.cursor]}
+	}
+	var targets []s3.MultipartUpload
+	for _, u := range m.uploads {
+		if _, ok := m.marked[u.UploadID]; ok {
+			targets = append(targets, u)
+		}
+	}
+	return targets
+}
+
+// confirmAbort asks for confirmation before aborting the uploads
+func (m *MultipartMenu) confirmAbort(uploads []s3.MultipartUpload) tea.Cmd {
+	if len(uploads) == 0 {
+		return nil
+	}
+	var size int64
+	for _, u := range uploads {
+		size += u.Size
+	}
+	m.pending = uploads
+	m.input.Placeholder = fmt.Sprintf("Abort %d uploads and discard %s of parts? [y/n]", len(uploads), formatBytes(size))
+	m.inputAction = multipartInputConfirm
+	m.input.Focus()
+	return textinput.Blink
+}
+
+func (m *MultipartMenu) submitInput(value string) tea.Cmd {
+	switch m.inputAction {
+	case multipartInputDays:
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("invalid number of days %q", value)})
+		}
+		old := s3.UploadsOlderThan(m.uploads, days, time.Now())
+		if len(old) == 0 {
+			return utils.SendMessage(internal.APIMessage{
+				Status:   fmt.Sprintf("No uploads older than %d days", days),
+				Severity: internal.SeverityWarn,
+			})
+		}
+		return m.confirmAbort(old)
+
+	case multipartInputConfirm:
+		pending := m.pending
+		m.pending = nil
+		if value != "y" {
+			return nil
+		}
+		m.loading = true
+		return tea.Batch(m.spinner.Tick,
+			m.s3Client.AbortMultipartUploads(context.Background(), m.bucket, pending),
+			utils.SendMessage(internal.APIMessage{
+				Status: fmt.Sprintf("S3: aborting %d multipart uploads...", len(pending)),
+			}))
+	}
+	return nil
+}
+
+// rows is the number of uploads that fit below the title and the summary,
+// leaving a line for the prompt
+func (m MultipartMenu) rows() int {
+	return listRows(5)
+}
+
+func (m MultipartMenu) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.Header.Render(fmt.Sprintf("Incomplete multipart uploads: %s", m.bucket)) + "\n\n")
+
+	if m.loading {
+		b.WriteString(m.theme.Doc.Render(fmt.Sprintf("%s Working...\n", m.theme.Spinner.Render(m.spinner.View()))))
+	} else if len(m.uploads) == 0 {
+		b.WriteString(m.theme.Doc.Render("No incomplete multipart uploads.\n"))
+	} else {
+		var total int64
+		unknown := 0
+		for _, u := range m.uploads {
+			total += u.Size
+			if u.PartsErr != nil {
+				unknown++
+			}
+		}
+		start, end := scrollWindow(m.cursor, len(m.uploads), m.rows())
+		b.WriteString(fmt.Sprintf("%d uploads holding %s", len(m.uploads), formatBytes(total)))
+		if unknown != 0 {
+			b.WriteString(fmt.Sprintf(" and %d of unknown size", unknown))
+		}
+		if len(m.marked) != 0 {
+			b.WriteString(m.theme.Alert.Render(fmt.Sprintf("  %d marked", len(m.marked))))
+		}
+		if position := scrollPosition(start, end, len(m.uploads)); position != "" {
+			b.WriteString("  " + m.theme.Help.Render(position))
+		}
+		b.WriteString("\n\n")
+
+		for i := start; i < end; i++ {
+			u := m.uploads[i]
+			cursor := " "
+			mark := "  "
+			if _, ok := m.marked[u.UploadID]; ok {
+				mark = "* "
+			}
+			parts, size := strconv.Itoa(u.Parts), formatBytes(u.Size)
+			if u.PartsErr != nil {
+				parts, size = "?", "?"
+			}
+			display := clip(fmt.Sprintf("%s%s  %5s parts  %10s  %s",
+				mark, u.Initiated.Local().Format("2006-01-02 15:04"), parts, size, u.Key), innerWidth(windowWidth()))
+			if i == m.cursor {
+				cursor = m.theme.Cursor.Render(">")
+				display = m.theme.Selected.Render(display)
+			} else {
+				display = m.theme.Choice.Render(display)
+			}
+			b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+		}
+	}
+
+	menu := m.theme.Border.Render(b.String())
+	if m.input.Focused() {
+		menu += "\n" + m.input.View()
+	}
+	return menu
+}
@@ -0,0 +1,251 @@
+package services
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+const (
+	commandHistoryFile = "command_history.json"
+	maxCommandHistory  = 100
+	maxSuggestions     = 6
+)
+
+// Palette is the ":" command line
+type Palette struct {
+	input       textinput.Model
+	history     []string
+	histPos     int // index into history while browsing it, len(history) otherwise
+	suggestions []internal.FuzzyMatch
+	selected    int
+	completing  bool         // tab is cycling through the suggestions
+	prefix      string       // text before the word being completed
+	state       SessionState // view the command line was opened on
+	logger      *slog.Logger
+	theme       *Theme
+}
+
+func InitPalette(logger *slog.Logger, theme *Theme) Palette {
+	input := textinput.New()
+	input.Prompt = ":"
+	input.CharLimit = 250
+	input.Width = 60
+
+	var history []string
+	if err := utils.LoadState(commandHistoryFile, &history); err != nil {
+		logger.Warn("could not load command history", "error", err)
+	}
+	return Palette{input: input, history: history, histPos: len(history), logger: logger, theme: theme}
+}
+
+// Active reports whether the command line has the keyboard
+func (p Palette) Active() bool {
+	return p.input.Focused()
+}
+
+// Open starts a command line over the view state, which resolves its keys
+func (p Palette) Open(state SessionState, commands []Command) (Palette, tea.Cmd) {
+	p.state = state
+	p.input.SetValue("")
+	p.histPos = len(p.history)
+	p.completing = false
+	p.suggest(commands)
+	p.input.Focus()
+	return p, textinput.Blink
+}
+
+func (p *Palette) close() {
+	p.input.SetValue("")
+	p.input.Blur()
+	p.suggestions = nil
+}
+
+// Update handles a key while the command line is open
+func (p Palette) Update(msg tea.KeyMsg, commands []Command) (Palette, tea.Cmd) {
+	switch msg.String() {
+	case "esc":
+		p.close()
+		return p, nil
+	case "enter":
+		line := strings.TrimSpace(p.input.Value())
+		p.close()
+		if line == "" {
+			return p, nil
+		}
+		p.remember(line)
+		return p, runCommand(commands, line)
+	case "tab", "shift+tab":
+		p.complete(msg.String() == "shift+tab", commands)
+		return p, nil
+	case "up":
+		if p.histPos > 0 {
+			p.histPos--
+			p.input.SetValue(p.history[p.histPos])
+			p.input.CursorEnd()
+		}
+		p.completing = false
+		p.suggest(commands)
+		return p, nil
+	case "down":
+		if p.histPos < len(p.history) {
+			p.histPos++
+			value := ""
+			if p.histPos < len(p.history) {
+				value = p.history[p.histPos]
+			}
+			p.input.SetValue(value)
+			p.input.CursorEnd()
+		}
+		p.completing = false
+		p.suggest(commands)
+		return p, nil
+	}
+	if key.Matches(msg, keysOf(p.state).Backspace) && p.input.Value() == "" {
+		p.close()
+		return p, nil
+	}
+
+	var cmd tea.Cmd
+	p.input, cmd = p.input.Update(msg)
+	p.completing = false
+	p.suggest(commands)
+	return p, cmd
+}
+
+// suggest fuzzy matches the word being typed: a command name, or an argument
+// of the command when a name and a space are typed
+func (p *Palette) suggest(commands []Command) {
+	p.selected = 0
+	value := p.input.Value()
+	name, arg, hasArg := strings.Cut(value, " ")
+	if !hasArg {
+		names := make([]string, len(commands))
+		for i, c := range commands {
+			names[i] = c.Name
+		}
+		p.prefix = ""
+		p.suggestions = internal.FuzzyFilter(name, names)
+		return
+	}
+
+	p.prefix = name + " "
+	p.suggestions = nil
+	if c, ok := findCommand(commands, name); ok && c.Complete != nil {
+		p.suggestions = internal.FuzzyFilter(strings.TrimLeft(arg, " "), c.Complete(arg))
+	}
+}
+
+// complete fills in the selected suggestion. Repeated tabs cycle through them
+func (p *Palette) complete(reverse bool, commands []Command) {
+	if len(p.suggestions) == 0 {
+		return
+	}
+	if p.completing {
+		step := 1
+		if reverse {
+			step = len(p.suggestions) - 1
+		}
+		p.selected = (p.selected + step) % len(p.suggestions)
+	}
+	p.completing = true
+
+	value := p.prefix + p.suggestions[p.selected].Str
+	if p.prefix == "" {
+		// a completed command name is followed by its arguments
+		if c, ok := findCommand(commands, value); ok && c.Usage != "" {
+			value += " "
+		}
+	}
+	p.input.SetValue(value)
+	p.input.CursorEnd()
+}
+
+// remember adds line to the persistent history, skipping repeats of the last line
+func (p *Palette) remember(line string) {
+	if len(p.history) == 0 || p.history[len(p.history)-1] != line {
+		p.history = append(p.history, line)
+		if len(p.history) > maxCommandHistory {
+			p.history = p.history[len(p.history)-maxCommandHistory:]
+		}
+		if err := utils.SaveState(commandHistoryFile, p.history); err != nil {
+			p.logger.Warn("could not save command history", "error", err)
+		}
+	}
+	p.histPos = len(p.history)
+}
+
+// findCommand resolves a command by its name, or by an unambiguous prefix of it
+func findCommand(commands []Command, name string) (Command, bool) {
+	var found []Command
+	for _, c := range commands {
+		if c.Name == name {
+			return c, true
+		}
+		if strings.HasPrefix(c.Name, name) {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 1 {
+		return found[0], true
+	}
+	return Command{}, false
+}
+
+func runCommand(commands []Command, line string) tea.Cmd {
+	name, arg, _ := strings.Cut(line, " ")
+	c, ok := findCommand(commands, name)
+	if !ok {
+		return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("unknown command :%s", name)})
+	}
+	return c.Run(strings.TrimSpace(arg))
+}
+
+// highlightMatches renders s with the runes at positions in match and the rest in style
+func highlightMatches(s string, positions []int, style, match lipgloss.Style) string {
+	var b strings.Builder
+	next := 0
+	for i, r := range s {
+		if next < len(positions) && positions[next] == i {
+			b.WriteString(match.Render(string(r)))
+			next++
+		} else {
+			b.WriteString(style.Render(string(r)))
+		}
+	}
+	return b.String()
+}
+
+func (p Palette) View(commands []Command) string {
+	var b strings.Builder
+	b.WriteString(p.input.View())
+
+	name, _, hasArg := strings.Cut(p.input.Value(), " ")
+	if c, ok := findCommand(commands, name); ok && hasArg {
+		b.WriteString("  " + p.theme.Help.Render(strings.TrimSpace(fmt.Sprintf(":%s %s  %s", c.Name, c.Usage, c.Help))))
+	}
+
+	var items []string
+	for i, s := range p.suggestions {
+		if i == maxSuggestions {
+			items = append(items, p.theme.Help.Render(fmt.Sprintf("+%d more", len(p.suggestions)-maxSuggestions)))
+			break
+		}
+		style := p.theme.Choice
+		if i == p.selected && p.completing {
+			style = p.theme.Selected
+		}
+		items = append(items, highlightMatches(s.Str, s.Positions, style, p.theme.Match))
+	}
+	if len(items) != 0 {
+		b.WriteString("\n" + strings.Join(items, "  "))
+	}
+	return b.String()
+}
@@ -2,14 +2,18 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
+	"github.com/Aearsears/fuzzy-guacamole/internal"
 	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -18,54 +22,137 @@ import (
 
 type ProfileMenu struct {
 	profiles        []string
-	cursor          int
+	endpoints       []internal.Endpoint // listed after the profiles
+	cursor          int                 // index into the profiles, then the endpoints
+	filter          ListFilter
 	selectedProfile string
 	config          aws.Config
+	endpoint        *internal.Endpoint
+	creds           aws.Credentials
+	credsErr        error // why the credentials of config could not be retrieved
+	logger          *slog.Logger
+	theme           *Theme
 }
 type ProfileMenuMessage struct {
-	profile string
-	config  aws.Config
+	profile  string
+	config   aws.Config
+	endpoint *internal.Endpoint // nil for AWS profiles
 }
 
-func InitProfileMenu() ProfileMenu {
-	profileSet := GetProfiles()
+func InitProfileMenu(endpoints []internal.Endpoint, logger *slog.Logger, theme *Theme) ProfileMenu {
+	profileSet := GetProfiles(logger)
 	profiles := make([]string, 0, len(profileSet))
 	for key := range profileSet {
 		profiles = append(profiles, key)
 	}
+	sort.Strings(profiles)
+
+	names := append([]string{}, profiles...)
+	for _, ep := range endpoints {
+		names = append(names, ep.Name)
+	}
 
 	//todo: handle error
 	cfg, _ := utils.LoadAWSConfig("")
 
 	return ProfileMenu{
+		logger:          logger,
+		theme:           theme,
 		profiles:        profiles,
+		endpoints:       endpoints,
 		cursor:          0,
+		filter:          InitListFilter(profileMenu, theme).SetItems(names),
 		selectedProfile: "",
-		config:          cfg,
+	}.useConfig(cfg, nil)
+}
+
+// useConfig makes cfg the config shown, retrieving its credentials once
+// rather than on every render
+func (m ProfileMenu) useConfig(cfg aws.Config, endpoint *internal.Endpoint) ProfileMenu {
+	m.config, m.endpoint = cfg, endpoint
+	m.creds, m.credsErr = aws.Credentials{}, nil
+	if cfg.Credentials == nil {
+		m.credsErr = errors.New("no credentials configured")
+	} else {
+		m.creds, m.credsErr = cfg.Credentials.Retrieve(context.TODO())
+	}
+	if m.credsErr != nil {
+		m.logger.Warn("unable to retrieve credentials", "profile", m.selectedProfile, "error", m.credsErr)
 	}
+	return m
 }
 
 func (m ProfileMenu) Init() tea.Cmd {
 	// todo: perform io loading in here
 	return nil
 }
+func (m ProfileMenu) Typing() bool {
+	return m.filter.Active()
+}
+
+func (m ProfileMenu) KeyMap() help.KeyMap {
+	if m.filter.Active() {
+		return m.filter.KeyMap()
+	}
+	has := m.filter.Len() != 0
+	keys := []key.Binding{
+		when(keysOf(profileMenu).Up, has), when(keysOf(profileMenu).Down, has),
+		when(withHelp(keysOf(profileMenu).Enter, "use profile"), has), keysOf(profileMenu).Filter,
+	}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys, scrollKeys(keysOf(profileMenu), has)}}
+}
+
 func (m ProfileMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 
 	case tea.KeyMsg:
+		if m.filter.Active() {
+			var cmd tea.Cmd
+			m.filter, cmd = m.filter.Update(msg)
+			m.cursor = m.filter.Move(m.cursor, 0)
+			return m, cmd
+		}
+		if cursor, ok := m.filter.Scroll(keysOf(profileMenu), msg, m.cursor, m.rows()); ok {
+			m.cursor = cursor
+			return m, nil
+		}
 
 		switch {
-		case key.Matches(msg, Keymap.Up):
-			if m.cursor > 0 {
-				m.cursor--
-			}
+		case key.Matches(msg, keysOf(profileMenu).Up):
+			m.cursor = m.filter.Move(m.cursor, -1)
+
+		case key.Matches(msg, keysOf(profileMenu).Down):
+			m.cursor = m.filter.Move(m.cursor, 1)
+
+		case key.Matches(msg, keysOf(profileMenu).Filter):
+			var cmd tea.Cmd
+			m.filter, cmd = m.filter.Open()
+			return m, cmd
+
+		case key.Matches(msg, keysOf(profileMenu).Enter) && m.filter.Len() == 0:
+			return m, nil
 
-		case key.Matches(msg, Keymap.Down):
-			if m.cursor < len(m.profiles)-1 {
-				m.cursor++
+		case key.Matches(msg, keysOf(profileMenu).Enter) && m.cursor >= len(m.profiles):
+			ep := m.endpoints[m.cursor-len(m.profiles)]
+			if m.selectedProfile == ep.Name {
+				return m, func() tea.Msg {
+					return ProfileMenuMessage{profile: m.selectedProfile}
+				}
+			}
+			cfg, err := utils.LoadEndpointConfig(ep)
+			if err != nil {
+				return m, utils.SendMessage(internal.APIMessage{Err: err})
+			}
+			m.selectedProfile = ep.Name
+			m = m.useConfig(cfg, &ep)
+			return m, func() tea.Msg {
+				return ProfileMenuMessage{
+					profile:  ep.Name,
+					config:   cfg,
+					endpoint: &ep}
 			}
 
-		case key.Matches(msg, Keymap.Enter):
+		case key.Matches(msg, keysOf(profileMenu).Enter):
 			if m.selectedProfile != m.profiles[m.cursor] {
 				m.selectedProfile = m.profiles[m.cursor]
 				//todo: handle error
@@ -76,7 +163,7 @@ func (m ProfileMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				// } else {
 				// 	fmt.Println("Error loading config:", err)
 				// }
-				m.config = cfg
+				m = m.useConfig(cfg, nil)
 				return m, func() tea.Msg {
 					return ProfileMenuMessage{
 						profile: m.selectedProfile,
@@ -93,59 +180,107 @@ func (m ProfileMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	return m, nil
 }
-func (m ProfileMenu) View() string {
 
-	var (
-		leftPanel = lipgloss.NewStyle().
-				Width(30).
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("#5A5A5A")).
-				MaxWidth(100)
+// detailsHeight is about the height of the details panel when it is stacked
+// below the list
+const detailsHeight = 10
 
-		rightPanel = lipgloss.NewStyle().
-				Border(lipgloss.RoundedBorder()).
-				BorderForeground(lipgloss.Color("#5A5A5A")).
-				MaxWidth(100)
+// rows is the number of profiles and endpoints that fit below the titles
+func (m ProfileMenu) rows() int {
+	reserved := 2
+	if len(m.endpoints) != 0 {
+		reserved += 3
+	}
+	if m.filter.View() != "" {
+		reserved++
+	}
+	if narrow() {
+		reserved += detailsHeight
+	}
+	return listRows(reserved)
+}
+
+// panelWidth is the width of the profile list, the details take the rest
+const panelWidth = 30
 
+func (m ProfileMenu) View() string {
+
+	var (
+		leftPanel  = m.theme.Border.Width(panelWidth)
+		rightPanel = m.theme.Border.Width(max(windowWidth()-panelWidth-2*frameSize, panelWidth))
 		flexLayout = lipgloss.NewStyle().
 				Align(lipgloss.Left)
 	)
+	if narrow() {
+		// stack the panels
+		leftPanel = leftPanel.Width(windowWidth() - frameSize)
+		rightPanel = leftPanel
+	}
+	total := m.filter.Len()
+	start, end := scrollWindow(max(m.filter.Row(m.cursor), 0), total, m.rows())
+
 	var left strings.Builder
-	left.WriteString(fmt.Sprintf("Available AWS Profiles  \n\n"))
+	left.WriteString(fmt.Sprintf("Available AWS Profiles  %s\n\n", m.theme.Help.Render(scrollPosition(start, end, total))))
 
 	// Iterate over our choices
-	for i, choice := range m.profiles {
+	filtered := m.filter.Pattern() != ""
+	for row := start; row < end; row++ {
+		i := m.filter.Index(row)
+		var choice string
+		if i < len(m.profiles) {
+			choice = m.profiles[i]
+		} else {
+			// matches are ranked, so endpoints are only grouped when unfiltered
+			if i == len(m.profiles) && !filtered {
+				left.WriteString("\nS3-compatible endpoints\n\n")
+			}
+			choice = m.endpoints[i-len(m.profiles)].Name
+		}
+		choice = clip(choice, leftPanel.GetWidth()-2)
 
 		cursor := " " // no cursor
 		display := ""
 
 		if m.cursor == i {
-			cursor = CursorStyle(">")              // cursor!
-			display = SelectedStyle.Render(choice) // Highlight the selected choice
+			cursor = m.theme.Cursor.Render(">")                         // cursor!
+			display = m.filter.Highlight(row, choice, m.theme.Selected) // Highlight the selected choice
 		} else {
-			display = ChoiceStyle(choice) // Regular style for unselected choices
+			display = m.filter.Highlight(row, choice, m.theme.Choice) // Regular style for unselected choices
+		}
+		if filtered && i >= len(m.profiles) {
+			display += m.theme.Help.Render(" endpoint")
 		}
 
 		// Render the row with styles
 		left.WriteString(fmt.Sprintf("%s %s\n", cursor, display))
 	}
+	if filter := m.filter.View(); filter != "" {
+		left.WriteString("\n" + filter)
+	}
 
 	var right strings.Builder
-	if m.config.Region != "" {
-		right.WriteString(HeaderStyle(fmt.Sprintf("Region: %s", m.config.Region)) + "\n\n")
+	if m.endpoint != nil {
+		right.WriteString(m.theme.Header.Render(fmt.Sprintf("Endpoint: %s", m.endpoint.URL)) + "\n\n")
+		right.WriteString(fmt.Sprintf("Path style: %t\n", m.endpoint.PathStyle))
+		right.WriteString(fmt.Sprintf("Signing region: %s\n", m.config.Region))
+		right.WriteString(fmt.Sprintf("Skip TLS verify: %t\n\n", m.endpoint.TLS.InsecureSkipVerify))
+	} else if m.config.Region != "" {
+		right.WriteString(m.theme.Header.Render(fmt.Sprintf("Region: %s", m.config.Region)) + "\n\n")
 	} else {
-		right.WriteString(DocStyle("No selected profile or no region in your configuration.\n"))
+		right.WriteString(m.theme.Doc.Render("No selected profile or no region in your configuration.\n"))
 	}
-	creds, err := m.config.Credentials.Retrieve(context.TODO())
-	if err != nil {
-		log.Fatal("Unable to retrieve credentials:", err)
+	if m.credsErr != nil {
+		right.WriteString(m.theme.Err.Render(fmt.Sprintf("Unable to retrieve credentials: %v", m.credsErr)) + "\n\n")
+	} else {
+		right.WriteString(m.theme.Header.Render(fmt.Sprintf("Provider used: %s", m.creds.Source)) + "\n\n")
 	}
 
-	right.WriteString(HeaderStyle(fmt.Sprintf("Provider used: %s", creds.Source)) + "\n\n")
-
 	leftBox := leftPanel.Render(left.String())
 	rightBox := rightPanel.Render(right.String())
 
+	if narrow() {
+		return flexLayout.Render(lipgloss.JoinVertical(lipgloss.Left, leftBox, rightBox))
+	}
 	return flexLayout.Render(
 		lipgloss.JoinHorizontal(lipgloss.Top, leftBox, rightBox),
 	)
@@ -179,19 +314,19 @@ func getProfilesFromFile(path string, isConfig bool) ([]string, error) {
 	return profiles, nil
 }
 
-func GetProfiles() map[string]struct{} {
+func GetProfiles(logger *slog.Logger) map[string]struct{} {
 	homeDir, _ := os.UserHomeDir()
 	configPath := filepath.Join(homeDir, ".aws", "config")
 	credsPath := filepath.Join(homeDir, ".aws", "credentials")
 
 	configProfiles, err := getProfilesFromFile(configPath, true)
 	if err != nil {
-		log.Printf("error reading config file: %v", err)
+		logger.Warn("could not read the AWS config file", "path", configPath, "error", err)
 	}
 
 	credentialProfiles, err := getProfilesFromFile(credsPath, false)
 	if err != nil {
-		log.Printf("error reading credentials file: %v", err)
+		logger.Warn("could not read the AWS credentials file", "path", credsPath, "error", err)
 	}
 
 	// Merge and deduplicate profiles
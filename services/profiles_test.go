@@ -0,0 +1,32 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileMenuCredentialsError(t *testing.T) {
+	var out bytes.Buffer
+	theme, _ := Config{}.LoadTheme("")
+	m := ProfileMenu{
+		logger: slog.New(slog.NewTextHandler(&out, nil)),
+		theme:  &theme,
+		filter: InitListFilter(profileMenu, &theme),
+	}
+	failing := aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+		return aws.Credentials{}, errors.New("token has expired")
+	})
+	m = m.useConfig(aws.Config{Region: "eu-west-1", Credentials: failing}, nil)
+
+	for range 3 {
+		assert.Contains(t, m.View(), "Unable to retrieve credentials")
+	}
+	assert.Equal(t, 1, strings.Count(out.String(), "unable to retrieve credentials"), "views do not log")
+}
@@ -3,17 +3,26 @@ package services
 import (
 	"context"
 	"fmt"
+	"log/slog"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
 	"strings"
+	"time"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 
 	"github.com/Aearsears/fuzzy-guacamole/internal"
 	"github.com/Aearsears/fuzzy-guacamole/internal/s3"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
 	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
 	s3aws "github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
@@ -21,76 +30,117 @@ import (
 
 // Styling constants for the S3 menu
 var (
-	borderStyle = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#5A5A5A")).
-		//to fix: border goes beyon right edge
-		MaxWidth(WindowSize.Width)
-
-	leftPanel = lipgloss.NewStyle().
-			Width(30).
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#5A5A5A")).
-			MaxWidth(100)
-
-	rightPanel = lipgloss.NewStyle().
-			Border(lipgloss.RoundedBorder()).
-			BorderForeground(lipgloss.Color("#5A5A5A")).
-			MaxWidth(100)
-
 	flexLayout = lipgloss.NewStyle().
-			Align(lipgloss.Left)
+		Align(lipgloss.Left)
+)
+
+const (
+	// bucketPaneWidth is the text width of the bucket pane beside the objects
+	bucketPaneWidth = 30
+	// bucketReserved and objectReserved are the lines each pane uses around
+	// its list: titles, lock and mark notes, the archive and the breadcrumbs
+	bucketReserved = 3
+	objectReserved = 7
+)
+
+// saveDirsFile stores the download directory chosen for each bucket
+const saveDirsFile = "download_dirs.json"
+
+// actions the S3 menu input prompt can be confirming
+const (
+	inputDelete = iota
+	inputBatchDelete
+	inputCopy
+	inputTag
+	inputStorageClass
+	inputRetention
+	inputNewFolder
+	inputContentType
 )
 
 type S3Menu struct {
 	buckets        []types.Bucket
-	selected       int
+	selected       int // index into buckets or the children of ptr, by pane
 	selectedBucket string
 	viewObjects    bool
 	objects        []string
+	sizes          map[string]int64 // sizes of the listed objects by key
 	objectMetadata s3.S3ObjectMetadata
+	bucketLock     s3.BucketObjectLock
 	paneFocus      int      // 0 = left for buckets, 1 = right for objects
 	breadcrumbs    []string // stack of directories
 	fileTree       *internal.Tree
 	ptr            *internal.TreeNode
+	pendingPrefix  string // folder to open once the bucket's objects are listed
+	pendingObject  string // object inside pendingPrefix to select once listed
+	opening        bool   // a goTo is waiting for its objects
 	savePath       string
+	saveDirs       map[string]string // download directory per bucket
+	picker         FilePicker
+	picking        bool
+	pendingUpload  string // file waiting for its content type to be confirmed
 	s3Client       s3.S3API
+	appConfig      Config
 	err            error
 	loading        bool
 	spinner        spinner.Model
 	input          textinput.Model
-	createFlag     bool
+	inputAction    int
+	marked         map[string]struct{}         // object keys marked for batch operations
+	archiveMembers map[string]s3.ArchiveMember // members of expanded archives by tree key
+	cursors        map[string]int              // cursor of each visited folder by bucket/path
+	bucketFilter   ListFilter
+	objectFilter   ListFilter // narrows the folder being browsed
+	logger         *slog.Logger
+	theme          *Theme
 }
 
-func InitS3Menu() S3Menu {
+func InitS3Menu(cfg aws.Config, endpoint *internal.Endpoint, appConfig Config, logger *slog.Logger, theme *Theme) S3Menu {
 	input := textinput.New()
 	input.Prompt = "$ "
 	input.Placeholder = "Enter a new bucket name..."
 	input.CharLimit = 250
 	input.Width = 50
 
-	//todo: handle error
-	cfg, _ := utils.LoadAWSConfig("")
+	client, _ := utils.ClientFactory("s3", cfg, endpoint, logger).(s3.S3API)
 
-	client, _ := utils.ClientFactory("s3", cfg, true).(s3.S3API)
+	saveDirs := make(map[string]string)
+	if err := utils.LoadState(saveDirsFile, &saveDirs); err != nil {
+		logger.Warn("could not load download directories", "error", err)
+	}
 	return S3Menu{
-		s3Client:    client,
-		buckets:     nil,
-		objects:     nil,
-		fileTree:    &internal.Tree{},
-		ptr:         &internal.TreeNode{},
-		selected:    0,
-		paneFocus:   0,
-		breadcrumbs: []string{},
-		err:         nil,
-		loading:     true,
-		spinner:     CreateSpinner(),
-		input:       input,
-		savePath:    ".",
-		createFlag:  true,
+		logger:         logger,
+		theme:          theme,
+		s3Client:       client,
+		appConfig:      appConfig,
+		buckets:        nil,
+		objects:        nil,
+		fileTree:       &internal.Tree{},
+		ptr:            &internal.TreeNode{},
+		selected:       0,
+		paneFocus:      0,
+		breadcrumbs:    []string{},
+		err:            nil,
+		loading:        true,
+		spinner:        CreateSpinner(),
+		input:          input,
+		savePath:       ".",
+		saveDirs:       saveDirs,
+		inputAction:    inputDelete,
+		marked:         make(map[string]struct{}),
+		archiveMembers: make(map[string]s3.ArchiveMember),
+		cursors:        make(map[string]int),
+		bucketFilter:   InitListFilter(s3Menu, theme),
+		objectFilter:   InitListFilter(s3Menu, theme),
 	}
 }
 
+// s3CommandMessage runs a ":" command contributed by the S3 view
+type s3CommandMessage struct {
+	name string
+	arg  string
+}
+
 func (m S3Menu) Init() tea.Cmd {
 	return tea.Batch(m.spinner.Tick,
 		m.s3Client.ListBuckets(context.Background(),
@@ -101,7 +151,6 @@ func (m S3Menu) Init() tea.Cmd {
 	)
 }
 
-// todo: allow user to change savePath
 func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
@@ -112,8 +161,41 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	switch msg := msg.(type) {
 
+	case FilePickedMessage:
+		m.picking = false
+		if msg.IsDir {
+			m.savePath = msg.Path
+			m.saveDirs[m.selectedBucket] = msg.Path
+			if err := utils.SaveState(saveDirsFile, m.saveDirs); err != nil {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Err: err}))
+			}
+			cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+				Status:   fmt.Sprintf("Downloads for %s will be saved to %s", m.selectedBucket, msg.Path),
+				Severity: internal.SeveritySuccess,
+			}))
+		} else {
+			contentType, err := s3.DetectContentType(msg.Path)
+			if err != nil {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Err: err}))
+				break
+			}
+			// let the detected type be accepted with enter or edited
+			m.pendingUpload = msg.Path
+			m.input.Prompt = fmt.Sprintf("Content-Type of %s: ", filepath.Base(msg.Path))
+			m.input.SetValue(contentType)
+			m.input.Focus()
+			m.inputAction = inputContentType
+			cmds = append(cmds, textinput.Blink)
+		}
+
+	case FilePickerCancelledMessage:
+		m.picking = false
+
+	case s3CommandMessage:
+		cmds = append(cmds, m.runCommand(msg))
+
 	case internal.AWSConfigMessage:
-		m.s3Client = m.createS3Client(msg.Config, true)
+		m.s3Client = m.createS3Client(msg.Config, msg.Endpoint)
 		//refresh the view
 		// refresh last recently used views to not cause too much latency
 		cmds = append(cmds,
@@ -123,6 +205,15 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case s3.S3MenuMessage:
 		if msg.APIMessage.Err != nil {
 			m.loading = false
+			if msg.Op == s3.S3OpListObjects {
+				m.pendingPrefix = ""
+				m.pendingObject = ""
+				m.opening = false
+			}
+			if msg.Op == s3.S3OpBatch && msg.Batch.Op == s3.BatchDelete {
+				// keys deleted before the failures are gone all the same
+				m.removeKeys(msg.Batch.Done)
+			}
 			cmds = append(cmds, func() tea.Msg {
 				return internal.APIMessage{
 					Err: msg.APIMessage.Err,
@@ -134,43 +225,120 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch msg.Op {
 			case s3.S3OpListBuckets:
 				m.buckets = msg.Buckets
+				m.filterBuckets()
 				cmds = append(cmds, func() tea.Msg {
 					return internal.APIMessage{
-						Status: fmt.Sprintf("S3: Listed %d buckets successfully", len(m.buckets)),
+						Status:   fmt.Sprintf("S3: Listed %d buckets successfully", len(m.buckets)),
+						Severity: internal.SeveritySuccess,
 					}
 				})
 			case s3.S3OpGetObjectMetadata:
 				m.objectMetadata = msg.Metadata
 				cmds = append(cmds, func() tea.Msg {
 					return internal.APIMessage{
-						Status: msg.APIMessage.Status,
+						Status:   msg.APIMessage.Status,
+						Severity: internal.SeveritySuccess,
 					}
 				})
 			case s3.S3OpCreateBucket:
 				cmds = append(cmds, func() tea.Msg {
 					return internal.APIMessage{
-						Status: fmt.Sprintf("S3: Created bucket %s", msg.Bucket),
+						Status:   fmt.Sprintf("S3: Created bucket %s", msg.Bucket),
+						Severity: internal.SeveritySuccess,
 					}
 				}, m.s3Client.ListBuckets(context.Background(),
 					&s3aws.ListBucketsInput{}))
 			case s3.S3OpListObjects:
 				m.viewObjects = true
 				m.objects = msg.Objects
+				m.sizes = msg.Sizes
 				m.fileTree = internal.CreateTree(m.objects)
 				m.ptr = m.fileTree.Root
 				m.paneFocus = 1
-				m.selected = 0
+				m.selected = min(m.cursors[m.cursorKey(m.ptr)], max(len(m.ptr.Children)-1, 0))
+				m.objectFilter = m.objectFilter.Clear()
+				m.filterObjects()
 				m.breadcrumbs = m.breadcrumbs[:0]
 				m.breadcrumbs = append(m.breadcrumbs, m.ptr.Value)
+				m.marked = make(map[string]struct{})
+				m.archiveMembers = make(map[string]s3.ArchiveMember)
 				cmds = append(cmds, func() tea.Msg {
 					return internal.APIMessage{
-						Status: fmt.Sprintf("S3: Fetched %d objects successfully for %s", len(m.objects), m.selectedBucket),
+						Status:   fmt.Sprintf("S3: Fetched %d objects successfully for %s", len(m.objects), m.selectedBucket),
+						Severity: internal.SeveritySuccess,
 					}
 				})
-			case s3.S3OpGetObject, s3.S3OpPutObject, s3.S3OpDeleteObject:
+				if m.pendingPrefix != "" {
+					node, found := m.fileTree.Find(m.pendingPrefix)
+					m.setPtr(node)
+					if !found {
+						cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+							Err: fmt.Errorf("%s/%s no longer exists", m.selectedBucket, m.pendingPrefix),
+						}))
+					}
+					m.pendingPrefix = ""
+				}
+				if node, ok := m.fileTree.Lookup(m.pendingObject); ok && m.pendingObject != "" {
+					m.setPtr(node)
+					if m.atObject() {
+						cmds = append(cmds, m.s3Client.GetObjectMetadata(context.Background(),
+							&s3aws.HeadObjectInput{
+								Bucket: aws.String(m.selectedBucket),
+								Key:    aws.String(m.objectKey()),
+							}))
+					}
+				}
+				m.pendingObject = ""
+				m.opening = false
+			case s3.S3OpGetObjectLock:
+				m.bucketLock = msg.ObjectLock
+			case s3.S3OpListArchive:
+				m.expandArchive(msg.Key, msg.Archive)
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+					Status:   msg.APIMessage.Status,
+					Severity: internal.SeveritySuccess,
+				}))
+			case s3.S3OpPutObjectRetention, s3.S3OpPutObjectLegalHold:
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+					Status:   msg.APIMessage.Status,
+					Severity: internal.SeveritySuccess,
+				}))
+				if m.atObject() {
+					cmds = append(cmds, m.s3Client.GetObjectMetadata(context.Background(),
+						&s3aws.HeadObjectInput{
+							Bucket: aws.String(m.selectedBucket),
+							Key:    aws.String(m.objectKey()),
+						}))
+				}
+			case s3.S3OpCreateFolder:
+				if msg.Bucket == m.selectedBucket {
+					m.fileTree.Root.AddNode(msg.Key, 0)
+					m.filterObjects()
+				}
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+					Status:   msg.APIMessage.Status,
+					Severity: internal.SeveritySuccess,
+				}))
+			case s3.S3OpDeleteObject:
+				m.removeKeys([]string{msg.Key})
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+					Status:   msg.APIMessage.Status,
+					Severity: internal.SeveritySuccess,
+				}))
+			case s3.S3OpBatch:
+				if msg.Batch.Op == s3.BatchDelete {
+					m.removeKeys(msg.Batch.Done)
+				}
+				m.marked = make(map[string]struct{})
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+					Status:   msg.APIMessage.Status,
+					Severity: internal.SeveritySuccess,
+				}))
+			case s3.S3OpGetObject, s3.S3OpPutObject:
 				cmds = append(cmds, func() tea.Msg {
 					return internal.APIMessage{
-						Status: msg.APIMessage.Status,
+						Status:   msg.APIMessage.Status,
+						Severity: internal.SeveritySuccess,
 					}
 				})
 
@@ -179,39 +347,35 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case tea.KeyMsg:
-		if m.input.Focused() {
-			if key.Matches(msg, Keymap.Enter) {
+		if m.picking {
+			m.picker, cmd = m.picker.Update(msg)
+			cmds = append(cmds, cmd)
+		} else if m.paneFocus == 0 && m.bucketFilter.Active() {
+			m.bucketFilter, cmd = m.bucketFilter.Update(msg)
+			m.selected = m.bucketFilter.Move(m.selected, 0)
+			cmds = append(cmds, cmd)
+		} else if m.paneFocus == 1 && m.objectFilter.Active() {
+			m.objectFilter, cmd = m.objectFilter.Update(msg)
+			m.selected = m.objectFilter.Move(m.selected, 0)
+			cmds = append(cmds, cmd)
+		} else if m.input.Focused() {
+			if key.Matches(msg, keysOf(s3Menu).Enter) {
 				if m.paneFocus == 0 {
 					cmds = append(cmds,
 						m.s3Client.CreateBucket(context.Background(),
 							&s3aws.CreateBucketInput{Bucket: aws.String(m.input.Value())}))
 				} else {
-					if m.createFlag {
-						cmds = append(cmds,
-							m.s3Client.PutObject(
-								context.Background(),
-								&s3aws.PutObjectInput{
-									Bucket: aws.String(m.selectedBucket),
-									Key:    aws.String(strings.Join(append(m.breadcrumbs[1:], m.input.Value()), "/"))},
-								m.input.Value()))
-					} else {
-						if m.input.Value() == "y" {
-							cmds = append(cmds,
-								m.s3Client.DeleteObject(context.Background(),
-									&s3aws.DeleteObjectInput{
-										Bucket: aws.String(m.selectedBucket),
-										Key:    aws.String(strings.Join(m.breadcrumbs[1:], "/")),
-									}))
-						}
-						m.createFlag = true
-					}
+					cmds = append(cmds, m.submitInput(m.input.Value()))
 				}
 				m.input.SetValue("")
+				m.input.Prompt = "$ "
 				m.input.Blur()
+				m.pendingUpload = ""
 			}
-			if key.Matches(msg, Keymap.Backspace) {
-				m.input.SetValue("")
+			if key.Matches(msg, keysOf(s3Menu).Backspace) && m.input.Value() == "" {
+				m.input.Prompt = "$ "
 				m.input.Blur()
+				m.pendingUpload = ""
 			}
 			// only log keypresses for the input field when it's focused
 			m.input, cmd = m.input.Update(msg)
@@ -219,18 +383,18 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		} else {
 			//bucket pane
 			if m.paneFocus == 0 {
+				if cursor, ok := m.bucketFilter.Scroll(keysOf(s3Menu), msg, m.selected, listRows(bucketReserved)); ok {
+					m.selected = cursor
+					return m, tea.Batch(cmds...)
+				}
 				switch {
-				case key.Matches(msg, Keymap.Up):
-					if m.selected > 0 {
-						m.selected--
-					}
+				case key.Matches(msg, keysOf(s3Menu).Up):
+					m.selected = m.bucketFilter.Move(m.selected, -1)
 
-				case key.Matches(msg, Keymap.Down):
-					if m.selected < len(m.buckets)-1 {
-						m.selected++
-					}
+				case key.Matches(msg, keysOf(s3Menu).Down):
+					m.selected = m.bucketFilter.Move(m.selected, 1)
 				//todo: handle the case where the file tree is already visible
-				case key.Matches(msg, Keymap.Left):
+				case key.Matches(msg, keysOf(s3Menu).Left):
 					if m.viewObjects {
 						if m.selected > len(m.buckets)-1 {
 							m.selected = len(m.buckets) - 1
@@ -238,73 +402,104 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 					}
 
-				case key.Matches(msg, Keymap.Right):
+				case key.Matches(msg, keysOf(s3Menu).Right):
 					if m.viewObjects {
 						m.paneFocus = 1
 						//need to move select index to the last valid object in the array
 						if m.selected > len(m.ptr.Children)-1 {
 							m.selected = len(m.ptr.Children) - 1
 						}
+						m.selected = m.objectFilter.Move(m.selected, 0)
 					}
 
-				case key.Matches(msg, Keymap.Enter):
-					if len(m.buckets) != 0 {
-						m.selectedBucket = *m.buckets[m.selected].Name
-						ctx := context.Background()
-						cmds = append(cmds,
-							m.s3Client.ListObjects(ctx,
-								&s3aws.ListObjectsV2Input{Bucket: aws.String(m.selectedBucket),
-									MaxKeys: aws.Int32(10)}))
+				case key.Matches(msg, keysOf(s3Menu).Filter):
+					m.bucketFilter, cmd = m.bucketFilter.Open()
+					cmds = append(cmds, cmd)
+
+				case key.Matches(msg, keysOf(s3Menu).Enter):
+					if m.bucketFilter.Len() != 0 {
+						m, cmd = m.goTo(*m.buckets[m.selected].Name, "")
+						cmds = append(cmds, cmd)
 					}
 
-				case key.Matches(msg, Keymap.Create):
+				case key.Matches(msg, keysOf(s3Menu).Bookmark):
+					if m.bucketFilter.Len() != 0 {
+						cmds = append(cmds, utils.SendMessage(AddBookmarkMessage{
+							bucket: *m.buckets[m.selected].Name,
+						}))
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Create):
 					m.input.Placeholder = "Enter a new bucket name..."
 					m.input.Focus()
 					cmds = append(cmds, textinput.Blink)
 
-				case key.Matches(msg, Keymap.Backspace):
+				case key.Matches(msg, keysOf(s3Menu).Backspace):
 					m.viewObjects = false
 					m.paneFocus = 0
-				}
-				switch msg.String() {
-				case "r":
+
+				case key.Matches(msg, keysOf(s3Menu).Sync):
+					if m.bucketFilter.Len() != 0 {
+						cmds = append(cmds, utils.SendMessage(OpenSyncMessage{
+							client: m.s3Client,
+							bucket: *m.buckets[m.selected].Name,
+						}))
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Compare):
+					if m.bucketFilter.Len() != 0 {
+						cmds = append(cmds, utils.SendMessage(OpenCompareMessage{
+							client: m.s3Client,
+							bucket: *m.buckets[m.selected].Name,
+						}))
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Multipart):
+					if m.bucketFilter.Len() != 0 {
+						cmds = append(cmds, utils.SendMessage(OpenMultipartMessage{
+							client: m.s3Client,
+							bucket: *m.buckets[m.selected].Name,
+						}))
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Refresh):
 					cmds = append(cmds,
 						m.s3Client.ListBuckets(context.Background(),
 							&s3aws.ListBucketsInput{}))
 				}
+			} else if cmd, ok := m.updateArchive(msg); ok {
+				cmds = append(cmds, cmd)
 			} else {
 				//object pane
+				if cursor, ok := m.objectFilter.Scroll(keysOf(s3Menu), msg, m.selected, m.objectRows()); ok {
+					m.selected = cursor
+					return m, tea.Batch(cmds...)
+				}
 				switch {
-				case key.Matches(msg, Keymap.Up):
-					if m.selected > 0 {
-						m.selected--
-					}
+				case key.Matches(msg, keysOf(s3Menu).Up):
+					m.selected = m.objectFilter.Move(m.selected, -1)
 
-				case key.Matches(msg, Keymap.Down):
-					if m.selected < len(m.ptr.Children)-1 {
-						m.selected++
-					}
+				case key.Matches(msg, keysOf(s3Menu).Down):
+					m.selected = m.objectFilter.Move(m.selected, 1)
+
+				case key.Matches(msg, keysOf(s3Menu).Filter) && m.ptr.IsDir:
+					m.objectFilter, cmd = m.objectFilter.Open()
+					cmds = append(cmds, cmd)
 
-				case key.Matches(msg, Keymap.Left):
+				case key.Matches(msg, keysOf(s3Menu).Left):
 					if m.ptr.Parent == nil {
 						//at root, go back to buckets
-						m.paneFocus = 0
-						if m.selected > len(m.buckets)-1 || m.selected < 0 {
-							m.selected = len(m.buckets) - 1
-						}
+						m.showBuckets()
 					} else {
 						//go up a level in the tree
-						m.ptr = m.ptr.Parent
-						m.breadcrumbs = m.breadcrumbs[:len(m.breadcrumbs)-1]
+						m.setPtr(m.ptr.Parent)
 					}
 
-				case key.Matches(msg, Keymap.Right):
-					if len(m.ptr.Children) != 0 {
+				case key.Matches(msg, keysOf(s3Menu).Right):
+					if m.objectFilter.Len() != 0 {
 						//go down a level in the tree
-						m.breadcrumbs = append(m.breadcrumbs, m.ptr.Children[m.selected].Value)
-						m.ptr = m.ptr.Children[m.selected]
-						m.selected = 0 // reset back to zero so dont get out of bounds
-						if len(m.ptr.Children) == 0 {
+						m.setPtr(m.ptr.Children[m.selected])
+						if !m.ptr.IsDir {
 							//get object metadata of file leaf node
 							ctx := context.Background()
 							cmds = append(cmds,
@@ -316,31 +511,123 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						}
 					}
 
-				case key.Matches(msg, Keymap.Create):
-					m.input.Placeholder = "Enter path of your file..."
+				case key.Matches(msg, keysOf(s3Menu).Create):
+					m.picker = InitFilePicker(m.savePath, false, s3Menu, m.theme)
+					m.picking = true
+
+				case key.Matches(msg, keysOf(s3Menu).NewFolder):
+					m.input.Placeholder = fmt.Sprintf("New folder in %s/%s...", m.selectedBucket, m.currentDir())
 					m.input.Focus()
-					m.createFlag = true
+					m.inputAction = inputNewFolder
 					cmds = append(cmds, textinput.Blink)
 
-				case key.Matches(msg, Keymap.Enter):
-					if len(m.ptr.Children) == 0 && m.ptr.Value != "/" {
-						ctx := context.Background()
-						cmds = append(cmds,
-							m.s3Client.GetObject(ctx,
-								&s3aws.GetObjectInput{
-									Bucket: aws.String(m.selectedBucket),
-									Key:    aws.String(strings.Join(m.breadcrumbs[1:], "/")),
-								}, m.savePath))
+				case key.Matches(msg, keysOf(s3Menu).SaveDir):
+					m.picker = InitFilePicker(m.savePath, true, s3Menu, m.theme)
+					m.picking = true
+
+				case key.Matches(msg, keysOf(s3Menu).Mark):
+					if m.objectFilter.Len() != 0 {
+						m.toggleMarks(m.ptr.Children[m.selected].Leaves())
+						m.selected = m.objectFilter.Move(m.selected, 1)
 					}
-					//TODO: somehow refresh the view after the file is downloaded/deleted
-				case key.Matches(msg, Keymap.Delete):
-					if len(m.ptr.Children) == 0 && m.ptr.Value != "/" {
-						m.input.Placeholder = fmt.Sprintf("Confirm delete of %s [y/n]", strings.Join(m.breadcrumbs[1:], "/"))
+
+				case key.Matches(msg, keysOf(s3Menu).SelectAll):
+					for _, leaf := range m.visibleLeaves() {
+						if leaf != m.fileTree.Root {
+							m.marked[leaf.Key()] = struct{}{}
+						}
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Invert):
+					if m.objectFilter.Len() != 0 {
+						for _, leaf := range m.visibleLeaves() {
+							if _, ok := m.marked[leaf.Key()]; ok {
+								delete(m.marked, leaf.Key())
+							} else {
+								m.marked[leaf.Key()] = struct{}{}
+							}
+						}
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Copy):
+					cmds = append(cmds, m.prompt(inputCopy, "Copy %d objects to prefix..."))
+
+				case key.Matches(msg, keysOf(s3Menu).Tag):
+					cmds = append(cmds, m.prompt(inputTag, "Tag %d objects with key=value,key2=value2..."))
+
+				case key.Matches(msg, keysOf(s3Menu).StorageClass):
+					cmds = append(cmds, m.prompt(inputStorageClass, "Storage class for %d objects (e.g. STANDARD_IA)..."))
+
+				case key.Matches(msg, keysOf(s3Menu).Enter) && len(m.marked) != 0:
+					cmds = append(cmds, m.downloadMarked())
+
+				case key.Matches(msg, keysOf(s3Menu).Delete) && len(m.marked) != 0:
+					m.input.Placeholder = fmt.Sprintf("Confirm delete of %d marked objects [y/n]", len(m.marked))
+					m.input.Focus()
+					m.inputAction = inputBatchDelete
+					cmds = append(cmds, textinput.Blink)
+
+				case key.Matches(msg, keysOf(s3Menu).Enter):
+					if m.atObject() {
+						cmds = append(cmds, m.queueDownload(m.objectKey()))
+					}
+				case key.Matches(msg, keysOf(s3Menu).Delete):
+					// a file, or an empty folder kept by its marker
+					if m.atObject() || (m.ptr.Marker && len(m.ptr.Children) == 0) {
+						m.input.Placeholder = fmt.Sprintf("Confirm delete of %s [y/n]", m.objectKey())
+						m.input.Focus()
+						m.inputAction = inputDelete
+						cmds = append(cmds, textinput.Blink)
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Bookmark):
+					cmds = append(cmds, utils.SendMessage(AddBookmarkMessage{
+						bucket: m.selectedBucket,
+						prefix: m.currentDir(),
+					}))
+
+				case key.Matches(msg, keysOf(s3Menu).Retention):
+					if m.atObject() && m.bucketLock.Enabled {
+						m.input.Placeholder = "Retain until YYYY-MM-DD or for Nd, optionally prefixed with governance/compliance..."
 						m.input.Focus()
-						m.createFlag = false
+						m.inputAction = inputRetention
 						cmds = append(cmds, textinput.Blink)
 					}
 
+				case key.Matches(msg, keysOf(s3Menu).Open):
+					if m.atObject() {
+						cmds = append(cmds, m.openObject())
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Expand):
+					if m.atObject() && s3.ArchiveFormatOf(m.objectKey()) != "" {
+						cmds = append(cmds,
+							m.s3Client.ListArchive(context.Background(), m.selectedBucket, m.objectKey()),
+							utils.SendMessage(internal.APIMessage{
+								Status: fmt.Sprintf("Reading archive %s/%s...", m.selectedBucket, m.objectKey()),
+							}))
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).LegalHold):
+					if m.atObject() && m.bucketLock.Enabled {
+						on := m.objectMetadata.LegalHold != types.ObjectLockLegalHoldStatusOn
+						cmds = append(cmds, m.s3Client.SetObjectLegalHold(context.Background(),
+							m.selectedBucket, m.objectKey(), on))
+					}
+
+				case key.Matches(msg, keysOf(s3Menu).Sync):
+					cmds = append(cmds, utils.SendMessage(OpenSyncMessage{
+						client: m.s3Client,
+						bucket: m.selectedBucket,
+						prefix: m.currentDir(),
+					}))
+
+				case key.Matches(msg, keysOf(s3Menu).Compare):
+					cmds = append(cmds, utils.SendMessage(OpenCompareMessage{
+						client: m.s3Client,
+						bucket: m.selectedBucket,
+						prefix: m.currentDir(),
+					}))
 				}
 
 			}
@@ -350,19 +637,33 @@ func (m S3Menu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// paneWidths are the text widths of the bucket and object panes. A narrow
+// window shows only the focused pane at full width
+func (m S3Menu) paneWidths() (int, int) {
+	if narrow() {
+		full := windowWidth() - frameSize
+		return full, full
+	}
+	return bucketPaneWidth, max(windowWidth()-bucketPaneWidth-2*frameSize, bucketPaneWidth)
+}
+
 func (m S3Menu) View() string {
+	leftWidth, rightWidth := m.paneWidths()
 
 	var left strings.Builder
-	left.WriteString(HeaderStyle("Buckets") + "\n\n")
+	left.WriteString(m.theme.Header.Render("Buckets") + "\n\n")
 	if m.loading {
-		left.WriteString(DocStyle(fmt.Sprintf("%s Loading buckets...\n", m.spinner.View())))
+		left.WriteString(m.theme.Doc.Render(fmt.Sprintf("%s Loading buckets...\n", m.theme.Spinner.Render(m.spinner.View()))))
 	} else if m.err != nil {
-		left.WriteString(ErrStyle("Error: :c"))
+		left.WriteString(m.theme.Err.Render("Error: :c"))
 	} else if len(m.buckets) == 0 {
-		left.WriteString(DocStyle("No buckets found.\n"))
+		left.WriteString(m.theme.Doc.Render("No buckets found.\n"))
 	} else {
 		//todo create column for creation date and region
-		for i, bucket := range m.buckets {
+		start, end := scrollWindow(max(m.bucketFilter.Row(m.selected), 0), m.bucketFilter.Len(), listRows(bucketReserved))
+		for row := start; row < end; row++ {
+			i := m.bucketFilter.Index(row)
+			bucket := m.buckets[i]
 			cursor := " "
 			display := ""
 
@@ -371,67 +672,97 @@ func (m S3Menu) View() string {
 			} else {
 				display = fmt.Sprintf("%s %s %s", *bucket.Name, *bucket.BucketRegion, bucket.CreationDate.Format("2006-01-02"))
 			}
+			display = clip(display, leftWidth-1)
 
+			// the name leads the row, so the matched positions line up
 			if i == m.selected && m.paneFocus == 0 {
-				cursor = CursorStyle(">")
-				display = SelectedStyle.Render(display)
+				cursor = m.theme.Cursor.Render(">")
+				display = m.bucketFilter.Highlight(row, display, m.theme.Selected)
 			} else {
-				display = ChoiceStyle(display)
+				display = m.bucketFilter.Highlight(row, display, m.theme.Choice)
 			}
 
 			left.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
 		}
+		if filter := m.bucketFilter.View(); filter != "" {
+			left.WriteString(filter + "\n")
+		}
 	}
 
 	var right strings.Builder
 	// would be cool if could view objects like a tree from left to right
-	if m.viewObjects {
-		right.WriteString(HeaderStyle(fmt.Sprintf("Objects in: %s", m.selectedBucket)) + "\n\n")
-		if len(m.objects) == 0 {
-			right.WriteString(DocStyle("No objects found.\n"))
+	if m.picking {
+		picker := m.picker
+		picker.width = rightWidth
+		right.WriteString(picker.View())
+	} else if m.viewObjects {
+		right.WriteString(m.theme.Header.Render(fmt.Sprintf("Objects in: %s", m.selectedBucket)) + "\n")
+		if m.bucketLock.Enabled {
+			right.WriteString(m.theme.Choice.Render(fmt.Sprintf("Object Lock: %s", m.bucketLock)) + "\n")
+		}
+		if len(m.marked) != 0 {
+			right.WriteString(m.theme.Alert.Render(fmt.Sprintf("%d marked", len(m.marked))))
+		}
+		right.WriteString("\n")
+		if len(m.fileTree.Root.Children) == 0 {
+			right.WriteString(m.theme.Doc.Render("No objects found.\n"))
 		} else {
 			// render the current dir
-			if len(m.ptr.Children) != 0 {
-				for i, object := range m.ptr.Children {
+			if archive := m.ptr.ArchiveNode(); archive != nil {
+				right.WriteString(m.theme.Choice.Render(fmt.Sprintf("Archive: %s (%s)", archive.Path(), s3.ArchiveFormatOf(archive.Path()))) + "\n")
+			}
+			if m.ptr.IsDir && len(m.ptr.Children) == 0 {
+				right.WriteString(m.theme.Doc.Render("Empty folder.\n"))
+			} else if len(m.ptr.Children) != 0 {
+				if m.ptr.Archive {
+					right.WriteString(m.objectDetails() + "\n")
+				}
+				start, end := scrollWindow(max(m.objectFilter.Row(m.selected), 0), m.objectFilter.Len(), m.objectRows())
+				for row := start; row < end; row++ {
+					i := m.objectFilter.Index(row)
+					object := m.ptr.Children[i]
 					cursor := " "
-					display := object.Value
+					prefix := m.markPrefix(object)
+					name := object.Value
+					if object.IsDir {
+						name += "/"
+					}
+					name = clip(name, rightWidth-1-lipgloss.Width(prefix))
 
+					style := m.theme.Choice
 					if i == m.selected && m.paneFocus == 1 {
-						cursor = CursorStyle(">")
-						display = SelectedStyle.Render(display)
-					} else {
-						display = ChoiceStyle(display)
+						cursor = m.theme.Cursor.Render(">")
+						style = m.theme.Selected
 					}
+					display := style.Render(prefix) + m.objectFilter.Highlight(row, name, style)
 
 					right.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
 				}
-			} else {
-				//render the file metadata
-				right.WriteString(fmt.Sprintf("File: %s\n", strings.Join(m.breadcrumbs[1:], "/")))
-				right.WriteString(fmt.Sprintf("Size: %d bytes\n", m.objectMetadata.ContentLength))
-				right.WriteString(fmt.Sprintf("Last Modified: %s\n", m.objectMetadata.LastModified.Format("2006-01-02 15:04:05")))
-				right.WriteString(fmt.Sprintf("ETag: %s\n", m.objectMetadata.ETag))
-				right.WriteString(fmt.Sprintf("Storage Class: %s\n", m.objectMetadata.StorageClass))
-				right.WriteString(fmt.Sprintf("Content Type: %s\n", m.objectMetadata.ContentType))
-				if len(m.objectMetadata.Metadata) != 0 {
-					right.WriteString("User Metadata:\n")
-					for k, v := range m.objectMetadata.Metadata {
-						right.WriteString(fmt.Sprintf("  %s: %s\n", k, v))
-					}
+				if filter := m.objectFilter.View(); filter != "" {
+					right.WriteString(filter + "\n")
 				}
-				right.WriteString(fmt.Sprintf("\nPress [Enter] to download %s\n", strings.Join(m.breadcrumbs[1:], "/")))
+			} else if m.ptr.Member {
+				m.viewMember(&right)
+			} else {
+				right.WriteString(m.objectDetails())
 			}
 		}
-		right.WriteString("\n" + ChoiceStyle(m.breadcrumbs[0]+strings.Join(m.breadcrumbs[1:], "/")))
+		right.WriteString("\n" + m.theme.Choice.Render(clip(m.breadcrumbs[0]+strings.Join(m.breadcrumbs[1:], "/"), rightWidth)))
 	} else {
-		right.WriteString(DocStyle("Press [Enter] to view bucket contents."))
+		right.WriteString(m.theme.Doc.Render("Press [Enter] to view bucket contents."))
 	}
 
-	leftBox := leftPanel.Render(left.String())
-	rightBox := rightPanel.Render(right.String())
-	menu := flexLayout.Render(
-		lipgloss.JoinHorizontal(lipgloss.Top, leftBox, rightBox),
-	)
+	leftBox := m.theme.Border.Width(leftWidth).Render(left.String())
+	rightBox := m.theme.Border.Width(rightWidth).Render(right.String())
+	var menu string
+	switch {
+	case !narrow():
+		menu = flexLayout.Render(lipgloss.JoinHorizontal(lipgloss.Top, leftBox, rightBox))
+	case m.paneFocus == 0 && !m.picking:
+		menu = leftBox
+	default:
+		menu = rightBox
+	}
 
 	if m.input.Focused() {
 		menu += "\n" + m.input.View() // Add the input field at the bottom
@@ -439,10 +770,879 @@ func (m S3Menu) View() string {
 	return menu
 }
 
-func (m S3Menu) createS3Client(cfg aws.Config, dev bool) s3.S3API {
-	client, ok := utils.ClientFactory("s3", cfg, true).(s3.S3API)
+// atObject reports whether the cursor is on an object's metadata, which
+// includes an expanded archive
+func (m S3Menu) atObject() bool {
+	return !m.ptr.IsDir && m.ptr.Parent != nil && !m.ptr.Member
+}
+
+// objectDetails renders the metadata of the object under the cursor
+func (m S3Menu) objectDetails() string {
+	var b strings.Builder
+	b.WriteString(fmt.Sprintf("File: %s\n", strings.Join(m.breadcrumbs[1:], "/")))
+	b.WriteString(fmt.Sprintf("Size: %d bytes\n", m.objectMetadata.ContentLength))
+	b.WriteString(fmt.Sprintf("Last Modified: %s\n", m.objectMetadata.LastModified.Format("2006-01-02 15:04:05")))
+	b.WriteString(fmt.Sprintf("ETag: %s\n", m.objectMetadata.ETag))
+	b.WriteString(fmt.Sprintf("Storage Class: %s\n", m.objectMetadata.StorageClass))
+	b.WriteString(fmt.Sprintf("Content Type: %s\n", m.objectMetadata.ContentType))
+	if m.objectMetadata.LockMode != "" {
+		b.WriteString(fmt.Sprintf("Retention: %s until %s\n", m.objectMetadata.LockMode,
+			m.objectMetadata.RetainUntil.Local().Format("2006-01-02 15:04:05")))
+	}
+	if m.objectMetadata.LegalHold != "" {
+		b.WriteString(fmt.Sprintf("Legal Hold: %s\n", m.objectMetadata.LegalHold))
+	}
+	if len(m.objectMetadata.Metadata) != 0 {
+		b.WriteString("User Metadata:\n")
+		for k, v := range m.objectMetadata.Metadata {
+			b.WriteString(fmt.Sprintf("  %s: %s\n", k, v))
+		}
+	}
+	k := keysOf(s3Menu)
+	b.WriteString(fmt.Sprintf("\nPress [%s] to download %s to %s\n", k.Enter.Help().Key, strings.Join(m.breadcrumbs[1:], "/"), m.savePath))
+	b.WriteString(fmt.Sprintf("Press [%s] to open it\n", k.Open.Help().Key))
+	switch {
+	case m.ptr.Archive:
+		b.WriteString(fmt.Sprintf("Press [%s] to read the archive again, [%s] to browse its members\n", k.Expand.Help().Key, k.Right.Help().Key))
+	case s3.ArchiveFormatOf(m.objectKey()) != "":
+		b.WriteString(fmt.Sprintf("Press [%s] to browse the archive\n", k.Expand.Help().Key))
+	}
+	if m.bucketLock.Enabled {
+		b.WriteString(m.theme.Help.Render(fmt.Sprintf("[%s] %s  [%s] %s",
+			k.Retention.Help().Key, k.Retention.Help().Desc, k.LegalHold.Help().Key, k.LegalHold.Help().Desc)) + "\n")
+	}
+	return b.String()
+}
+
+// objectRows is how many entries the object pane lists. An expanded archive
+// shows its metadata above its members
+func (m S3Menu) objectRows() int {
+	if m.ptr.Archive {
+		return listRows(objectReserved + lipgloss.Height(m.objectDetails()))
+	}
+	return listRows(objectReserved)
+}
+
+// expandArchive lists the members of the archive at key under its tree node
+func (m *S3Menu) expandArchive(key string, members []s3.ArchiveMember) {
+	dir, name := path.Split(key)
+	parent, found := m.fileTree.Find(dir)
+	if !found {
+		return
+	}
+	for _, node := range parent.Children {
+		if node.Value != name || node.IsDir {
+			continue
+		}
+		names := make([]string, len(members))
+		for i, member := range members {
+			names[i] = member.Name
+			m.archiveMembers[key+"/"+member.Name] = member
+		}
+		node.AddArchive(names)
+		if node == m.ptr {
+			m.selected = 0
+			m.filterObjects()
+		}
+	}
+}
+
+// updateArchive handles keys while browsing an archive, where only
+// navigation and extracting members make sense. The archive itself is still
+// an object, so it reports false for its object keys to let the object pane
+// download or open it
+func (m *S3Menu) updateArchive(msg tea.KeyMsg) (tea.Cmd, bool) {
+	switch {
+	case m.ptr.ArchiveNode() == nil:
+		return nil, false
+	case m.ptr.Archive && m.archiveObjectKey(msg):
+		return nil, false
+	}
+	if cursor, ok := m.objectFilter.Scroll(keysOf(s3Menu), msg, m.selected, m.objectRows()); ok {
+		m.selected = cursor
+		return nil, true
+	}
+	switch {
+	case key.Matches(msg, keysOf(s3Menu).Up):
+		m.selected = m.objectFilter.Move(m.selected, -1)
+	case key.Matches(msg, keysOf(s3Menu).Down):
+		m.selected = m.objectFilter.Move(m.selected, 1)
+	case key.Matches(msg, keysOf(s3Menu).Filter) && m.ptr.IsDir:
+		var cmd tea.Cmd
+		m.objectFilter, cmd = m.objectFilter.Open()
+		return cmd, true
+	case key.Matches(msg, keysOf(s3Menu).Left):
+		m.setPtr(m.ptr.Parent)
+	case key.Matches(msg, keysOf(s3Menu).Right):
+		if m.objectFilter.Len() != 0 {
+			m.setPtr(m.ptr.Children[m.selected])
+		}
+	case key.Matches(msg, keysOf(s3Menu).Enter):
+		if m.ptr.Member && !m.ptr.IsDir {
+			return m.queueExtract(m.ptr), true
+		}
+	}
+	return nil, true
+}
+
+// archiveObjectKey reports whether msg is one of the object keys an expanded
+// archive keeps
+func (m S3Menu) archiveObjectKey(msg tea.KeyMsg) bool {
+	k := keysOf(s3Menu)
+	return key.Matches(msg, k.Enter, k.Delete, k.Open, k.Expand, k.Retention, k.LegalHold, k.Bookmark, k.Sync, k.Compare)
+}
+
+// viewMember renders the details of an archive member
+func (m S3Menu) viewMember(b *strings.Builder) {
+	member := m.archiveMembers[m.ptr.Key()]
+	b.WriteString(fmt.Sprintf("Member: %s\n", member.Name))
+	b.WriteString(fmt.Sprintf("Size: %d bytes\n", member.Size))
+	if member.CompressedSize > 0 {
+		b.WriteString(fmt.Sprintf("Compressed: %d bytes\n", member.CompressedSize))
+	}
+	if !member.Modified.IsZero() {
+		b.WriteString(fmt.Sprintf("Last Modified: %s\n", member.Modified.Format("2006-01-02 15:04:05")))
+	}
+	b.WriteString(fmt.Sprintf("\nPress [Enter] to extract %s to %s\n", member.Name, m.savePath))
+}
+
+// objectKey returns the key of the object under the cursor, or of the
+// current folder's marker
+func (m S3Menu) objectKey() string {
+	return m.ptr.Key()
+}
+
+// removeKeys drops deleted objects from the tree, moving up when the
+// current folder disappears with them
+func (m *S3Menu) removeKeys(keys []string) {
+	for _, k := range keys {
+		m.fileTree.Remove(k)
+		delete(m.marked, k)
+	}
+
+	node := m.ptr
+	for !node.Attached() {
+		node = node.Parent
+	}
+	if node != m.ptr {
+		m.setPtr(node)
+	}
+	if m.selected > len(m.ptr.Children)-1 {
+		m.selected = max(len(m.ptr.Children)-1, 0)
+	}
+	m.filterObjects()
+}
+
+// setPtr moves the tree pointer to node, rebuilds the breadcrumbs to match and
+// puts the cursor back where it was when node was last visited
+func (m *S3Menu) setPtr(node *internal.TreeNode) {
+	m.cursors[m.cursorKey(m.ptr)] = m.selected
+	m.ptr = node
+	m.breadcrumbs = []string{m.fileTree.Root.Value}
+	if p := node.Path(); p != "" {
+		m.breadcrumbs = append(m.breadcrumbs, strings.Split(p, "/")...)
+	}
+	m.selected = min(m.cursors[m.cursorKey(node)], max(len(node.Children)-1, 0))
+	m.objectFilter = m.objectFilter.Clear()
+	m.filterObjects()
+}
+
+// filterBuckets matches the bucket filter against the listed buckets
+func (m *S3Menu) filterBuckets() {
+	names := make([]string, len(m.buckets))
+	for i, b := range m.buckets {
+		names[i] = aws.ToString(b.Name)
+	}
+	m.bucketFilter = m.bucketFilter.SetItems(names)
+	if m.paneFocus == 0 {
+		m.selected = m.bucketFilter.Move(m.selected, 0)
+	}
+}
+
+// filterObjects matches the object filter against the folder being browsed
+func (m *S3Menu) filterObjects() {
+	names := make([]string, len(m.ptr.Children))
+	for i, child := range m.ptr.Children {
+		names[i] = child.Value
+	}
+	m.objectFilter = m.objectFilter.SetItems(names)
+	if m.paneFocus == 1 {
+		m.selected = m.objectFilter.Move(m.selected, 0)
+	}
+}
+
+// visibleLeaves are the objects under the entries the object filter shows
+func (m S3Menu) visibleLeaves() []*internal.TreeNode {
+	var leaves []*internal.TreeNode
+	for row := range m.objectFilter.Len() {
+		leaves = append(leaves, m.ptr.Children[m.objectFilter.Index(row)].Leaves()...)
+	}
+	return leaves
+}
+
+func (m S3Menu) cursorKey(node *internal.TreeNode) string {
+	return m.selectedBucket + "/" + node.Path()
+}
+
+// showBuckets moves the focus to the bucket list with the cursor on the open bucket
+func (m *S3Menu) showBuckets() {
+	m.cursors[m.cursorKey(m.ptr)] = m.selected
+	m.paneFocus = 0
+	m.selected = 0
+	for i, b := range m.buckets {
+		if aws.ToString(b.Name) == m.selectedBucket {
+			m.selected = i
+		}
+	}
+	m.selected = m.bucketFilter.Move(m.selected, 0)
+}
+
+// Location returns where the view is for the navigation history: "" on the
+// bucket list, otherwise the bucket followed by the key of the folder,
+// object or archive member under the cursor
+func (m S3Menu) Location() string {
+	if m.opening {
+		// report where the view is going so the listing does not count as a visit
+		target := m.pendingObject
+		if target == "" && m.pendingPrefix != "" {
+			target = m.pendingPrefix + "/"
+		}
+		return m.selectedBucket + "/" + target
+	}
+	if m.paneFocus == 0 {
+		return ""
+	}
+	return m.selectedBucket + "/" + strings.TrimPrefix(m.ptr.Key(), "/")
+}
+
+// Restore moves the view back to a location returned by Location, listing
+// the bucket again when its objects are no longer loaded
+func (m S3Menu) Restore(place string) (tea.Model, tea.Cmd) {
+	if place == "" {
+		m.showBuckets()
+		return m, nil
+	}
+
+	bucket, p, _ := strings.Cut(place, "/")
+	if bucket == m.selectedBucket && m.viewObjects {
+		if node, ok := m.fileTree.Lookup(p); ok {
+			m.paneFocus = 1
+			m.setPtr(node)
+			if m.atObject() && m.objectMetadata.Key != m.objectKey() {
+				return m, m.s3Client.GetObjectMetadata(context.Background(),
+					&s3aws.HeadObjectInput{
+						Bucket: aws.String(m.selectedBucket),
+						Key:    aws.String(m.objectKey()),
+					})
+			}
+			return m, nil
+		}
+	}
+
+	// objects are listed by folder, so list the closest one and select the object in it
+	dir := p
+	if !strings.HasSuffix(dir, "/") {
+		dir = path.Dir(dir)
+	}
+	if dir == "." {
+		dir = ""
+	}
+	m, cmd := m.goTo(bucket, dir)
+	if !strings.HasSuffix(p, "/") {
+		m.pendingObject = p
+	}
+	return m, cmd
+}
+
+// goTo opens bucket and moves to the prefix folder once its objects are listed
+func (m S3Menu) goTo(bucket, prefix string) (S3Menu, tea.Cmd) {
+	m.selectedBucket = bucket
+	m.savePath = "."
+	if dir, ok := m.saveDirs[bucket]; ok {
+		m.savePath = dir
+	}
+	m.bucketLock = s3.BucketObjectLock{}
+	m.pendingPrefix = strings.Trim(prefix, "/")
+	m.pendingObject = ""
+	m.opening = true
+	for i, b := range m.buckets {
+		if aws.ToString(b.Name) == bucket {
+			m.selected = i
+		}
+	}
+
+	input := &s3aws.ListObjectsV2Input{Bucket: aws.String(bucket),
+		MaxKeys: aws.Int32(10)}
+	if m.pendingPrefix != "" {
+		input.Prefix = aws.String(m.pendingPrefix + "/")
+	}
+	ctx := context.Background()
+	return m, tea.Batch(
+		m.s3Client.ListObjects(ctx, input),
+		m.s3Client.GetBucketObjectLock(ctx, bucket))
+}
+
+// currentDir returns the key prefix of the folder being browsed, which is the
+// parent folder when the cursor is on a file's metadata
+func (m S3Menu) currentDir() string {
+	if !m.ptr.IsDir && m.ptr.Parent != nil {
+		return m.ptr.Parent.Path()
+	}
+	return m.ptr.Path()
+}
+
+// currentDirKey returns the object key for name inside the current folder
+func (m S3Menu) currentDirKey(name string) string {
+	if dir := m.currentDir(); dir != "" {
+		return dir + "/" + name
+	}
+	return name
+}
+
+// targetKeys returns the marked keys, or the keys under the cursor when nothing is marked
+func (m S3Menu) targetKeys() []string {
+	if len(m.marked) != 0 {
+		return m.markedKeys()
+	}
+	node := m.ptr
+	if m.objectFilter.Len() != 0 {
+		node = m.ptr.Children[m.selected]
+	} else if m.ptr.Parent == nil || len(m.ptr.Children) != 0 {
+		// nothing under the cursor, or every entry is filtered out
+		return nil
+	}
+	var keys []string
+	for _, leaf := range node.Leaves() {
+		keys = append(keys, leaf.Key())
+	}
+	return keys
+}
+
+func (m S3Menu) markedKeys() []string {
+	keys := make([]string, 0, len(m.marked))
+	for k := range m.marked {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// toggleMarks unmarks the leaves if they are all marked, otherwise marks them
+func (m *S3Menu) toggleMarks(leaves []*internal.TreeNode) {
+	all := true
+	for _, leaf := range leaves {
+		if _, ok := m.marked[leaf.Key()]; !ok {
+			all = false
+			break
+		}
+	}
+	for _, leaf := range leaves {
+		if all {
+			delete(m.marked, leaf.Key())
+		} else {
+			m.marked[leaf.Key()] = struct{}{}
+		}
+	}
+}
+
+// markPrefix shows "* " for fully marked entries and "~ " for partially marked folders
+func (m S3Menu) markPrefix(node *internal.TreeNode) string {
+	if len(m.marked) == 0 {
+		return ""
+	}
+	leaves := node.Leaves()
+	if len(leaves) == 0 {
+		return "  "
+	}
+	count := 0
+	for _, leaf := range leaves {
+		if _, ok := m.marked[leaf.Key()]; ok {
+			count++
+		}
+	}
+	switch {
+	case count == len(leaves):
+		return "* "
+	case count > 0:
+		return "~ "
+	}
+	return "  "
+}
+
+// prompt asks for the argument of a batch action over the target keys
+func (m *S3Menu) prompt(action int, placeholder string) tea.Cmd {
+	keys := m.targetKeys()
+	if len(keys) == 0 {
+		return nil
+	}
+	m.input.Placeholder = fmt.Sprintf(placeholder, len(keys))
+	m.input.Focus()
+	m.inputAction = action
+	return textinput.Blink
+}
+
+// submitInput runs the object pane action the input prompt was opened for
+func (m S3Menu) submitInput(value string) tea.Cmd {
+	value = strings.TrimSpace(value)
+	ctx := context.Background()
+	batch := s3.BatchInput{Bucket: m.selectedBucket, Keys: m.targetKeys()}
+	var same []string
+
+	switch m.inputAction {
+	case inputDelete:
+		if value != "y" {
+			return nil
+		}
+		return m.s3Client.DeleteObject(ctx, &s3aws.DeleteObjectInput{
+			Bucket: aws.String(m.selectedBucket),
+			Key:    aws.String(m.objectKey()),
+		})
+
+	case inputContentType:
+		if m.pendingUpload == "" {
+			return nil
+		}
+		return m.queueUpload(m.currentDirKey(filepath.Base(m.pendingUpload)), m.pendingUpload, value)
+
+	case inputNewFolder:
+		name := strings.Trim(value, "/")
+		if name == "" {
+			return nil
+		}
+		return m.s3Client.CreateFolder(ctx, m.selectedBucket, m.currentDirKey(name)+"/")
+
+	case inputBatchDelete:
+		if value != "y" {
+			return nil
+		}
+		batch.Op = s3.BatchDelete
+
+	case inputRetention:
+		mode, until, err := s3.ParseRetention(value, m.objectMetadata.LockMode, m.bucketLock.Mode, time.Now())
+		if err != nil {
+			return utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		return m.s3Client.SetObjectRetention(ctx, s3.RetentionInput{
+			Bucket:      m.selectedBucket,
+			Key:         m.objectKey(),
+			Mode:        mode,
+			RetainUntil: until,
+			Current:     m.objectMetadata,
+		})
+
+	case inputCopy:
+		batch.Op = s3.BatchCopy
+		batch.CopyTargets, same = m.copyTargets(value, batch.Keys)
+		batch.Keys = slices.DeleteFunc(batch.Keys, func(k string) bool {
+			_, ok := batch.CopyTargets[k]
+			return !ok
+		})
+		if len(batch.Keys) == 0 {
+			return utils.SendMessage(internal.APIMessage{
+				Status:   fmt.Sprintf("Nothing to copy, the %d objects would be copied onto themselves", len(same)),
+				Severity: internal.SeverityWarn,
+			})
+		}
+
+	case inputTag:
+		tags, err := s3.ParseTags(value)
+		if err != nil {
+			return utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		batch.Op = s3.BatchTag
+		batch.Tags = tags
+
+	case inputStorageClass:
+		class, err := s3.ParseStorageClass(value)
+		if err != nil {
+			return utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		batch.Op = s3.BatchStorageClass
+		batch.StorageClass = class
+	}
+
+	status := internal.APIMessage{Status: fmt.Sprintf("S3: running %s on %d objects...", batch.Op, len(batch.Keys))}
+	if len(same) != 0 {
+		status.Status += fmt.Sprintf(" skipping %d that would be copied onto themselves", len(same))
+		status.Severity = internal.SeverityWarn
+	}
+	return tea.Batch(m.s3Client.RunBatch(ctx, batch), utils.SendMessage(status))
+}
+
+// copyTargets maps each key to the destination prefix, keeping its path
+// relative to the current folder, or its base name when outside of it.
+// Keys that would be copied onto themselves are left out and returned apart
+func (m S3Menu) copyTargets(prefix string, keys []string) (map[string]string, []string) {
+	prefix = strings.Trim(prefix, "/")
+	current := m.currentDir()
+
+	targets := make(map[string]string, len(keys))
+	var same []string
+	for _, k := range keys {
+		rel := k
+		if current != "" {
+			if trimmed, ok := strings.CutPrefix(k, current+"/"); ok {
+				rel = trimmed
+			} else {
+				_, rel = path.Split(k)
+			}
+		}
+		dest := rel
+		if prefix != "" {
+			dest = prefix + "/" + rel
+		}
+		if dest == k {
+			same = append(same, k)
+			continue
+		}
+		targets[k] = dest
+	}
+	return targets, same
+}
+
+// downloadMarked queues downloads of the marked objects and clears the marks
+func (m *S3Menu) downloadMarked() tea.Cmd {
+	keys := m.markedKeys()
+	cmds := make([]tea.Cmd, 0, len(keys)+1)
+	for _, k := range keys {
+		cmds = append(cmds, m.queueDownload(k))
+	}
+	m.marked = make(map[string]struct{})
+	cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+		Status: fmt.Sprintf("Queued %d downloads", len(keys)),
+	}))
+	return tea.Batch(cmds...)
+}
+
+// queueUpload hands the upload of filePath to key over to the transfer manager.
+// An empty contentType is detected, and the config decides on compression
+func (m S3Menu) queueUpload(key, filePath, contentType string) tea.Cmd {
+	client, bucket := m.s3Client, m.selectedBucket
+	encoding := internal.CompressionFor(m.appConfig.Compression, filePath)
+	// the size is needed up front to decide whether the upload waits for the transfer window
+	var size int64
+	if info, err := os.Stat(filePath); err == nil {
+		size = info.Size()
+	}
+	TransferManager.Enqueue(transfer.Upload, bucket+"/"+key, size,
+		func(ctx context.Context, tracker *transfer.Tracker) error {
+			input := &s3aws.PutObjectInput{
+				Bucket: aws.String(bucket),
+				Key:    aws.String(key),
+			}
+			if contentType != "" {
+				input.ContentType = aws.String(contentType)
+			}
+			if encoding != "" {
+				input.ContentEncoding = aws.String(encoding)
+			}
+			return client.Upload(ctx, input, filePath, tracker)
+		})
+
+	status := fmt.Sprintf("Queued upload of %s to %s/%s", filePath, bucket, key)
+	if encoding != "" {
+		status += fmt.Sprintf(" (%s)", encoding)
+	}
+	return utils.SendMessage(internal.APIMessage{Status: status})
+}
+
+// queueDownload hands the download of key into savePath over to the transfer
+// manager. The size comes from the listing, or else from a HeadObject, so the
+// transfer window defers the download on its real size
+func (m S3Menu) queueDownload(key string) tea.Cmd {
+	client, bucket, savePath := m.s3Client, m.selectedBucket, m.savePath
+	enqueue := func(size int64) tea.Msg {
+		TransferManager.Enqueue(transfer.Download, bucket+"/"+key, size,
+			func(ctx context.Context, tracker *transfer.Tracker) error {
+				return client.Download(ctx, &s3aws.GetObjectInput{
+					Bucket: aws.String(bucket),
+					Key:    aws.String(key),
+				}, savePath, tracker)
+			})
+		return internal.APIMessage{Status: fmt.Sprintf("Queued download of %s/%s", bucket, key)}
+	}
+	if size, ok := m.sizes[key]; ok {
+		return func() tea.Msg { return enqueue(size) }
+	}
+	return func() tea.Msg {
+		head, err := client.Head(context.Background(), bucket, key)
+		if err != nil {
+			// the download reports the error if the object is really gone
+			return enqueue(0)
+		}
+		return enqueue(aws.ToInt64(head.ContentLength))
+	}
+}
+
+// queueExtract hands the extraction of an archive member into savePath over to the transfer manager
+func (m S3Menu) queueExtract(node *internal.TreeNode) tea.Cmd {
+	client, bucket, savePath := m.s3Client, m.selectedBucket, m.savePath
+	archive, member := node.ArchiveNode().Path(), node.MemberName()
+	size := m.archiveMembers[node.Key()].CompressedSize
+	if size == 0 {
+		size = m.archiveMembers[node.Key()].Size
+	}
+	TransferManager.Enqueue(transfer.Download, bucket+"/"+node.Key(), size,
+		func(ctx context.Context, tracker *transfer.Tracker) error {
+			return client.ExtractMember(ctx, bucket, archive, member, savePath, tracker)
+		})
+	return utils.SendMessage(internal.APIMessage{
+		Status: fmt.Sprintf("Queued extraction of %s from %s/%s", member, bucket, archive),
+	})
+}
+
+// openObject opens the object under the cursor with the configured opener,
+// downloading it into the cache unless an unchanged copy is already there
+func (m S3Menu) openObject() tea.Cmd {
+	client, bucket, key, meta := m.s3Client, m.selectedBucket, m.objectKey(), m.objectMetadata
+	if meta.Key != key {
+		return utils.SendMessage(internal.APIMessage{Status: "Metadata is still loading, try again", Severity: internal.SeverityWarn})
+	}
+	cacheDir, err := utils.CacheDir()
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: err})
+	}
+
+	opener := m.appConfig.Opener
+	if path, ok := utils.CachedObject(cacheDir, bucket, key, meta.ETag); ok {
+		if err := utils.OpenFile(path, opener); err != nil {
+			return utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		return utils.SendMessage(internal.APIMessage{
+			Status:   fmt.Sprintf("Opened cached copy of %s/%s", bucket, key),
+			Severity: internal.SeveritySuccess,
+		})
+	}
+
+	dir := utils.ObjectCacheDir(cacheDir, bucket, key, meta.ETag)
+	TransferManager.Enqueue(transfer.Download, bucket+"/"+key, meta.ContentLength,
+		func(ctx context.Context, tracker *transfer.Tracker) error {
+			if err := os.MkdirAll(filepath.Dir(dir), 0o755); err != nil {
+				return err
+			}
+			// download next to the cache entry and move it in place when complete,
+			// so an interrupted download is never mistaken for a cached copy
+			tmp, err := os.MkdirTemp(filepath.Dir(dir), "download-")
+			if err != nil {
+				return err
+			}
+			defer os.RemoveAll(tmp)
+
+			input := &s3aws.GetObjectInput{Bucket: aws.String(bucket), Key: aws.String(key)}
+			if meta.ETag != "" {
+				input.IfMatch = aws.String(meta.ETag)
+			}
+			if err := client.Download(ctx, input, tmp, tracker); err != nil {
+				return err
+			}
+			os.RemoveAll(dir)
+			if err := os.Rename(tmp, dir); err != nil {
+				return err
+			}
+			path, _ := utils.CachedObject(cacheDir, bucket, key, meta.ETag)
+			return utils.OpenFile(path, opener)
+		})
+	return utils.SendMessage(internal.APIMessage{
+		Status: fmt.Sprintf("Downloading %s/%s to open it", bucket, key),
+	})
+}
+
+func (m S3Menu) createS3Client(cfg aws.Config, endpoint *internal.Endpoint) s3.S3API {
+	client, ok := utils.ClientFactory("s3", cfg, endpoint, m.logger).(s3.S3API)
 	if !ok {
 		panic("utils.ClientFactory(\"s3\") does not implement s3.S3API")
 	}
 	return client
 }
+
+func (m S3Menu) Typing() bool {
+	return m.input.Focused() || m.picking || m.paneFilter().Active()
+}
+
+// paneFilter is the filter of the focused pane
+func (m S3Menu) paneFilter() ListFilter {
+	if m.paneFocus == 0 {
+		return m.bucketFilter
+	}
+	return m.objectFilter
+}
+
+func (m S3Menu) KeyMap() help.KeyMap {
+	switch {
+	case m.picking:
+		return m.picker.KeyMap()
+	case m.input.Focused():
+		return promptKeys(s3Menu)
+	case m.paneFilter().Active():
+		return m.paneFilter().KeyMap()
+	case m.paneFocus == 0:
+		has := m.bucketFilter.Len() != 0
+		nav := []key.Binding{
+			when(keysOf(s3Menu).Up, has), when(keysOf(s3Menu).Down, has),
+			when(withHelp(keysOf(s3Menu).Enter, "open bucket"), has),
+			when(withHelp(keysOf(s3Menu).Right, "objects"), m.viewObjects),
+			when(withHelp(keysOf(s3Menu).Backspace, "hide objects"), m.viewObjects),
+		}
+		actions := []key.Binding{
+			withHelp(keysOf(s3Menu).Create, "create bucket"),
+			keysOf(s3Menu).Refresh, keysOf(s3Menu).Filter,
+			when(keysOf(s3Menu).Bookmark, has),
+		}
+		views := []key.Binding{when(keysOf(s3Menu).Sync, has), when(keysOf(s3Menu).Compare, has), when(keysOf(s3Menu).Multipart, has)}
+		return viewKeyMap{
+			short: append(nav[:3:3], withHelp(keysOf(s3Menu).Create, "create bucket")),
+			full:  [][]key.Binding{nav, scrollKeys(keysOf(s3Menu), has), actions, views},
+		}
+	case m.ptr.Member:
+		has := m.objectFilter.Len() != 0
+		keys := []key.Binding{
+			when(keysOf(s3Menu).Up, has), when(keysOf(s3Menu).Down, has),
+			withHelp(keysOf(s3Menu).Left, "up"), when(withHelp(keysOf(s3Menu).Right, "open"), has),
+			when(withHelp(keysOf(s3Menu).Enter, "extract"), !m.ptr.IsDir),
+			when(keysOf(s3Menu).Filter, m.ptr.IsDir),
+		}
+		return viewKeyMap{short: keys, full: [][]key.Binding{keys, scrollKeys(keysOf(s3Menu), has)}}
+	case m.ptr.Archive:
+		has := m.objectFilter.Len() != 0
+		nav := []key.Binding{
+			when(keysOf(s3Menu).Up, has), when(keysOf(s3Menu).Down, has),
+			withHelp(keysOf(s3Menu).Left, "up"), when(withHelp(keysOf(s3Menu).Right, "browse"), has),
+		}
+		actions := []key.Binding{
+			withHelp(keysOf(s3Menu).Enter, "download"), keysOf(s3Menu).Delete, keysOf(s3Menu).Open,
+			withHelp(keysOf(s3Menu).Expand, "reread archive"),
+			when(keysOf(s3Menu).Retention, m.bucketLock.Enabled), when(keysOf(s3Menu).LegalHold, m.bucketLock.Enabled),
+		}
+		views := []key.Binding{keysOf(s3Menu).Bookmark, keysOf(s3Menu).Sync, keysOf(s3Menu).Compare}
+		return viewKeyMap{
+			short: append(nav[:4:4], actions[0], actions[2]),
+			full:  [][]key.Binding{nav, scrollKeys(keysOf(s3Menu), has), actions, views},
+		}
+	}
+
+	has := m.objectFilter.Len() != 0
+	marked := len(m.marked) != 0
+	object := m.atObject()
+	targets := len(m.targetKeys()) != 0
+	enter, del := withHelp(keysOf(s3Menu).Enter, "download"), keysOf(s3Menu).Delete
+	if marked {
+		enter, del = withHelp(keysOf(s3Menu).Enter, "download marked"), withHelp(keysOf(s3Menu).Delete, "delete marked")
+	}
+	nav := []key.Binding{
+		when(keysOf(s3Menu).Up, has), when(keysOf(s3Menu).Down, has),
+		withHelp(keysOf(s3Menu).Left, "up"), when(withHelp(keysOf(s3Menu).Right, "open"), has),
+		when(keysOf(s3Menu).Filter, m.ptr.IsDir),
+	}
+	marks := []key.Binding{when(keysOf(s3Menu).Mark, has), when(keysOf(s3Menu).SelectAll, has), when(keysOf(s3Menu).Invert, has)}
+	actions := []key.Binding{
+		when(enter, marked || object),
+		when(del, marked || object || (m.ptr.Marker && len(m.ptr.Children) == 0)),
+		withHelp(keysOf(s3Menu).Create, "upload"), keysOf(s3Menu).NewFolder, keysOf(s3Menu).SaveDir,
+		when(keysOf(s3Menu).Copy, targets), when(keysOf(s3Menu).Tag, targets), when(keysOf(s3Menu).StorageClass, targets),
+	}
+	details := []key.Binding{
+		when(keysOf(s3Menu).Open, object),
+		when(keysOf(s3Menu).Expand, object && s3.ArchiveFormatOf(m.objectKey()) != ""),
+		when(keysOf(s3Menu).Retention, object && m.bucketLock.Enabled), when(keysOf(s3Menu).LegalHold, object && m.bucketLock.Enabled),
+	}
+	views := []key.Binding{keysOf(s3Menu).Bookmark, keysOf(s3Menu).Sync, keysOf(s3Menu).Compare}
+	return viewKeyMap{
+		short: append(nav[:4:4], actions[0], actions[1], marks[0]),
+		full:  [][]key.Binding{nav, scrollKeys(keysOf(s3Menu), has), marks, actions, details, views},
+	}
+}
+
+// Commands contributes the S3 view's ":" commands
+func (m S3Menu) Commands() []Command {
+	send := func(name string) func(string) tea.Cmd {
+		return func(arg string) tea.Cmd {
+			return utils.SendMessage(s3CommandMessage{name: name, arg: arg})
+		}
+	}
+	return []Command{
+		{
+			Name:     "cd",
+			Usage:    "<prefix>",
+			Help:     "open a folder, relative to the current one unless it starts with /",
+			Complete: m.completeDir,
+			Run:      send("cd"),
+		},
+		{Name: "get", Help: "download the marked objects or the object under the cursor", Run: send("get")},
+		{Name: "refresh", Help: "list the buckets or the current folder again", Run: send("refresh")},
+	}
+}
+
+// resolveDir turns a ":cd" argument into a folder path
+func (m S3Menu) resolveDir(arg string) string {
+	if !strings.HasPrefix(arg, "/") {
+		arg = path.Join("/", m.currentDir(), arg)
+	}
+	return strings.Trim(path.Clean(arg), "/")
+}
+
+// completeDir offers the sub folders of the folder typed so far
+func (m S3Menu) completeDir(arg string) []string {
+	if !m.viewObjects {
+		return nil
+	}
+	typed := arg[:strings.LastIndex(arg, "/")+1]
+	node, found := m.fileTree.Find(m.resolveDir(typed))
+	if !found {
+		return nil
+	}
+	var dirs []string
+	for _, child := range node.Children {
+		if child.IsDir {
+			dirs = append(dirs, typed+child.Value+"/")
+		}
+	}
+	return dirs
+}
+
+func (m *S3Menu) runCommand(msg s3CommandMessage) tea.Cmd {
+	switch msg.name {
+	case "cd":
+		if !m.viewObjects {
+			return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("open a bucket before :cd")})
+		}
+		dir := m.resolveDir(msg.arg)
+		if node, found := m.fileTree.Find(dir); found {
+			m.paneFocus = 1
+			m.setPtr(node)
+			return nil
+		}
+		// only part of the bucket is listed, so the folder may still exist
+		var cmd tea.Cmd
+		*m, cmd = m.goTo(m.selectedBucket, dir)
+		return cmd
+
+	case "get":
+		switch {
+		case len(m.marked) != 0:
+			return m.downloadMarked()
+		case m.paneFocus == 0:
+		case m.atObject():
+			return m.queueDownload(m.objectKey())
+		case m.ptr.Member && !m.ptr.IsDir:
+			return m.queueExtract(m.ptr)
+		case m.objectFilter.Len() != 0:
+			child := m.ptr.Children[m.selected]
+			if child.Member && !child.IsDir {
+				return m.queueExtract(child)
+			}
+			if !child.IsDir {
+				return m.queueDownload(child.Key())
+			}
+		}
+		return utils.SendMessage(internal.APIMessage{Err: fmt.Errorf("no object to download")})
+
+	case "refresh":
+		if m.paneFocus == 0 || !m.viewObjects {
+			return m.s3Client.ListBuckets(context.Background(), &s3aws.ListBucketsInput{})
+		}
+		var cmd tea.Cmd
+		*m, cmd = m.goTo(m.selectedBucket, m.currentDir())
+		return cmd
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/s3"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCopyTargets(t *testing.T) {
+	tree := internal.CreateTree([]string{"docs/a.txt", "docs/b/c.txt", "other/d.txt"})
+	docs, _ := tree.Find("docs")
+	m := S3Menu{fileTree: tree, ptr: docs}
+	keys := []string{"docs/a.txt", "docs/b/c.txt", "other/d.txt"}
+
+	targets, same := m.copyTargets("backup/", keys)
+	assert.Equal(t, map[string]string{
+		"docs/a.txt":   "backup/a.txt",
+		"docs/b/c.txt": "backup/b/c.txt",
+		"other/d.txt":  "backup/d.txt",
+	}, targets)
+	assert.Empty(t, same)
+
+	targets, same = m.copyTargets("docs", keys)
+	assert.Equal(t, map[string]string{"other/d.txt": "docs/d.txt"}, targets)
+	assert.Equal(t, []string{"docs/a.txt", "docs/b/c.txt"}, same, "copying onto themselves is left out")
+}
+
+func TestS3MenuPromptBackspace(t *testing.T) {
+	m := S3Menu{input: textinput.New(), paneFocus: 1, pendingUpload: "/tmp/site.css", inputAction: inputContentType}
+	m.input.SetValue("text/css")
+	m.input.Focus()
+	backspace := tea.KeyMsg{Type: tea.KeyBackspace}
+
+	model, _ := m.Update(backspace)
+	m = model.(S3Menu)
+	assert.True(t, m.input.Focused(), "backspace edits a filled prompt")
+	assert.Equal(t, "text/cs", m.input.Value())
+	assert.Equal(t, "/tmp/site.css", m.pendingUpload)
+
+	m.input.SetValue("")
+	model, _ = m.Update(backspace)
+	m = model.(S3Menu)
+	assert.False(t, m.input.Focused(), "backspace on an empty prompt cancels it")
+	assert.Empty(t, m.pendingUpload)
+}
+
+func TestQueueDownloadUsesListedSize(t *testing.T) {
+	// a window that is closed now holds every job back, so none of them runs
+	now := time.Now()
+	window, err := transfer.ParseWindow(now.Add(2*time.Hour).Format("15:04") + "-" + now.Add(3*time.Hour).Format("15:04"))
+	assert.NoError(t, err)
+	manager := transfer.NewManager(1)
+	manager.SetSchedule(&window, 1)
+	previous := TransferManager
+	TransferManager = manager
+	t.Cleanup(func() { TransferManager = previous })
+
+	m := S3Menu{
+		selectedBucket: "bucket",
+		sizes:          map[string]int64{"small.txt": 10, "large.iso": 4 << 20},
+		objectMetadata: s3.S3ObjectMetadata{ContentLength: 1},
+	}
+	for _, key := range []string{"small.txt", "large.iso"} {
+		msg, ok := m.queueDownload(key)().(internal.APIMessage)
+		assert.True(t, ok)
+		assert.Equal(t, "Queued download of bucket/"+key, msg.Status)
+	}
+
+	jobs := manager.Jobs()
+	if assert.Len(t, jobs, 2) {
+		assert.Equal(t, int64(10), jobs[0].Total, "the size of the listed key, not of the last opened object")
+		assert.Equal(t, int64(4<<20), jobs[1].Total)
+		assert.Equal(t, transfer.Scheduled, jobs[1].State)
+	}
+	for _, job := range jobs {
+		manager.Cancel(job.ID)
+	}
+}
+
+func TestObjectDetailsNameBoundKeys(t *testing.T) {
+	applyKeys(t, KeyConfig{"s3": {"open": {"O"}, "expand": {"X"}, "retention": {"T"}}})
+	theme, _ := Config{}.LoadTheme("")
+	root := &internal.TreeNode{IsDir: true}
+	m := S3Menu{
+		theme:       &theme,
+		ptr:         &internal.TreeNode{Value: "logs.zip", Parent: root},
+		breadcrumbs: []string{"bucket", "logs.zip"},
+		bucketLock:  s3.BucketObjectLock{Enabled: true},
+	}
+
+	details := m.objectDetails()
+	assert.Contains(t, details, "Press [O] to open it")
+	assert.Contains(t, details, "Press [X] to browse the archive")
+	assert.Contains(t, details, "[T] set retention")
+	assert.Contains(t, details, "["+keysOf(s3Menu).LegalHold.Help().Key+"] toggle legal hold")
+}
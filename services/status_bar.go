@@ -1,21 +1,44 @@
 package services
 
 import (
+	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/Aearsears/fuzzy-guacamole/internal"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
+// maxLogEntries is how many status messages the message log keeps
+const maxLogEntries = 500
+
+// maxSticky is how many different errors wait to be dismissed. Older ones
+// are dropped from the status bar but stay in the message log
+const maxSticky = 10
+
+// maxQueued is how many other messages wait for their turn. Older ones are
+// dropped from the status bar but stay in the message log
+const maxQueued = 5
+
 type StatusBarTimeoutMessage struct{}
 
+// stickyError is an error waiting to be dismissed
+type stickyError struct {
+	entry internal.LogEntry
+	count int // times it was sent while waiting
+}
+
 type StatusBar struct {
-	err          error
-	display_text string
+	current      internal.LogEntry
 	display      bool
-	loading      bool
 	timeout      int
-	messageQueue []internal.APIMessage
+	messageQueue []internal.LogEntry
+	sticky       []stickyError // errors waiting to be dismissed, oldest first
+	log          *internal.MessageLog
+	state        SessionState // view whose keys the hints name
+	theme        *Theme
 }
 
 func statusBarTimeout(seconds int) tea.Cmd {
@@ -24,10 +47,11 @@ func statusBarTimeout(seconds int) tea.Cmd {
 	})
 }
 
-func InitStatusBar() StatusBar {
+func InitStatusBar(theme *Theme) StatusBar {
 	return StatusBar{
+		theme:   theme,
 		timeout: 3,
-		loading: false,
+		log:     internal.NewMessageLog(maxLogEntries),
 	}
 }
 
@@ -35,36 +59,68 @@ func (m StatusBar) Init() tea.Cmd {
 	return nil
 }
 
+// Log is every message the status bar was sent, shared with the message log view
+func (m StatusBar) Log() *internal.MessageLog {
+	return m.log
+}
+
+// Sticky reports whether an error is waiting to be dismissed
+func (m StatusBar) Sticky() bool {
+	return len(m.sticky) != 0
+}
+
+// Dismiss acknowledges the oldest error, showing the next one if any
+func (m StatusBar) Dismiss() StatusBar {
+	if len(m.sticky) != 0 {
+		m.sticky = m.sticky[1:]
+	}
+	return m
+}
+
+// For returns the status bar as shown over the view state
+func (m StatusBar) For(state SessionState) StatusBar {
+	m.state = state
+	return m
+}
+
 func (m *StatusBar) showNextMessage() (StatusBar, tea.Cmd) {
 	if len(m.messageQueue) == 0 {
 		m.display = false
 		return *m, nil
 	}
-	msg := m.messageQueue[0]
+	m.current = m.messageQueue[0]
 	m.messageQueue = m.messageQueue[1:]
 	m.display = true
-	if msg.Status != "" {
-		m.display_text = msg.Status
-		m.loading = true
-	} else if msg.Err != nil {
-		m.err = msg.Err
-		m.display_text = m.err.Error()
-		m.loading = false
-	} else if msg.Response != "" {
-		if response, ok := msg.Response.(string); ok {
-			m.display_text = response
-		} else {
-			m.display_text = "Invalid response type"
-		}
-		m.loading = false
-	}
 	return *m, statusBarTimeout(m.timeout)
 }
 
 func (m StatusBar) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case internal.APIMessage:
-		m.messageQueue = append(m.messageQueue, msg)
+		entry := m.log.Add(msg, time.Now())
+		if entry.Severity == internal.SeverityError {
+			// errors stay up until dismissed instead of timing out. One sent
+			// again while it waits is counted rather than queued twice
+			m.sticky = slices.Clone(m.sticky)
+			if i := slices.IndexFunc(m.sticky, func(s stickyError) bool { return s.entry.Text == entry.Text }); i >= 0 {
+				m.sticky[i].count++
+				return m, nil
+			}
+			m.sticky = append(m.sticky, stickyError{entry: entry, count: 1})
+			if len(m.sticky) > maxSticky {
+				m.sticky = m.sticky[len(m.sticky)-maxSticky:]
+			}
+			return m, nil
+		}
+		// a message already up or waiting is not shown again
+		sameText := func(e internal.LogEntry) bool { return e.Text == entry.Text }
+		if m.display && sameText(m.current) || slices.ContainsFunc(m.messageQueue, sameText) {
+			return m, nil
+		}
+		m.messageQueue = append(slices.Clip(m.messageQueue), entry)
+		if len(m.messageQueue) > maxQueued {
+			m.messageQueue = m.messageQueue[len(m.messageQueue)-maxQueued:]
+		}
 		if !m.display {
 			return m.showNextMessage()
 		}
@@ -77,14 +133,26 @@ func (m StatusBar) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 }
 
 func (m StatusBar) View() string {
-	if m.display {
-		if m.loading {
-			return StatusBarStyle(m.display_text)
-		} else if m.err != nil {
-			return StatusBarErrorStyle(m.err.Error())
-		} else {
-			return StatusBarSuccessStyle(m.display_text)
+	width := windowWidth()
+	if len(m.sticky) != 0 {
+		k := keysOf(m.state)
+		hint := fmt.Sprintf("  [%s dismiss, %s messages]", k.Dismiss.Help().Key, k.Messages.Help().Key)
+		if more := len(m.sticky) - 1; more > 0 {
+			hint = fmt.Sprintf("  +%d more%s", more, hint)
+		}
+		if count := m.sticky[0].count; count > 1 {
+			hint = fmt.Sprintf("  (%d times)%s", count, hint)
 		}
+		text := clip(firstLine(m.sticky[0].entry.Text), width-lipgloss.Width(hint))
+		return m.theme.StatusError.Render(text) + m.theme.Help.Render(hint)
+	}
+	if m.display {
+		return m.theme.Severity(m.current.Severity).Render(clip(firstLine(m.current.Text), width))
 	}
 	return ""
 }
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/stretchr/testify/assert"
+)
+
+// send passes msg to the status bar
+func send(t *testing.T, m StatusBar, msg internal.APIMessage) StatusBar {
+	t.Helper()
+	model, _ := m.Update(msg)
+	bar, ok := model.(StatusBar)
+	assert.True(t, ok)
+	return bar
+}
+
+func TestStatusBarStickyErrors(t *testing.T) {
+	theme, _ := Config{}.LoadTheme("")
+	WindowSize.Width = 120
+	bar := InitStatusBar(&theme)
+
+	for range 3 {
+		bar = send(t, bar, internal.APIMessage{Err: errors.New("access denied")})
+	}
+	bar = send(t, bar, internal.APIMessage{Err: errors.New("no such bucket")})
+	assert.Len(t, bar.sticky, 2, "a repeated error is counted, not queued")
+	assert.Contains(t, bar.View(), "access denied")
+	assert.Contains(t, bar.View(), "(3 times)")
+	assert.Contains(t, bar.View(), "+1 more")
+	assert.Equal(t, 4, bar.Log().Len(), "every error is logged")
+
+	bar = bar.Dismiss()
+	assert.Contains(t, bar.View(), "no such bucket")
+	assert.NotContains(t, bar.View(), "times")
+	bar = bar.Dismiss()
+	assert.False(t, bar.Sticky())
+
+	for i := range maxSticky + 5 {
+		bar = send(t, bar, internal.APIMessage{Err: fmt.Errorf("error %d", i)})
+	}
+	assert.Len(t, bar.sticky, maxSticky)
+	assert.Contains(t, bar.View(), "error 5", "the oldest errors are dropped")
+}
+
+func TestStatusBarKeepsCopiesApart(t *testing.T) {
+	theme, _ := Config{}.LoadTheme("")
+	bar := send(t, InitStatusBar(&theme), internal.APIMessage{Err: errors.New("boom")})
+	again := send(t, bar, internal.APIMessage{Err: errors.New("boom")})
+	assert.Equal(t, 1, bar.sticky[0].count)
+	assert.Equal(t, 2, again.sticky[0].count)
+}
+
+func TestStatusBarQueue(t *testing.T) {
+	theme, _ := Config{}.LoadTheme("")
+	bar := InitStatusBar(&theme)
+
+	for range 3 {
+		bar = send(t, bar, internal.APIMessage{Status: "saved"})
+	}
+	assert.Equal(t, "saved", bar.current.Text)
+	assert.Empty(t, bar.messageQueue, "a message already up is not queued again")
+
+	for i := range maxQueued + 5 {
+		bar = send(t, bar, internal.APIMessage{Status: fmt.Sprintf("message %d", i)})
+		bar = send(t, bar, internal.APIMessage{Status: fmt.Sprintf("message %d", i)})
+	}
+	assert.Len(t, bar.messageQueue, maxQueued)
+	assert.Equal(t, "message 5", bar.messageQueue[0].Text, "the oldest messages are dropped")
+	assert.Equal(t, 3+2*(maxQueued+5), bar.Log().Len(), "every message is logged")
+}
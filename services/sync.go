@@ -0,0 +1,406 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/s3"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// OpenSyncMessage asks the TUI to open the sync view for a bucket prefix
+type OpenSyncMessage struct {
+	client s3.S3API
+	bucket string
+	prefix string
+}
+
+// fields of the sync form, in display order
+const (
+	syncFieldLocalDir = iota
+	syncFieldPrefix
+	syncFieldDirection
+	syncFieldDelete
+	syncFieldPlan
+)
+
+var syncFieldNames = []string{"Local directory", "Bucket prefix", "Direction", "Delete policy", "Compute plan"}
+
+type SyncMenu struct {
+	s3Client    s3.S3API
+	bucket      string
+	prefix      string
+	localDir    string
+	options     internal.SyncOptions
+	plan        []internal.SyncAction
+	reviewing   bool // false = editing the form, true = reviewing the plan
+	confirming  bool
+	cursor      int
+	editField   int
+	input       textinput.Model
+	loading     bool
+	spinner     spinner.Model
+	lastSummary string
+	theme       *Theme
+}
+
+// InitSyncMenu opens the sync form. Uploads are compressed by the rules of
+// the config, like any other upload
+func InitSyncMenu(client s3.S3API, bucket, prefix string, compression []internal.CompressionRule, theme *Theme) SyncMenu {
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.CharLimit = 250
+	input.Width = 50
+
+	return SyncMenu{
+		theme:    theme,
+		s3Client: client,
+		bucket:   bucket,
+		prefix:   prefix,
+		localDir: ".",
+		options: internal.SyncOptions{
+			Direction:   internal.SyncUp,
+			Delete:      internal.SyncKeepExtraneous,
+			Compression: compression,
+		},
+		input:   input,
+		spinner: CreateSpinner(),
+	}
+}
+
+func (m SyncMenu) Init() tea.Cmd {
+	return nil
+}
+
+func (m SyncMenu) Typing() bool {
+	return m.input.Focused()
+}
+
+func (m SyncMenu) KeyMap() help.KeyMap {
+	if m.input.Focused() {
+		return promptKeys(syncMenu)
+	}
+	var keys []key.Binding
+	switch {
+	case m.loading:
+	case m.confirming:
+		keys = []key.Binding{key.NewBinding(key.WithKeys("y"), key.WithHelp("y", "execute")), withHelp(keysOf(syncMenu).Backspace, "cancel")}
+	case m.reviewing:
+		has := len(m.plan) != 0
+		keys = []key.Binding{
+			when(keysOf(syncMenu).Up, has), when(keysOf(syncMenu).Down, has),
+			when(withHelp(keysOf(syncMenu).Enter, "execute plan"), has),
+			withHelp(keysOf(syncMenu).Backspace, "back to options"),
+		}
+		return viewKeyMap{short: keys, full: [][]key.Binding{keys, scrollKeys(keysOf(syncMenu), has)}}
+	default:
+		keys = []key.Binding{keysOf(syncMenu).Up, keysOf(syncMenu).Down, withHelp(keysOf(syncMenu).Enter, "edit")}
+	}
+	return viewKeyMap{short: keys, full: [][]key.Binding{keys}}
+}
+
+// Location returns the bucket prefix being synced, followed by "plan" while
+// the plan is reviewed
+func (m SyncMenu) Location() string {
+	place := path.Join(m.bucket, s3.CleanSyncPrefix(m.prefix))
+	if m.reviewing {
+		place += "/plan"
+	}
+	return place
+}
+
+// Restore goes back to the form, or to the plan while it has not been
+// queued. The view syncs one prefix, so other places leave it as it is
+func (m SyncMenu) Restore(place string) (tea.Model, tea.Cmd) {
+	if m.loading {
+		return m, nil
+	}
+	form := path.Join(m.bucket, s3.CleanSyncPrefix(m.prefix))
+	switch {
+	case place == form && m.reviewing:
+		m.reviewing, m.confirming = false, false
+		m.cursor = syncFieldPlan
+	case place == form+"/plan" && !m.reviewing && m.plan != nil:
+		m.reviewing = true
+		m.cursor = 0
+	}
+	return m, nil
+}
+
+func (m SyncMenu) syncInput() s3.SyncInput {
+	return s3.SyncInput{
+		Bucket:   m.bucket,
+		Prefix:   s3.CleanSyncPrefix(m.prefix),
+		LocalDir: m.localDir,
+		Options:  m.options,
+	}
+}
+
+func (m SyncMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	var cmd tea.Cmd
+	var cmds []tea.Cmd
+	if m.loading {
+		m.spinner, cmd = m.spinner.Update(msg)
+		cmds = append(cmds, cmd)
+	}
+
+	switch msg := msg.(type) {
+	case s3.S3MenuMessage:
+		switch msg.Op {
+		case s3.S3OpPlanSync:
+			m.loading = false
+			if msg.APIMessage.Err != nil {
+				cmds = append(cmds, utils.SendMessage(internal.APIMessage{Err: msg.APIMessage.Err}))
+				break
+			}
+			m.plan = msg.SyncPlan
+			m.reviewing = true
+			m.cursor = 0
+			cmds = append(cmds, utils.SendMessage(internal.APIMessage{Status: msg.APIMessage.Status, Severity: msg.APIMessage.Severity}))
+		}
+
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			if key.Matches(msg, keysOf(syncMenu).Enter) {
+				m.applyInput()
+				m.input.SetValue("")
+				m.input.Blur()
+				return m, nil
+			}
+			if key.Matches(msg, keysOf(syncMenu).Backspace) && m.input.Value() == "" {
+				m.input.Blur()
+				return m, nil
+			}
+			m.input, cmd = m.input.Update(msg)
+			cmds = append(cmds, cmd)
+			break
+		}
+		if m.loading {
+			break
+		}
+
+		if m.confirming {
+			if msg.String() == "y" {
+				status := m.queuePlan()
+				m.confirming = false
+				m.lastSummary = status.Status
+				m.reviewing = false
+				m.plan = nil
+				m.cursor = syncFieldPlan
+				cmds = append(cmds, utils.SendMessage(status))
+			} else {
+				m.confirming = false
+			}
+			break
+		}
+
+		if m.reviewing {
+			if cursor, ok := scrollKey(keysOf(syncMenu), msg, m.cursor, len(m.plan), m.rows()); ok {
+				m.cursor = cursor
+				break
+			}
+			switch {
+			case key.Matches(msg, keysOf(syncMenu).Up):
+				if m.cursor > 0 {
+					m.cursor--
+				}
+			case key.Matches(msg, keysOf(syncMenu).Down):
+				if m.cursor < len(m.plan)-1 {
+					m.cursor++
+				}
+			case key.Matches(msg, keysOf(syncMenu).Enter):
+				if len(m.plan) != 0 {
+					m.confirming = true
+				}
+			case key.Matches(msg, keysOf(syncMenu).Backspace):
+				m.reviewing = false
+				m.cursor = syncFieldPlan
+			}
+			break
+		}
+
+		switch {
+		case key.Matches(msg, keysOf(syncMenu).Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case key.Matches(msg, keysOf(syncMenu).Down):
+			if m.cursor < syncFieldPlan {
+				m.cursor++
+			}
+		case key.Matches(msg, keysOf(syncMenu).Enter):
+			cmds = append(cmds, m.selectField())
+		}
+	}
+
+	return m, tea.Batch(cmds...)
+}
+
+// queuePlan hands every action of the plan over to the transfer manager,
+// which bounds, throttles and schedules them like any other transfer
+func (m SyncMenu) queuePlan() internal.APIMessage {
+	input := m.syncInput()
+	queued, conflicts := 0, 0
+	for _, action := range m.plan {
+		kind, size := transfer.Delete, int64(0)
+		switch action.Type {
+		case internal.SyncConflict:
+			conflicts++
+			continue
+		case internal.SyncUpload:
+			kind, size = transfer.Upload, action.Size
+		case internal.SyncDownload:
+			kind, size = transfer.Download, action.Size
+		}
+		TransferManager.Enqueue(kind, input.JobName(action), size, m.s3Client.SyncJob(input, action))
+		queued++
+	}
+
+	status := internal.APIMessage{
+		Status: fmt.Sprintf("Queued %d sync actions for %s/%s, follow them in transfers", queued, input.Bucket, input.Prefix),
+	}
+	if conflicts != 0 {
+		status.Status += fmt.Sprintf(", %d conflicts left alone", conflicts)
+		status.Severity = internal.SeverityWarn
+	}
+	return status
+}
+
+// selectField edits text fields, cycles option fields and starts the plan
+func (m *SyncMenu) selectField() tea.Cmd {
+	switch m.cursor {
+	case syncFieldLocalDir, syncFieldPrefix:
+		m.editField = m.cursor
+		m.input.Placeholder = fmt.Sprintf("Enter %s...", strings.ToLower(syncFieldNames[m.cursor]))
+		m.input.SetValue(m.fieldValue(m.cursor))
+		m.input.Focus()
+		return textinput.Blink
+	case syncFieldDirection:
+		m.options.Direction = (m.options.Direction + 1) % 3
+	case syncFieldDelete:
+		m.options.Delete = (m.options.Delete + 1) % 2
+	case syncFieldPlan:
+		m.loading = true
+		m.lastSummary = ""
+		return tea.Batch(m.spinner.Tick,
+			m.s3Client.PlanSync(context.Background(), m.syncInput()),
+			utils.SendMessage(internal.APIMessage{Status: "Computing sync plan..."}))
+	}
+	return nil
+}
+
+func (m *SyncMenu) applyInput() {
+	value := strings.TrimSpace(m.input.Value())
+	switch m.editField {
+	case syncFieldLocalDir:
+		if value != "" {
+			m.localDir = value
+		}
+	case syncFieldPrefix:
+		m.prefix = value
+	}
+}
+
+func (m SyncMenu) fieldValue(field int) string {
+	switch field {
+	case syncFieldLocalDir:
+		return m.localDir
+	case syncFieldPrefix:
+		return m.prefix
+	case syncFieldDirection:
+		return m.options.Direction.String()
+	case syncFieldDelete:
+		return m.options.Delete.String()
+	}
+	return ""
+}
+
+// rows is the number of plan actions that fit below the title and the counts,
+// leaving room for the confirmation
+func (m SyncMenu) rows() int {
+	return listRows(6)
+}
+
+func (m SyncMenu) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.Header.Render(fmt.Sprintf("Sync: %s/%s", m.bucket, s3.CleanSyncPrefix(m.prefix))) + "\n\n")
+
+	if m.loading {
+		b.WriteString(m.theme.Doc.Render(fmt.Sprintf("%s Working...\n", m.theme.Spinner.Render(m.spinner.View()))))
+	} else if m.reviewing {
+		m.viewPlan(&b)
+	} else {
+		for i, name := range syncFieldNames {
+			cursor := " "
+			display := name
+			if i != syncFieldPlan {
+				display = fmt.Sprintf("%-16s %s", name+":", m.fieldValue(i))
+			}
+			if i == m.cursor {
+				cursor = m.theme.Cursor.Render(">")
+				display = m.theme.Selected.Render(display)
+			} else {
+				display = m.theme.Choice.Render(display)
+			}
+			b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+		}
+		if m.lastSummary != "" {
+			b.WriteString("\n" + m.theme.Alert.Render(m.lastSummary) + "\n")
+		}
+	}
+
+	menu := m.theme.Border.Render(b.String())
+	if m.input.Focused() {
+		menu += "\n" + m.input.View()
+	}
+	return menu
+}
+
+func (m SyncMenu) viewPlan(b *strings.Builder) {
+	if len(m.plan) == 0 {
+		b.WriteString(m.theme.Doc.Render("Everything is in sync.\n"))
+		return
+	}
+
+	counts := make(map[internal.SyncActionType]int)
+	for _, action := range m.plan {
+		counts[action.Type]++
+	}
+	start, end := scrollWindow(m.cursor, len(m.plan), m.rows())
+	b.WriteString(fmt.Sprintf("%d uploads, %d downloads, %d local deletes, %d remote deletes",
+		counts[internal.SyncUpload], counts[internal.SyncDownload],
+		counts[internal.SyncDeleteLocal], counts[internal.SyncDeleteRemote]))
+	if n := counts[internal.SyncConflict]; n != 0 {
+		b.WriteString(", " + m.theme.StatusWarn.Render(fmt.Sprintf("%d conflicts left alone", n)))
+	}
+	if position := scrollPosition(start, end, len(m.plan)); position != "" {
+		b.WriteString("  " + m.theme.Help.Render(position))
+	}
+	b.WriteString("\n\n")
+
+	for i := start; i < end; i++ {
+		action := m.plan[i]
+		cursor := " "
+		display := clip(fmt.Sprintf("%-13s %s (%d bytes, %s)", action.Type, action.Path, action.Size, action.Reason), innerWidth(windowWidth()))
+		if i == m.cursor {
+			cursor = m.theme.Cursor.Render(">")
+			display = m.theme.Selected.Render(display)
+		} else {
+			display = m.theme.Choice.Render(display)
+		}
+		b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+	}
+
+	if m.confirming {
+		b.WriteString("\n" + m.theme.Alert.Render(fmt.Sprintf("Queue %d actions as transfers? [y/n]", len(m.plan))) + "\n")
+	}
+}
@@ -0,0 +1,334 @@
+package services
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/lipgloss"
+	"gopkg.in/yaml.v3"
+)
+
+// names of the built-in themes
+const (
+	themeAuto         = "auto"
+	themeDark         = "dark"
+	themeLight        = "light"
+	themeHighContrast = "high-contrast"
+	themeNone         = "none"
+)
+
+// ThemeColor is a color such as "#7D56F4" or "205", or a pair picked by the
+// terminal background: {light: "#303030", dark: "#d0d0d0"}
+type ThemeColor struct {
+	Light string `yaml:"light"`
+	Dark  string `yaml:"dark"`
+}
+
+func (c *ThemeColor) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		c.Light, c.Dark = value.Value, value.Value
+		return nil
+	}
+	type pair ThemeColor
+	return value.Decode((*pair)(c))
+}
+
+func (c ThemeColor) empty() bool {
+	return c.Light == "" && c.Dark == ""
+}
+
+func (c ThemeColor) color() lipgloss.TerminalColor {
+	if c.empty() {
+		return lipgloss.NoColor{}
+	}
+	return lipgloss.AdaptiveColor{Light: c.Light, Dark: c.Dark}
+}
+
+// ThemeColors are the colors a theme is built from
+type ThemeColors struct {
+	Text      ThemeColor `yaml:"text"`
+	Muted     ThemeColor `yaml:"muted"`
+	Accent    ThemeColor `yaml:"accent"`
+	HeaderBg  ThemeColor `yaml:"header_background"`
+	Cursor    ThemeColor `yaml:"cursor"`
+	Selected  ThemeColor `yaml:"selected"`
+	Border    ThemeColor `yaml:"border"`
+	Info      ThemeColor `yaml:"info"`
+	Success   ThemeColor `yaml:"success"`
+	Warn      ThemeColor `yaml:"warn"`
+	Error     ThemeColor `yaml:"error"`
+	Match     ThemeColor `yaml:"match"`
+	Spinner   ThemeColor `yaml:"spinner"`
+	StatusBar ThemeColor `yaml:"status_bar"`
+}
+
+// over fills the colors c leaves out from base
+func (c ThemeColors) over(base ThemeColors) ThemeColors {
+	pick := func(own, fallback ThemeColor) ThemeColor {
+		if own.empty() {
+			return fallback
+		}
+		return own
+	}
+	return ThemeColors{
+		Text:      pick(c.Text, base.Text),
+		Muted:     pick(c.Muted, base.Muted),
+		Accent:    pick(c.Accent, base.Accent),
+		HeaderBg:  pick(c.HeaderBg, base.HeaderBg),
+		Cursor:    pick(c.Cursor, base.Cursor),
+		Selected:  pick(c.Selected, base.Selected),
+		Border:    pick(c.Border, base.Border),
+		Info:      pick(c.Info, base.Info),
+		Success:   pick(c.Success, base.Success),
+		Warn:      pick(c.Warn, base.Warn),
+		Error:     pick(c.Error, base.Error),
+		Match:     pick(c.Match, base.Match),
+		Spinner:   pick(c.Spinner, base.Spinner),
+		StatusBar: pick(c.StatusBar, base.StatusBar),
+	}
+}
+
+// adapt takes the light colors of light and the dark colors of dark
+func adapt(light, dark ThemeColors) ThemeColors {
+	pair := func(l, d ThemeColor) ThemeColor {
+		return ThemeColor{Light: l.Light, Dark: d.Dark}
+	}
+	return ThemeColors{
+		Text:      pair(light.Text, dark.Text),
+		Muted:     pair(light.Muted, dark.Muted),
+		Accent:    pair(light.Accent, dark.Accent),
+		HeaderBg:  pair(light.HeaderBg, dark.HeaderBg),
+		Cursor:    pair(light.Cursor, dark.Cursor),
+		Selected:  pair(light.Selected, dark.Selected),
+		Border:    pair(light.Border, dark.Border),
+		Info:      pair(light.Info, dark.Info),
+		Success:   pair(light.Success, dark.Success),
+		Warn:      pair(light.Warn, dark.Warn),
+		Error:     pair(light.Error, dark.Error),
+		Match:     pair(light.Match, dark.Match),
+		Spinner:   pair(light.Spinner, dark.Spinner),
+		StatusBar: pair(light.StatusBar, dark.StatusBar),
+	}
+}
+
+// same uses one color on every background
+func same(color string) ThemeColor {
+	return ThemeColor{Light: color, Dark: color}
+}
+
+var darkColors = ThemeColors{
+	Text:      same("7"),
+	Muted:     same("241"),
+	Accent:    same("#7D56F4"),
+	HeaderBg:  same("#1a1a1a"),
+	Cursor:    same("12"),
+	Selected:  same("10"),
+	Border:    same("#5A5A5A"),
+	Info:      same("62"),
+	Success:   same("#5FAF5F"),
+	Warn:      same("#D7AF5F"),
+	Error:     same("#bd534b"),
+	Match:     same("212"),
+	Spinner:   same("205"),
+	StatusBar: same("#8A8A8A"),
+}
+
+var lightColors = ThemeColors{
+	Text:      same("#303030"),
+	Muted:     same("#6C6C6C"),
+	Accent:    same("#5A3FC0"),
+	HeaderBg:  same("#EDEDED"),
+	Cursor:    same("#0057B8"),
+	Selected:  same("#006400"),
+	Border:    same("#A0A0A0"),
+	Info:      same("#3A3AA0"),
+	Success:   same("#0F4D0F"),
+	Warn:      same("#8A5A00"),
+	Error:     same("#A4262C"),
+	Match:     same("#C2185B"),
+	Spinner:   same("#C2185B"),
+	StatusBar: same("#5A5A5A"),
+}
+
+var highContrastColors = ThemeColors{
+	Text:      ThemeColor{Light: "#000000", Dark: "#FFFFFF"},
+	Muted:     ThemeColor{Light: "#000000", Dark: "#FFFFFF"},
+	Accent:    ThemeColor{Light: "#0000AA", Dark: "#FFFF00"},
+	HeaderBg:  ThemeColor{Light: "#FFFFFF", Dark: "#000000"},
+	Cursor:    ThemeColor{Light: "#0000FF", Dark: "#00FFFF"},
+	Selected:  ThemeColor{Light: "#0000AA", Dark: "#FFFF00"},
+	Border:    ThemeColor{Light: "#000000", Dark: "#FFFFFF"},
+	Info:      ThemeColor{Light: "#0000FF", Dark: "#00FFFF"},
+	Success:   ThemeColor{Light: "#006400", Dark: "#00FF00"},
+	Warn:      ThemeColor{Light: "#8A4B00", Dark: "#FFAF00"},
+	Error:     ThemeColor{Light: "#B00000", Dark: "#FF5555"},
+	Match:     ThemeColor{Light: "#B00000", Dark: "#FF00FF"},
+	Spinner:   ThemeColor{Light: "#0000AA", Dark: "#FFFF00"},
+	StatusBar: ThemeColor{Light: "#000000", Dark: "#FFFFFF"},
+}
+
+// builtinThemes in the order the switcher cycles through them. auto follows
+// the terminal background
+var builtinThemes = []struct {
+	name   string
+	colors ThemeColors
+}{
+	{themeAuto, adapt(lightColors, darkColors)},
+	{themeDark, darkColors},
+	{themeLight, lightColors},
+	{themeHighContrast, highContrastColors},
+	{themeNone, ThemeColors{}},
+}
+
+// ThemeConfig is a custom theme. Colors it leaves out come from its base, a
+// built-in theme, auto by default
+type ThemeConfig struct {
+	Name   string      `yaml:"name"`
+	Base   string      `yaml:"base"`
+	Colors ThemeColors `yaml:"colors"`
+}
+
+// Theme holds the styles every view renders with. Views share a pointer to
+// it, so switching themes restyles them all
+type Theme struct {
+	Name          string
+	Doc           lipgloss.Style
+	Help          lipgloss.Style
+	Err           lipgloss.Style
+	Alert         lipgloss.Style
+	Header        lipgloss.Style
+	Choice        lipgloss.Style
+	Cursor        lipgloss.Style
+	Selected      lipgloss.Style
+	Border        lipgloss.Style
+	Match         lipgloss.Style
+	Spinner       lipgloss.Style
+	Status        lipgloss.Style
+	StatusSuccess lipgloss.Style
+	StatusWarn    lipgloss.Style
+	StatusError   lipgloss.Style
+	muted         lipgloss.TerminalColor
+	text          lipgloss.TerminalColor
+}
+
+// NewTheme builds the styles of a theme from its colors. Without colors the
+// styles fall back to bold, italics and reverse video
+func NewTheme(name string, c ThemeColors) Theme {
+	t := Theme{
+		Name: name,
+		Doc:  lipgloss.NewStyle().Margin(0, 2),
+		Help: lipgloss.NewStyle().Foreground(c.Muted.color()),
+		Err:  lipgloss.NewStyle().Foreground(c.Error.color()),
+		Alert: lipgloss.NewStyle().
+			Foreground(c.Info.color()),
+		Header: lipgloss.NewStyle().
+			Foreground(c.Accent.color()).
+			Background(c.HeaderBg.color()).
+			Bold(true).
+			PaddingLeft(1),
+		Choice:   lipgloss.NewStyle().Foreground(c.Text.color()),
+		Cursor:   lipgloss.NewStyle().Foreground(c.Cursor.color()),
+		Selected: lipgloss.NewStyle().Foreground(c.Selected.color()).Italic(true),
+		Border: lipgloss.NewStyle().
+			Border(lipgloss.RoundedBorder()).
+			BorderForeground(c.Border.color()),
+		Match:         lipgloss.NewStyle().Foreground(c.Match.color()).Bold(true),
+		Spinner:       lipgloss.NewStyle().Foreground(c.Spinner.color()),
+		Status:        lipgloss.NewStyle().Foreground(c.StatusBar.color()),
+		StatusSuccess: lipgloss.NewStyle().Foreground(c.Success.color()),
+		StatusWarn:    lipgloss.NewStyle().Foreground(c.Warn.color()),
+		StatusError:   lipgloss.NewStyle().Foreground(c.Error.color()),
+		muted:         c.Muted.color(),
+		text:          c.Text.color(),
+	}
+	if c.Selected.empty() {
+		t.Selected = t.Selected.Reverse(true)
+		t.Match = t.Match.Underline(true)
+		t.Err = t.Err.Bold(true)
+		t.StatusWarn = t.StatusWarn.Underline(true)
+		t.StatusError = t.StatusError.Bold(true)
+	}
+	return t
+}
+
+// HelpStyles styles the key help of the views
+func (t *Theme) HelpStyles() help.Styles {
+	key := lipgloss.NewStyle().Foreground(t.text)
+	desc := lipgloss.NewStyle().Foreground(t.muted)
+	return help.Styles{
+		Ellipsis:       desc,
+		ShortKey:       key,
+		ShortDesc:      desc,
+		ShortSeparator: desc,
+		FullKey:        key,
+		FullDesc:       desc,
+		FullSeparator:  desc,
+	}
+}
+
+// Severity is the style of status messages of a severity
+func (t *Theme) Severity(severity internal.Severity) lipgloss.Style {
+	switch severity {
+	case internal.SeveritySuccess:
+		return t.StatusSuccess
+	case internal.SeverityWarn:
+		return t.StatusWarn
+	case internal.SeverityError:
+		return t.StatusError
+	}
+	return t.Status
+}
+
+// ThemeNames lists the built-in themes followed by the custom ones
+func (c Config) ThemeNames() []string {
+	names := make([]string, 0, len(builtinThemes)+len(c.Themes))
+	for _, t := range builtinThemes {
+		names = append(names, t.name)
+	}
+	for _, t := range c.Themes {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+// LoadTheme builds the named theme. An empty name is the configured theme,
+// or none when NO_COLOR is set
+func (c Config) LoadTheme(name string) (Theme, error) {
+	if name == "" {
+		name = c.Theme
+		if os.Getenv("NO_COLOR") != "" {
+			name = themeNone
+		}
+	}
+	if name == "" {
+		name = themeAuto
+	}
+	for _, t := range c.Themes {
+		if t.Name == name {
+			base, ok := builtinColors(t.Base)
+			if !ok {
+				return Theme{}, fmt.Errorf("theme %q: unknown base theme %q", t.Name, t.Base)
+			}
+			return NewTheme(name, t.Colors.over(base)), nil
+		}
+	}
+	if colors, ok := builtinColors(name); ok {
+		return NewTheme(name, colors), nil
+	}
+	return Theme{}, fmt.Errorf("unknown theme %q", name)
+}
+
+// builtinColors returns the colors of a built-in theme, auto for an empty name
+func builtinColors(name string) (ThemeColors, bool) {
+	if name == "" {
+		name = themeAuto
+	}
+	for _, t := range builtinThemes {
+		if t.name == name {
+			return t.colors, true
+		}
+	}
+	return ThemeColors{}, false
+}
@@ -0,0 +1,284 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// TransferEventMessage wraps a transfer manager event for the tea runtime
+type TransferEventMessage struct {
+	Event transfer.Event
+}
+
+// waitForTransferEvent blocks until the manager reports a change
+func waitForTransferEvent(manager *transfer.Manager) tea.Cmd {
+	return func() tea.Msg {
+		return TransferEventMessage{Event: <-manager.Events()}
+	}
+}
+
+// finishTransfer records a finished job. Once no transfers are left active,
+// the completed ones are reported together instead of one message each
+func (m TUI) finishTransfer(job transfer.Job, active int) (TUI, tea.Cmd) {
+	if job.State == transfer.Completed {
+		m.completed = append(slices.Clip(m.completed), job)
+	}
+	if active != 0 || len(m.completed) == 0 {
+		return m, nil
+	}
+	status := fmt.Sprintf("Finished %d transfers", len(m.completed))
+	if len(m.completed) == 1 {
+		status = fmt.Sprintf("Finished %s of %s", m.completed[0].Kind, m.completed[0].Name)
+	}
+	m.completed = nil
+	return m, utils.SendMessage(internal.APIMessage{Status: status, Severity: internal.SeveritySuccess})
+}
+
+// limitGlobal is the limitFor value of the global bandwidth limit
+const limitGlobal = -1
+
+type TransfersMenu struct {
+	manager  *transfer.Manager
+	cursor   int
+	input    textinput.Model
+	limitFor int // job id the limit being entered applies to, or limitGlobal
+	theme    *Theme
+}
+
+func InitTransfersMenu(manager *transfer.Manager, theme *Theme) TransfersMenu {
+	input := textinput.New()
+	input.Prompt = "$ "
+	input.CharLimit = 20
+	input.Width = 50
+	return TransfersMenu{manager: manager, input: input, theme: theme}
+}
+
+func (m TransfersMenu) Init() tea.Cmd {
+	return nil
+}
+
+func (m TransfersMenu) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	jobs := m.manager.Jobs()
+	if m.cursor > len(jobs)-1 {
+		m.cursor = max(len(jobs)-1, 0)
+	}
+
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if m.input.Focused() {
+			if key.Matches(msg, keysOf(transfersMenu).Enter) {
+				value := m.input.Value()
+				m.input.SetValue("")
+				m.input.Blur()
+				return m, m.applyLimit(value)
+			}
+			if key.Matches(msg, keysOf(transfersMenu).Backspace) && m.input.Value() == "" {
+				m.input.Blur()
+				return m, nil
+			}
+			var cmd tea.Cmd
+			m.input, cmd = m.input.Update(msg)
+			return m, cmd
+		}
+
+		if cursor, ok := scrollKey(keysOf(transfersMenu), msg, m.cursor, len(jobs), m.rows()); ok {
+			m.cursor = cursor
+			return m, nil
+		}
+		switch {
+		case key.Matches(msg, keysOf(transfersMenu).GlobalLimit):
+			m.limitFor = limitGlobal
+			m.input.Placeholder = "Bandwidth limit for all transfers per second, e.g. 5MiB, 0 for none..."
+			m.input.Focus()
+			return m, textinput.Blink
+		case key.Matches(msg, keysOf(transfersMenu).JobLimit):
+			if len(jobs) != 0 {
+				m.limitFor = jobs[m.cursor].ID
+				m.input.Placeholder = fmt.Sprintf("Bandwidth limit for %s per second, 0 for none...", jobs[m.cursor].Name)
+				m.input.Focus()
+				return m, textinput.Blink
+			}
+		case key.Matches(msg, keysOf(transfersMenu).StartNow):
+			if len(jobs) != 0 {
+				m.manager.StartNow(jobs[m.cursor].ID)
+			}
+		case key.Matches(msg, keysOf(transfersMenu).Up):
+			if m.cursor > 0 {
+				m.cursor--
+			}
+		case key.Matches(msg, keysOf(transfersMenu).Down):
+			if m.cursor < len(jobs)-1 {
+				m.cursor++
+			}
+		case key.Matches(msg, keysOf(transfersMenu).Pause):
+			if len(jobs) != 0 {
+				job := jobs[m.cursor]
+				if job.State == transfer.Paused {
+					m.manager.Resume(job.ID)
+				} else {
+					m.manager.Pause(job.ID)
+				}
+			}
+		case key.Matches(msg, keysOf(transfersMenu).Cancel):
+			if len(jobs) != 0 {
+				m.manager.Cancel(jobs[m.cursor].ID)
+			}
+		case key.Matches(msg, keysOf(transfersMenu).Retry):
+			if len(jobs) != 0 {
+				m.manager.Retry(jobs[m.cursor].ID)
+			}
+		case key.Matches(msg, keysOf(transfersMenu).Clear):
+			m.manager.Clear()
+			m.cursor = 0
+		}
+	}
+	return m, nil
+}
+
+func (m TransfersMenu) Typing() bool {
+	return m.input.Focused()
+}
+
+func (m TransfersMenu) KeyMap() help.KeyMap {
+	if m.input.Focused() {
+		return promptKeys(transfersMenu)
+	}
+	jobs := m.manager.Jobs()
+	var job transfer.Job
+	has := m.cursor < len(jobs)
+	if has {
+		job = jobs[m.cursor]
+	}
+	nav := []key.Binding{when(keysOf(transfersMenu).Up, has), when(keysOf(transfersMenu).Down, has)}
+	actions := []key.Binding{
+		when(keysOf(transfersMenu).Pause, has && !job.State.Finished()),
+		when(keysOf(transfersMenu).Cancel, has && !job.State.Finished()),
+		when(keysOf(transfersMenu).Retry, has && (job.State == transfer.Failed || job.State == transfer.Cancelled)),
+		when(keysOf(transfersMenu).StartNow, has && job.State == transfer.Scheduled),
+		when(keysOf(transfersMenu).Clear, has),
+	}
+	limits := []key.Binding{when(keysOf(transfersMenu).JobLimit, has && !job.State.Finished()), keysOf(transfersMenu).GlobalLimit}
+	return viewKeyMap{
+		short: append(append(nav, actions...), limits...),
+		full:  [][]key.Binding{nav, scrollKeys(keysOf(transfersMenu), has), actions, limits},
+	}
+}
+
+// applyLimit sets the bandwidth limit entered for a job or for all transfers
+func (m TransfersMenu) applyLimit(value string) tea.Cmd {
+	rate, err := transfer.ParseBytes(value)
+	if err != nil {
+		return utils.SendMessage(internal.APIMessage{Err: err})
+	}
+	target := "all transfers"
+	if m.limitFor == limitGlobal {
+		m.manager.SetGlobalLimit(rate)
+	} else {
+		m.manager.SetLimit(m.limitFor, rate)
+		target = fmt.Sprintf("transfer %d", m.limitFor)
+	}
+	return utils.SendMessage(internal.APIMessage{
+		Status:   fmt.Sprintf("Bandwidth limit for %s set to %s", target, formatRate(rate)),
+		Severity: internal.SeveritySuccess,
+	})
+}
+
+// rows is the number of jobs that fit below the title, the settings and the
+// scroll position, leaving a line for the limit prompt
+func (m TransfersMenu) rows() int {
+	return listRows(5)
+}
+
+func (m TransfersMenu) View() string {
+	var b strings.Builder
+	b.WriteString(m.theme.Header.Render("Transfers") + "\n")
+	settings := fmt.Sprintf("Limit: %s", formatRate(m.manager.GlobalLimit()))
+	if window, deferAbove := m.manager.Schedule(); window != nil {
+		settings += fmt.Sprintf("   Window: %s for transfers of %s or more", window, formatBytes(deferAbove))
+	}
+	b.WriteString(m.theme.Choice.Render(settings) + "\n\n")
+
+	jobs := m.manager.Jobs()
+	if len(jobs) == 0 {
+		b.WriteString(m.theme.Doc.Render("No transfers.\n"))
+	}
+	start, end := scrollWindow(m.cursor, len(jobs), m.rows())
+	if position := scrollPosition(start, end, len(jobs)); position != "" {
+		b.WriteString(m.theme.Help.Render(position) + "\n")
+	}
+	for i := start; i < end; i++ {
+		job := jobs[i]
+		cursor := " "
+		display := fmt.Sprintf("%-8s %-9s %s %s %s",
+			job.Kind, job.State, progressBar(job.Percent(), 20), formatProgress(job), job.Name)
+		if job.Limit > 0 {
+			display += " @ " + formatRate(job.Limit)
+		}
+		if job.Err != nil && job.State == transfer.Failed {
+			display += " - " + job.Err.Error()
+		}
+		display = clip(display, innerWidth(windowWidth()))
+
+		if i == m.cursor {
+			cursor = m.theme.Cursor.Render(">")
+			display = m.theme.Selected.Render(display)
+		} else if job.State == transfer.Failed {
+			display = m.theme.Err.Render(display)
+		} else {
+			display = m.theme.Choice.Render(display)
+		}
+		b.WriteString(fmt.Sprintf("%s%s\n", cursor, display))
+	}
+
+	menu := m.theme.Border.Render(b.String())
+	if m.input.Focused() {
+		menu += "\n" + m.input.View()
+	}
+	return menu
+}
+
+// formatRate renders a bandwidth limit
+func formatRate(rate int64) string {
+	if rate <= 0 {
+		return "unlimited"
+	}
+	return formatBytes(rate) + "/s"
+}
+
+func progressBar(percent float64, width int) string {
+	filled := int(percent * float64(width))
+	if filled > width {
+		filled = width
+	}
+	return "[" + strings.Repeat("=", filled) + strings.Repeat(" ", width-filled) + "]"
+}
+
+func formatProgress(job transfer.Job) string {
+	if job.Total <= 0 {
+		return formatBytes(job.Done)
+	}
+	return fmt.Sprintf("%s/%s", formatBytes(job.Done), formatBytes(job.Total))
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
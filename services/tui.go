@@ -2,11 +2,19 @@ package services
 
 import (
 	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
 
 	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/s3"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
 	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/charmbracelet/bubbles/cursor"
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/spinner"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 	"github.com/muesli/reflow/wordwrap"
@@ -18,8 +26,24 @@ const (
 	mainMenu SessionState = iota
 	s3Menu
 	profileMenu
+	syncMenu
+	transfersMenu
+	multipartMenu
+	bookmarksMenu
+	compareMenu
+	messagesMenu
 )
 
+// String names the view as the keys config does
+func (s SessionState) String() string {
+	for name, state := range keyViews {
+		if state == s {
+			return name
+		}
+	}
+	return fmt.Sprintf("view %d", int(s))
+}
+
 type SwitchMenuMessage struct {
 	menu SessionState
 }
@@ -29,30 +53,164 @@ type TUI struct {
 	views     map[SessionState]tea.Model
 	profile   string
 	config    aws.Config
+	appConfig Config
+	endpoint  *internal.Endpoint // nil when talking to AWS
+	initErr   error
 	statusBar StatusBar
+	history   history
+	palette   Palette
+	help      help.Model
+	showHelp  bool // the "?" overlay with every binding of the view
+	logger    *slog.Logger
+	theme     *Theme
+	completed []transfer.Job // transfers finished since the queue was last empty
 	// to implement
 	quitting bool
 }
 
-func InitTUI() TUI {
+// InitTUI starts on the main menu with the loaded config. cfgErr is the error
+// loading it, reported once the program runs
+func InitTUI(cfg Config, cfgErr error, logger *slog.Logger) TUI {
 	views := make(map[SessionState]tea.Model)
-	views[mainMenu] = InitialMenu()
-	cfg, _ := utils.LoadAWSConfig("")
-	return TUI{
+	m := TUI{
 		state:     mainMenu,
 		views:     views,
 		profile:   "default",
-		config:    cfg,
+		appConfig: cfg,
+		initErr:   cfgErr,
 		quitting:  false,
-		statusBar: InitStatusBar(),
+		help:      help.New(),
+		logger:    logger,
+	}
+
+	theme, err := m.appConfig.LoadTheme("")
+	if err != nil {
+		m.initErr = err
+		theme, _ = Config{}.LoadTheme("")
+	}
+	m.theme = &theme
+	views[mainMenu] = InitialMenu(m.theme)
+	m.statusBar = InitStatusBar(m.theme)
+	views[messagesMenu] = InitMessagesMenu(m.statusBar.Log(), m.theme)
+	m.palette = InitPalette(m.logger, m.theme)
+
+	TransferManager = m.appConfig.Transfers.NewManager()
+	if err := m.appConfig.Transfers.Apply(TransferManager); err != nil {
+		m.initErr = err
 	}
+	views[transfersMenu] = InitTransfersMenu(TransferManager, m.theme)
+	if err := m.appConfig.Keys.Apply(); err != nil {
+		m.initErr = err
+	}
+
+	name := m.appConfig.Profile
+	if m.appConfig.Endpoint != "" {
+		name = m.appConfig.Endpoint
+	}
+	awsConfig, endpoint, err := m.loadProfile(name)
+	if err != nil && name != "" {
+		// fall back to the default credential chain
+		m.initErr = fmt.Errorf("profile %s: %w", name, err)
+		awsConfig, _ = utils.LoadAWSConfig("")
+		name, endpoint = "", nil
+	}
+	if name != "" {
+		m.profile = name
+	}
+	m.config, m.endpoint = awsConfig, endpoint
+	if m.appConfig.Region != "" {
+		m.config.Region = m.appConfig.Region
+	}
+	m.logger.Debug("starting", "profile", m.profile, "region", m.config.Region, "endpoint", endpoint != nil)
+	return m
 }
 
 func (m TUI) Init() tea.Cmd {
-	return nil
+	cmds := []tea.Cmd{waitForTransferEvent(TransferManager), m.start()}
+	if m.initErr != nil {
+		cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+			Err: fmt.Errorf("config: %w", m.initErr),
+		}))
+	}
+	return tea.Batch(cmds...)
+}
+
+// start switches to the configured start view
+func (m TUI) start() tea.Cmd {
+	state, ok := startView(m.appConfig.StartView)
+	if !ok {
+		return utils.SendMessage(internal.APIMessage{
+			Status:   fmt.Sprintf("Unknown start view %q, starting on the main menu", m.appConfig.StartView),
+			Severity: internal.SeverityWarn,
+		})
+	}
+	if state == mainMenu {
+		return nil
+	}
+	return func() tea.Msg { return SwitchMenuMessage{menu: state} }
 }
 
 func (m TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	m.logMsg(msg)
+	from := m.location()
+	m, cmd := m.update(msg)
+	if to := m.location(); to != from {
+		m.logger.Debug("view changed", "from", from.state, "to", to.state, "place", to.place)
+	}
+	m.history = m.history.visit(m.location())
+	return m, cmd
+}
+
+// logMsg logs every message but the spinner and cursor ticks at debug level
+func (m TUI) logMsg(msg tea.Msg) {
+	switch msg := msg.(type) {
+	case spinner.TickMsg, cursor.BlinkMsg:
+	case tea.KeyMsg:
+		m.logger.Debug("key", "key", msg.String(), "state", m.state)
+	case internal.APIMessage:
+		m.logger.Debug("message", "severity", msg.Level(), "text", msg.Text())
+	default:
+		m.logger.Debug("message", "type", fmt.Sprintf("%T", msg))
+	}
+}
+
+// location returns the view being shown and the place inside it
+func (m TUI) location() location {
+	loc := location{state: m.state}
+	if view, ok := m.views[m.state].(navigable); ok {
+		loc.place = view.Location()
+	}
+	return loc
+}
+
+// navigate shows a location taken from the history
+func (m TUI) navigate(loc location) (TUI, tea.Cmd) {
+	var cmds []tea.Cmd
+	if m.views[loc.state] == nil {
+		// views of a previous profile are dropped, rebuild them
+		switch loc.state {
+		case s3Menu:
+			m.views[s3Menu] = InitS3Menu(m.config, m.endpoint, m.appConfig, m.logger, m.theme)
+		case profileMenu:
+			m.views[profileMenu] = InitProfileMenu(m.appConfig.Endpoints, m.logger, m.theme)
+		default:
+			loc = location{state: mainMenu}
+		}
+		if view := m.views[loc.state]; view != nil {
+			cmds = append(cmds, view.Init())
+		}
+	}
+
+	m.state = loc.state
+	if view, ok := m.views[m.state].(navigable); ok {
+		newView, cmd := view.Restore(loc.place)
+		m.views[m.state] = newView
+		cmds = append(cmds, cmd)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m TUI) update(msg tea.Msg) (TUI, tea.Cmd) {
 	var cmd tea.Cmd
 	var cmds []tea.Cmd
 
@@ -61,34 +219,147 @@ func (m TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		if msg.menu == profileMenu {
 			m.state = profileMenu
 			if m.views[profileMenu] == nil {
-				m.views[profileMenu] = InitProfileMenu()
+				m.views[profileMenu] = InitProfileMenu(m.appConfig.Endpoints, m.logger, m.theme)
 				cmd = m.views[profileMenu].Init()
 			}
+		} else if msg.menu == bookmarksMenu {
+			// reload every time so bookmarks added elsewhere show up
+			m.state = bookmarksMenu
+			m.views[bookmarksMenu] = InitBookmarksMenu(m.theme)
+			cmd = m.views[bookmarksMenu].Init()
+		} else if msg.menu == transfersMenu || msg.menu == messagesMenu || msg.menu == mainMenu {
+			m.state = msg.menu
 		} else if msg.menu == s3Menu {
 			m.state = s3Menu
 			if m.views[s3Menu] == nil {
-				m.views[s3Menu] = InitS3Menu()
+				m.views[s3Menu] = InitS3Menu(m.config, m.endpoint, m.appConfig, m.logger, m.theme)
 				cmd = m.views[s3Menu].Init()
 			}
 		}
 		return m, cmd
-	case ProfileMenuMessage:
-		m.state = mainMenu
-		if m.profile != msg.profile {
-			m.profile = msg.profile
-			m.config = msg.config
-
-			cmd1 := utils.SendMessage(internal.APIMessage{
-				Status: fmt.Sprintf("Profile changed to %s", m.profile),
+	case OpenSyncMessage:
+		m.state = syncMenu
+		m.views[syncMenu] = InitSyncMenu(msg.client, msg.bucket, msg.prefix, m.appConfig.Compression, m.theme)
+		return m, m.views[syncMenu].Init()
+	case OpenCompareMessage:
+		m.state = compareMenu
+		m.views[compareMenu] = InitCompareMenu(m.profile, msg.client, msg.bucket, msg.prefix, m.clientFor, m.theme)
+		return m, m.views[compareMenu].Init()
+	case OpenMultipartMessage:
+		m.state = multipartMenu
+		m.views[multipartMenu] = InitMultipartMenu(msg.client, msg.bucket, m.theme)
+		return m, m.views[multipartMenu].Init()
+	case AddBookmarkMessage:
+		bookmarks, err := loadBookmarks()
+		if err != nil {
+			return m, utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		bookmark := internal.NewBookmark(m.profile, msg.bucket, msg.prefix)
+		bookmarks, added := internal.AddBookmark(bookmarks, bookmark)
+		if !added {
+			return m, utils.SendMessage(internal.APIMessage{
+				Status:   fmt.Sprintf("%s is already bookmarked", bookmark.Name),
+				Severity: internal.SeverityWarn,
 			})
-			cmd2 := utils.SendMessage(internal.AWSConfigMessage{
-				Config: m.config,
+		}
+		if err := utils.SaveState(bookmarksFile, bookmarks); err != nil {
+			return m, utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		return m, utils.SendMessage(internal.APIMessage{
+			Status:   fmt.Sprintf("Bookmarked %s", bookmark.Name),
+			Severity: internal.SeveritySuccess,
+		})
+
+	case OpenBookmarkMessage:
+		bookmark := msg.bookmark
+		if bookmark.Profile != m.profile {
+			cfg, endpoint, err := m.loadProfile(bookmark.Profile)
+			if err != nil {
+				return m, utils.SendMessage(internal.APIMessage{
+					Err: fmt.Errorf("switching to profile %s: %w", bookmark.Profile, err),
+				})
+			}
+			m.profile, m.config, m.endpoint = bookmark.Profile, cfg, endpoint
+			// views holding clients of the old profile are rebuilt on demand
+			delete(m.views, s3Menu)
+			delete(m.views, profileMenu)
+		}
+		if m.views[s3Menu] == nil {
+			m.views[s3Menu] = InitS3Menu(m.config, m.endpoint, m.appConfig, m.logger, m.theme)
+			cmds = append(cmds, m.views[s3Menu].Init())
+		}
+		s3MenuModel, ok := m.views[s3Menu].(S3Menu)
+		if !ok {
+			panic("assertion on S3 menu failed")
+		}
+		s3MenuModel, cmd = s3MenuModel.goTo(bookmark.Bucket, bookmark.Prefix)
+		m.views[s3Menu] = s3MenuModel
+		m.state = s3Menu
+		cmds = append(cmds, cmd)
+		return m, tea.Batch(cmds...)
+
+	case SwitchProfileMessage:
+		if msg.profile == m.profile {
+			return m, nil
+		}
+		cfg, endpoint, err := m.loadProfile(msg.profile)
+		if err != nil {
+			return m, utils.SendMessage(internal.APIMessage{
+				Err: fmt.Errorf("switching to profile %s: %w", msg.profile, err),
 			})
+		}
+		m, cmd = m.useConfig(msg.profile, cfg, endpoint)
+		return m, tea.Batch(cmd, utils.SendMessage(internal.APIMessage{
+			Status:   fmt.Sprintf("Profile changed to %s", m.profile),
+			Severity: internal.SeveritySuccess,
+		}))
 
-			return m, tea.Batch(cmd1, cmd2)
+	case SwitchThemeMessage:
+		name := msg.theme
+		if name == "" {
+			names := m.appConfig.ThemeNames()
+			name = names[(slices.Index(names, m.theme.Name)+1)%len(names)]
 		}
-		// todo: if profile is different, then need to refresh all clients
-		return m, nil
+		theme, err := m.appConfig.LoadTheme(name)
+		if err != nil {
+			return m, utils.SendMessage(internal.APIMessage{Err: err})
+		}
+		*m.theme = theme
+		return m, utils.SendMessage(internal.APIMessage{Status: fmt.Sprintf("Using the %s theme", name), Severity: internal.SeveritySuccess})
+
+	case SwitchRegionMessage:
+		cfg := m.config.Copy()
+		cfg.Region = msg.region
+		m, cmd = m.useConfig(m.profile, cfg, m.endpoint)
+		return m, tea.Batch(cmd, utils.SendMessage(internal.APIMessage{
+			Status:   fmt.Sprintf("Region changed to %s", msg.region),
+			Severity: internal.SeveritySuccess,
+		}))
+
+	case ProfileMenuMessage:
+		m.state = mainMenu
+		if m.profile == msg.profile {
+			return m, nil
+		}
+		m, cmd = m.useConfig(msg.profile, msg.config, msg.endpoint)
+		return m, tea.Batch(cmd, utils.SendMessage(internal.APIMessage{
+			Status:   fmt.Sprintf("Profile changed to %s", m.profile),
+			Severity: internal.SeveritySuccess,
+		}))
+
+	case TransferEventMessage:
+		cmds = append(cmds, waitForTransferEvent(TransferManager))
+		job := msg.Event.Job
+		if job.State == transfer.Failed {
+			cmds = append(cmds, utils.SendMessage(internal.APIMessage{
+				Err: fmt.Errorf("%s of %s failed: %w", job.Kind, job.Name, job.Err),
+			}))
+		}
+		if job.State.Finished() {
+			m, cmd = m.finishTransfer(job, TransferManager.Active())
+			cmds = append(cmds, cmd)
+		}
+		return m, tea.Batch(cmds...)
 
 	case internal.APIMessage, StatusBarTimeoutMessage:
 		newStatusBar, newCmd := m.statusBar.Update(msg)
@@ -100,18 +371,75 @@ func (m TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, newCmd
 
 	case tea.WindowSizeMsg:
-		// todo: implement resize handling
+		// views size their panes and lists from WindowSize as they render
 		WindowSize = msg
-		// top, right, bottom, left := DocStyle.GetMargin()
 
 	case tea.KeyMsg:
+		if m.palette.Active() {
+			m.palette, cmd = m.palette.Update(msg, m.commands())
+			return m, cmd
+		}
+		if m.showHelp {
+			switch {
+			case key.Matches(msg, keysOf(m.state).Quit):
+				return m, tea.Quit
+			case key.Matches(msg, keysOf(m.state).Help), key.Matches(msg, keysOf(m.state).Back):
+				m.showHelp = false
+			}
+			return m, nil
+		}
+		view, ok := m.views[m.state].(typing)
+		typed := ok && view.Typing()
+		if !typed {
+			switch {
+			case key.Matches(msg, keysOf(m.state).Command):
+				m.palette, cmd = m.palette.Open(m.state, m.commands())
+				return m, cmd
+			case key.Matches(msg, keysOf(m.state).Help):
+				m.showHelp = true
+				return m, nil
+			}
+		}
+
+		// keys typed into a view's input are its own, bar ctrl+c. A filter
+		// pattern or a prefix may well contain a "q", and esc in a filter
+		// clears it rather than leaving the view
 		switch {
-		case key.Matches(msg, Keymap.Quit):
+		case key.Matches(msg, keysOf(m.state).Quit) && (!typed || msg.Type == tea.KeyCtrlC):
 			return m, tea.Quit
 
-		case key.Matches(msg, Keymap.Back):
-			//todo: implement go back to last screen, not mainmenu
-			m.state = mainMenu
+		case key.Matches(msg, keysOf(m.state).Transfers):
+			m.state = transfersMenu
+			return m, nil
+
+		case key.Matches(msg, keysOf(m.state).Messages):
+			m.state = messagesMenu
+			return m, nil
+
+		case key.Matches(msg, keysOf(m.state).Dismiss) && m.statusBar.Sticky():
+			m.statusBar = m.statusBar.Dismiss()
+			return m, nil
+
+		case key.Matches(msg, keysOf(m.state).Back) && !typed:
+			history, loc, ok := m.history.goBack()
+			if !ok {
+				m.state = mainMenu
+				return m, nil
+			}
+			m.history = history
+			return m.navigate(loc)
+
+		case key.Matches(msg, keysOf(m.state).Forward) && !typed:
+			history, loc, ok := m.history.goForward()
+			if !ok {
+				return m, nil
+			}
+			m.history = history
+			return m.navigate(loc)
+		}
+
+		// views act on the keys their help offers and nothing else
+		if view, ok := m.views[m.state].(keyed); ok && !typed && !handles(view.KeyMap(), m.state, msg) {
 			return m, nil
 		}
 	}
@@ -141,6 +469,54 @@ func (m TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		m.views[s3Menu] = s3MenuModel
 		cmd = newCmd
+	case syncMenu:
+		newSync, newCmd := m.views[syncMenu].Update(msg)
+		syncMenuModel, ok := newSync.(SyncMenu)
+		if !ok {
+			panic("assertion on sync menu failed")
+		}
+		m.views[syncMenu] = syncMenuModel
+		cmd = newCmd
+	case transfersMenu:
+		newTransfers, newCmd := m.views[transfersMenu].Update(msg)
+		transfersMenuModel, ok := newTransfers.(TransfersMenu)
+		if !ok {
+			panic("assertion on transfers menu failed")
+		}
+		m.views[transfersMenu] = transfersMenuModel
+		cmd = newCmd
+	case multipartMenu:
+		newMultipart, newCmd := m.views[multipartMenu].Update(msg)
+		multipartMenuModel, ok := newMultipart.(MultipartMenu)
+		if !ok {
+			panic("assertion on multipart menu failed")
+		}
+		m.views[multipartMenu] = multipartMenuModel
+		cmd = newCmd
+	case bookmarksMenu:
+		newBookmarks, newCmd := m.views[bookmarksMenu].Update(msg)
+		bookmarksMenuModel, ok := newBookmarks.(BookmarksMenu)
+		if !ok {
+			panic("assertion on bookmarks menu failed")
+		}
+		m.views[bookmarksMenu] = bookmarksMenuModel
+		cmd = newCmd
+	case compareMenu:
+		newCompare, newCmd := m.views[compareMenu].Update(msg)
+		compareMenuModel, ok := newCompare.(CompareMenu)
+		if !ok {
+			panic("assertion on compare menu failed")
+		}
+		m.views[compareMenu] = compareMenuModel
+		cmd = newCmd
+	case messagesMenu:
+		newMessages, newCmd := m.views[messagesMenu].Update(msg)
+		messagesMenuModel, ok := newMessages.(MessagesMenu)
+		if !ok {
+			panic("assertion on messages menu failed")
+		}
+		m.views[messagesMenu] = messagesMenuModel
+		cmd = newCmd
 	}
 
 	cmds = append(cmds, cmd)
@@ -148,10 +524,36 @@ func (m TUI) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, tea.Batch(cmds...)
 }
 
+// loadProfile loads the config of a configured endpoint, or else of the AWS profile called name
+func (m TUI) loadProfile(name string) (aws.Config, *internal.Endpoint, error) {
+	if ep := m.appConfig.FindEndpoint(name); ep != nil {
+		cfg, err := utils.LoadEndpointConfig(*ep)
+		return cfg, ep, err
+	}
+	cfg, err := utils.LoadAWSConfig(name)
+	return cfg, nil, err
+}
+
+// clientFor creates an S3 client for a profile other than the current one
+func (m TUI) clientFor(profile string) (s3.S3API, error) {
+	cfg, endpoint, err := m.loadProfile(profile)
+	if err != nil {
+		return nil, err
+	}
+	client, ok := utils.ClientFactory("s3", cfg, endpoint, m.logger).(s3.S3API)
+	if !ok {
+		return nil, fmt.Errorf("no S3 client for profile %s", profile)
+	}
+	return client, nil
+}
+
 func (m TUI) View() string {
 	menu := ""
 
 	left := fmt.Sprintf("Profile: %s   Region: %s", m.profile, m.config.Region)
+	if m.endpoint != nil {
+		left = fmt.Sprintf("Endpoint: %s (%s)", m.endpoint.Name, m.endpoint.URL)
+	}
 	center := ""
 	switch m.state {
 	case mainMenu:
@@ -160,6 +562,22 @@ func (m TUI) View() string {
 		center = "[AWS] Profiles"
 	case s3Menu:
 		center = "[AWS] S3"
+	case syncMenu:
+		center = "[AWS] S3 Sync"
+	case transfersMenu:
+		center = "[AWS] Transfers"
+	case multipartMenu:
+		center = "[AWS] S3 Multipart Uploads"
+	case bookmarksMenu:
+		center = "[AWS] Bookmarks"
+	case compareMenu:
+		center = "[AWS] S3 Compare"
+	case messagesMenu:
+		center = "[AWS] Messages"
+	}
+	// breadcrumbs of the place inside the view
+	if place := strings.TrimSuffix(m.history.current.place, "/"); place != "" && m.history.current.state == m.state {
+		center += " > " + strings.ReplaceAll(place, "/", " > ")
 	}
 
 	totalWidth := WindowSize.Width
@@ -182,6 +600,15 @@ func (m TUI) View() string {
 
 	menu += headerLine + "\n"
 
+	m.help.Width = WindowSize.Width
+	m.help.Styles = m.theme.HelpStyles()
+	keys := m.keyMap()
+	if m.showHelp {
+		menu += m.theme.Border.Render(m.theme.Header.Render("Keys") + "\n\n" + m.help.FullHelpView(keys.FullHelp()))
+		menu += "\n" + m.theme.Help.Render("[?/esc] close help")
+		return wordwrap.String(menu, WindowSize.Width)
+	}
+
 	switch m.state {
 	case mainMenu:
 		menu += m.views[mainMenu].View()
@@ -189,15 +616,27 @@ func (m TUI) View() string {
 		menu += m.views[profileMenu].View()
 	case s3Menu:
 		menu += m.views[s3Menu].View()
+	case syncMenu:
+		menu += m.views[syncMenu].View()
+	case transfersMenu:
+		menu += m.views[transfersMenu].View()
+	case multipartMenu:
+		menu += m.views[multipartMenu].View()
+	case bookmarksMenu:
+		menu += m.views[bookmarksMenu].View()
+	case compareMenu:
+		menu += m.views[compareMenu].View()
+	case messagesMenu:
+		menu += m.views[messagesMenu].View()
 	}
 
-	menu += "\n" + m.statusBar.View()
+	menu += "\n" + m.statusBar.For(m.state).View()
 
-	helpText := "\n"
-	for _, binding := range Keymap.List() {
-		helpText += FooterStyle(fmt.Sprintf("%s ", binding.Help()))
+	if m.palette.Active() {
+		menu += "\n" + m.palette.View(m.commands())
+		return wordwrap.String(menu, WindowSize.Width)
 	}
 
-	menu += helpText
+	menu += "\n" + m.help.ShortHelpView(keys.ShortHelp())
 	return wordwrap.String(menu, WindowSize.Width)
 }
@@ -1,210 +1,182 @@
 package services
 
 import (
+	"fmt"
+	"path/filepath"
 	"testing"
 
+	"github.com/Aearsears/fuzzy-guacamole/internal"
+	"github.com/Aearsears/fuzzy-guacamole/internal/transfer"
+	"github.com/Aearsears/fuzzy-guacamole/internal/utils"
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/charmbracelet/bubbles/key"
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/stretchr/testify/assert"
 )
 
-// Mock implementations for menu models
-type mockModel struct{ tea.Model }
-
-func (m mockModel) Init() tea.Cmd                           { return nil }
-func (m mockModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) { return m, nil }
-func (m mockModel) View() string                            { return "mock view" }
+// newTestTUI starts the TUI with its state and the AWS files in a
+// temporary home directory
+func newTestTUI(t *testing.T, cfg Config) TUI {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(home, ".aws", "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(home, ".aws", "credentials"))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_REGION", "us-east-1")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	return InitTUI(cfg, nil, utils.DiscardLogger())
+}
 
-func mockStatusBar() StatusBar {
-	return StatusBar{}
+// update sends msg to the TUI
+func update(t *testing.T, m TUI, msg tea.Msg) (TUI, tea.Cmd) {
+	t.Helper()
+	model, cmd := m.Update(msg)
+	tui, ok := model.(TUI)
+	assert.True(t, ok)
+	return tui, cmd
 }
 
 func TestInitTUI(t *testing.T) {
-	// Override menu initializers for test
-	InitialMenu := func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
+	tui := newTestTUI(t, Config{})
 	assert.Equal(t, mainMenu, tui.state)
 	assert.Equal(t, "default", tui.profile)
+	assert.Nil(t, tui.endpoint)
 	assert.NotNil(t, tui.views[mainMenu])
 }
 
+func TestInitTUIEndpointAndRegion(t *testing.T) {
+	tui := newTestTUI(t, Config{
+		Endpoint:  "localstack",
+		Endpoints: []internal.Endpoint{{Name: "localstack", URL: "http://localhost:4566"}},
+		Region:    "eu-west-1",
+	})
+	assert.Equal(t, "localstack", tui.profile)
+	assert.NotNil(t, tui.endpoint)
+	assert.Equal(t, "eu-west-1", tui.config.Region)
+}
+
 func TestUpdateSwitchMenuMessage(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	msg := SwitchMenuMessage{menu: profileMenu}
-	updated, _ := tui.Update(msg)
-	tui2 := updated.(TUI)
-	assert.Equal(t, profileMenu, tui2.state)
-	assert.NotNil(t, tui2.views[profileMenu])
+	tui, _ := update(t, newTestTUI(t, Config{}), SwitchMenuMessage{menu: profileMenu})
+	assert.Equal(t, profileMenu, tui.state)
+	assert.NotNil(t, tui.views[profileMenu])
+
+	tui, _ = update(t, tui, SwitchMenuMessage{menu: s3Menu})
+	assert.Equal(t, s3Menu, tui.state)
+	assert.NotNil(t, tui.views[s3Menu])
 }
 
 func TestUpdateProfileMenuMessage(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	msg := ProfileMenuMessage{
+	tui, _ := update(t, newTestTUI(t, Config{}), ProfileMenuMessage{
 		profile: "test-profile",
 		config:  aws.Config{Region: "us-west-2"},
-	}
-	updated, _ := tui.Update(msg)
-	tui2 := updated.(TUI)
-	assert.Equal(t, mainMenu, tui2.state)
-	assert.Equal(t, "test-profile", tui2.profile)
-	assert.Equal(t, "us-west-2", tui2.config.Region)
+	})
+	assert.Equal(t, mainMenu, tui.state)
+	assert.Equal(t, "test-profile", tui.profile)
+	assert.Equal(t, "us-west-2", tui.config.Region)
 }
 
-func TestViewRenders(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
+func TestUpdateProfileMenuMessageRebuildsS3Menu(t *testing.T) {
+	tui, _ := update(t, newTestTUI(t, Config{}), SwitchMenuMessage{menu: s3Menu})
+	before := tui.views[s3Menu].(S3Menu).s3Client
 
-	tui := InitTUI()
-	WindowSize.Width = 80
-	view := tui.View()
-	assert.Contains(t, view, "Profile: default")
-	assert.Contains(t, view, "[AWS]")
+	tui, _ = update(t, tui, ProfileMenuMessage{profile: "default"})
+	assert.Same(t, before, tui.views[s3Menu].(S3Menu).s3Client, "the same profile keeps the view")
+
+	tui, cmd := update(t, tui, ProfileMenuMessage{
+		profile:  "test-profile",
+		config:   aws.Config{Region: "us-west-2"},
+		endpoint: &internal.Endpoint{Name: "localstack", URL: "http://localhost:4566"},
+	})
+	assert.NotNil(t, cmd)
+	assert.Equal(t, "localstack", tui.endpoint.Name)
+	assert.NotSame(t, before, tui.views[s3Menu].(S3Menu).s3Client, "the open S3 view gets a client for the new profile")
 }
 
-// Additional tests
+func TestFinishTransfer(t *testing.T) {
+	tui := newTestTUI(t, Config{})
+	done := func(name string) transfer.Job {
+		return transfer.Job{Kind: transfer.Download, Name: name, State: transfer.Completed}
+	}
 
-func TestUpdateS3MenuSwitch(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
+	tui, cmd := tui.finishTransfer(done("b/one"), 0)
+	if assert.NotNil(t, cmd) {
+		assert.Equal(t, "Finished download of b/one", cmd().(internal.APIMessage).Status)
+	}
 
-	tui := InitTUI()
-	msg := SwitchMenuMessage{menu: s3Menu}
-	updated, _ := tui.Update(msg)
-	tui2 := updated.(TUI)
-	assert.Equal(t, s3Menu, tui2.state)
-	assert.NotNil(t, tui2.views[s3Menu])
+	for i := range 1000 {
+		tui, cmd = tui.finishTransfer(done(fmt.Sprintf("b/%d", i)), 1)
+		assert.Nil(t, cmd, "nothing is reported while transfers are left")
+	}
+	tui, cmd = tui.finishTransfer(transfer.Job{State: transfer.Cancelled}, 0)
+	if assert.NotNil(t, cmd) {
+		assert.Equal(t, "Finished 1000 transfers", cmd().(internal.APIMessage).Status)
+	}
+	assert.Empty(t, tui.completed)
+
+	_, cmd = tui.finishTransfer(transfer.Job{State: transfer.Failed}, 0)
+	assert.Nil(t, cmd, "failures are reported as errors of their own")
 }
 
 func TestUpdateKeyMsgQuit(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	// Simulate a quit key press
-	msg := tea.KeyMsg{Type: tea.KeyCtrlC}
-	Keymap.Quit = key.NewBinding(key.WithKeys("ctrl+c"))
-	model, cmd := tui.Update(msg)
-	assert.Equal(t, tui, model)
-	assert.NotNil(t, cmd)
+	_, cmd := update(t, newTestTUI(t, Config{}), tea.KeyMsg{Type: tea.KeyCtrlC})
+	if assert.NotNil(t, cmd) {
+		assert.Equal(t, tea.QuitMsg{}, cmd())
+	}
 }
 
 func TestUpdateKeyMsgBack(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	tui.state = profileMenu
-	msg := tea.KeyMsg{Type: tea.KeyEsc}
-	Keymap.Back = key.NewBinding(key.WithKeys("esc"))
-	model, cmd := tui.Update(msg)
-	tui2 := model.(TUI)
-	assert.Equal(t, mainMenu, tui2.state)
-	assert.Nil(t, cmd)
-}
-
-func TestUpdateStatusBarTimeoutMessage(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	msg := StatusBarTimeoutMessage{}
-	model, _ := tui.Update(msg)
-	_, ok := model.(TUI)
-	assert.True(t, ok)
-}
-
-func TestUpdateWindowSizeMsg(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	msg := tea.WindowSizeMsg{Width: 100, Height: 40}
-	model, _ := tui.Update(msg)
-	_, ok := model.(TUI)
-	assert.True(t, ok)
+	tui, _ := update(t, newTestTUI(t, Config{}), SwitchMenuMessage{menu: transfersMenu})
+	tui, _ = update(t, tui, tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, mainMenu, tui.state)
 }
 
-func TestUpdateWithUnknownMsg(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
+func TestUpdateOtherMessages(t *testing.T) {
 	type unknownMsg struct{}
-	model, _ := tui.Update(unknownMsg{})
-	_, ok := model.(TUI)
-	assert.True(t, ok)
+	tui := newTestTUI(t, Config{})
+	for _, msg := range []tea.Msg{StatusBarTimeoutMessage{}, tea.WindowSizeMsg{Width: 100, Height: 40}, unknownMsg{}} {
+		tui, _ = update(t, tui, msg)
+		assert.Equal(t, mainMenu, tui.state)
+	}
 }
 
-func TestUpdateSwitchMenuMessage(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
-
-	tui := InitTUI()
-	msg := SwitchMenuMessage{menu: profileMenu}
-	updated, _ := tui.Update(msg)
-	tui2 := updated.(TUI)
-	assert.Equal(t, profileMenu, tui2.state)
-	assert.NotNil(t, tui2.views[profileMenu])
+func TestViewRenders(t *testing.T) {
+	tui := newTestTUI(t, Config{})
+	WindowSize.Width = 80
+	view := tui.View()
+	assert.Contains(t, view, "Profile: default")
+	assert.Contains(t, view, "[AWS]")
 }
 
-func TestUpdateProfileMenuMessage(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
+func TestStart(t *testing.T) {
+	assert.Nil(t, newTestTUI(t, Config{}).start())
 
-	tui := InitTUI()
-	msg := ProfileMenuMessage{
-		profile: "test-profile",
-		config:  aws.Config{Region: "us-west-2"},
+	cmd := newTestTUI(t, Config{StartView: "transfers"}).start()
+	if assert.NotNil(t, cmd) {
+		assert.Equal(t, SwitchMenuMessage{menu: transfersMenu}, cmd())
+	}
+
+	cmd = newTestTUI(t, Config{StartView: "compare"}).start()
+	if assert.NotNil(t, cmd) {
+		msg, ok := cmd().(internal.APIMessage)
+		assert.True(t, ok)
+		assert.Equal(t, internal.SeverityWarn, msg.Level())
 	}
-	updated, _ := tui.Update(msg)
-	tui2 := updated.(TUI)
-	assert.Equal(t, mainMenu, tui2.state)
-	assert.Equal(t, "test-profile", tui2.profile)
-	assert.Equal(t, "us-west-2", tui2.config.Region)
 }
 
-func TestViewRenders(t *testing.T) {
-	InitialMenu = func() tea.Model { return mockModel{} }
-	InitProfileMenu = func() tea.Model { return mockModel{} }
-	InitS3Menu = func() tea.Model { return mockModel{} }
-	InitStatusBar = mockStatusBar
+func TestUpdateKeyMsgTypedIntoFilter(t *testing.T) {
+	tui, _ := update(t, newTestTUI(t, Config{}), SwitchMenuMessage{menu: profileMenu})
+	tui, _ = update(t, tui, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("/")})
+	tui, _ = update(t, tui, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("q")})
+	profiles, ok := tui.views[profileMenu].(ProfileMenu)
+	if assert.True(t, ok) {
+		assert.Equal(t, "q", profiles.filter.Pattern(), "q is typed into the filter instead of quitting")
+	}
 
-	tui := InitTUI()
-	WindowSize.Width = 80
-	view := tui.View()
-	assert.Contains(t, view, "Profile: default")
-	assert.Contains(t, view, "[AWS]")
+	tui, _ = update(t, tui, tea.KeyMsg{Type: tea.KeyEsc})
+	assert.Equal(t, profileMenu, tui.state, "esc clears the filter instead of going back")
+	profiles, ok = tui.views[profileMenu].(ProfileMenu)
+	if assert.True(t, ok) {
+		assert.Equal(t, "", profiles.filter.Pattern())
+	}
 }